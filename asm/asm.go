@@ -0,0 +1,578 @@
+// Package asm is a small 6502 assembler to build test programs and ROMs.
+//
+//	        .org $8000
+//	reset:  LDX #$00
+//	loop:   LDA data,X
+//	        STA $0200,X
+//	        INX
+//	        BNE loop
+//	        JMP reset
+//	data:   .byte $01, $02, "NES"
+//	        .org $FFFA
+//	        .word reset, reset, reset
+//
+// Supported syntax:
+//   - labels `name:` and constants `name = expr`
+//   - all addressing modes. Zero page is chosen when the address is known to be less than $100
+//   - directives .org, .byte and .word
+//   - numbers $hex, %binary and decimal, `*` for the current address,
+//     `+`/`-` operators, and `<`/`>` for the low/high byte
+//   - comments starting with `;`
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Addressing mode names used in Opcode.Mode.
+const (
+	Implied     = "Implied"
+	Accumulator = "Accumulator"
+	Immediate   = "Immediate"
+	ZeroPage    = "ZeroPage"
+	ZeroPageX   = "ZeroPageX"
+	ZeroPageY   = "ZeroPageY"
+	Absolute    = "Absolute"
+	AbsoluteX   = "AbsoluteX"
+	AbsoluteY   = "AbsoluteY"
+	Indirect    = "Indirect"
+	IndirectX   = "IndirectX"
+	IndirectY   = "IndirectY"
+	Relative    = "Relative"
+)
+
+// DefaultOrigin is the address of the first byte when the source has no .org.
+const DefaultOrigin = 0x8000
+
+// Opcode is an entry of the instruction set. cpu.Opcodes can be converted to it.
+type Opcode struct {
+	Code       byte
+	Name       string
+	Mode       string
+	Unofficial bool
+}
+
+// Error is an error at a line of the source.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Program is an assembled program.
+type Program struct {
+	// Origin is the lowest address written.
+	Origin uint16
+	// Bytes is the memory image from Origin. Gaps between .org blocks are filled with 0.
+	Bytes   []byte
+	Symbols map[string]uint16
+}
+
+// Option configures Assembler.
+type Option func(*Assembler)
+
+// WithUnofficial enables unofficial opcodes such as LAX and DCP.
+func WithUnofficial() Option {
+	return func(a *Assembler) {
+		a.unofficial = true
+	}
+}
+
+// Assembler assembles source with an instruction set.
+type Assembler struct {
+	opcodes    map[string]map[string]Opcode // name -> mode -> opcode
+	unofficial bool
+}
+
+func New(set []Opcode, opts ...Option) *Assembler {
+	a := &Assembler{
+		opcodes: map[string]map[string]Opcode{},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	for _, op := range set {
+		if op.Unofficial && !a.unofficial {
+			continue
+		}
+		modes, ok := a.opcodes[op.Name]
+		if !ok {
+			modes = map[string]Opcode{}
+			a.opcodes[op.Name] = modes
+		}
+		// 同じニーモニックとモードが複数ある場合(NOP, SBC)は公式命令、次にopcodeの若いものを使う
+		if prev, ok := modes[op.Mode]; ok && (!prev.Unofficial || op.Unofficial && prev.Code < op.Code) {
+			continue
+		}
+		modes[op.Mode] = op
+	}
+	return a
+}
+
+// statement is a parsed line.
+type statement struct {
+	line    int
+	label   string
+	name    string // ニーモニック、ディレクティブ、定数名
+	operand string
+	isConst bool
+
+	// 1パス目で決まる
+	address uint16
+	mode    string
+	size    int
+}
+
+// Assemble assembles src.
+func (a *Assembler) Assemble(src string) (*Program, error) {
+	stmts := parse(src)
+	symbols := map[string]uint16{}
+	if err := a.layout(stmts, symbols); err != nil {
+		return nil, err
+	}
+	return a.emit(stmts, symbols)
+}
+
+// layout decides the address, addressing mode and size of statements and defines symbols.
+func (a *Assembler) layout(stmts []*statement, symbols map[string]uint16) error {
+	pc := uint32(DefaultOrigin)
+	for _, s := range stmts {
+		s.address = uint16(pc)
+		if s.label != "" {
+			if _, ok := symbols[s.label]; ok {
+				return &Error{s.line, fmt.Sprintf("symbol %s is already defined", s.label)}
+			}
+			symbols[s.label] = uint16(pc)
+		}
+		switch {
+		case s.name == "":
+		case s.isConst:
+			if _, ok := symbols[s.name]; ok {
+				return &Error{s.line, fmt.Sprintf("symbol %s is already defined", s.name)}
+			}
+			v, known, err := eval(s.operand, symbols, s.address)
+			if err != nil {
+				return &Error{s.line, err.Error()}
+			}
+			if !known {
+				return &Error{s.line, fmt.Sprintf("%s must be defined before use", s.operand)}
+			}
+			symbols[s.name] = uint16(v)
+		case s.name == ".ORG":
+			v, known, err := eval(s.operand, symbols, s.address)
+			if err != nil {
+				return &Error{s.line, err.Error()}
+			}
+			if !known {
+				return &Error{s.line, fmt.Sprintf("%s must be defined before use", s.operand)}
+			}
+			if v < 0 || v > 0xFFFF {
+				return &Error{s.line, fmt.Sprintf("address out of range: %d", v)}
+			}
+			pc = uint32(v)
+			s.address = uint16(pc)
+		case s.name == ".BYTE":
+			args, err := splitArgs(s.operand)
+			if err != nil {
+				return &Error{s.line, err.Error()}
+			}
+			for _, arg := range args {
+				if str, ok := unquote(arg); ok {
+					s.size += len(str)
+				} else {
+					s.size++
+				}
+			}
+		case s.name == ".WORD":
+			args, err := splitArgs(s.operand)
+			if err != nil {
+				return &Error{s.line, err.Error()}
+			}
+			s.size = 2 * len(args)
+		case strings.HasPrefix(s.name, "."):
+			return &Error{s.line, fmt.Sprintf("unknown directive %s", s.name)}
+		default:
+			mode, err := a.selectMode(s, symbols)
+			if err != nil {
+				return &Error{s.line, err.Error()}
+			}
+			s.mode, s.size = mode, modeSize(mode)
+		}
+		pc += uint32(s.size)
+		if pc > 0x10000 {
+			return &Error{s.line, "program exceeds $FFFF"}
+		}
+	}
+	return nil
+}
+
+// selectMode decides the addressing mode from the operand syntax.
+// 前方参照のアドレスはゼロページか分からないので絶対アドレスとみなす
+func (a *Assembler) selectMode(s *statement, symbols map[string]uint16) (string, error) {
+	modes, ok := a.opcodes[s.name]
+	if !ok {
+		return "", fmt.Errorf("unknown instruction %s", s.name)
+	}
+	syntax, expr := operandSyntax(s.operand)
+	var candidates []string
+	switch syntax {
+	case Implied:
+		candidates = []string{Implied, Accumulator}
+	case Accumulator:
+		candidates = []string{Accumulator}
+	case Immediate, Indirect, IndirectX, IndirectY:
+		candidates = []string{syntax}
+	default:
+		zp, abs := ZeroPage, Absolute
+		switch syntax {
+		case "X":
+			zp, abs = ZeroPageX, AbsoluteX
+		case "Y":
+			zp, abs = ZeroPageY, AbsoluteY
+		default:
+			if _, ok := modes[Relative]; ok {
+				return Relative, nil
+			}
+		}
+		v, known, err := eval(expr, symbols, s.address)
+		if err != nil {
+			return "", err
+		}
+		if known && 0 <= v && v <= 0xFF {
+			candidates = []string{zp, abs}
+		} else {
+			candidates = []string{abs, zp}
+		}
+	}
+	for _, mode := range candidates {
+		if _, ok := modes[mode]; ok {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("%s does not support operand %q", s.name, s.operand)
+}
+
+// emit writes statements into memory.
+func (a *Assembler) emit(stmts []*statement, symbols map[string]uint16) (*Program, error) {
+	var mem [0x10000]byte
+	low, high := 0x10000, -1
+	put := func(address uint16, data ...byte) {
+		for i, b := range data {
+			addr := int(address) + i
+			mem[addr] = b
+			if addr < low {
+				low = addr
+			}
+			if addr > high {
+				high = addr
+			}
+		}
+	}
+	value := func(s *statement, expr string, max int) (int, error) {
+		v, known, err := eval(expr, symbols, s.address)
+		if err != nil {
+			return 0, err
+		}
+		if !known {
+			return 0, fmt.Errorf("undefined symbol in %s", expr)
+		}
+		// 負数は2の補数として扱う
+		if v < -(max+1)/2 || v > max {
+			return 0, fmt.Errorf("value out of range: %s", expr)
+		}
+		return v & max, nil
+	}
+
+	for _, s := range stmts {
+		switch {
+		case s.name == "", s.isConst, s.name == ".ORG":
+		case s.name == ".BYTE":
+			args, _ := splitArgs(s.operand)
+			addr := s.address
+			for _, arg := range args {
+				if str, ok := unquote(arg); ok {
+					put(addr, []byte(str)...)
+					addr += uint16(len(str))
+					continue
+				}
+				v, err := value(s, arg, 0xFF)
+				if err != nil {
+					return nil, &Error{s.line, err.Error()}
+				}
+				put(addr, byte(v))
+				addr++
+			}
+		case s.name == ".WORD":
+			args, _ := splitArgs(s.operand)
+			for i, arg := range args {
+				v, err := value(s, arg, 0xFFFF)
+				if err != nil {
+					return nil, &Error{s.line, err.Error()}
+				}
+				put(s.address+uint16(2*i), byte(v), byte(v>>8))
+			}
+		default:
+			op := a.opcodes[s.name][s.mode]
+			_, expr := operandSyntax(s.operand)
+			switch s.size {
+			case 1:
+				put(s.address, op.Code)
+			case 2:
+				v, err := value(s, expr, 0xFF)
+				if s.mode == Relative {
+					v, err = branchOffset(s, expr, symbols)
+				}
+				if err != nil {
+					return nil, &Error{s.line, err.Error()}
+				}
+				put(s.address, op.Code, byte(v))
+			case 3:
+				v, err := value(s, expr, 0xFFFF)
+				if err != nil {
+					return nil, &Error{s.line, err.Error()}
+				}
+				put(s.address, op.Code, byte(v), byte(v>>8))
+			}
+		}
+	}
+
+	p := &Program{Symbols: symbols}
+	if high >= low {
+		p.Origin = uint16(low)
+		p.Bytes = append([]byte{}, mem[low:high+1]...)
+	}
+	return p, nil
+}
+
+// branchOffset calculates the relative offset from the next instruction to expr.
+func branchOffset(s *statement, expr string, symbols map[string]uint16) (int, error) {
+	v, known, err := eval(expr, symbols, s.address)
+	if err != nil {
+		return 0, err
+	}
+	if !known {
+		return 0, fmt.Errorf("undefined symbol in %s", expr)
+	}
+	offset := v - (int(s.address) + 2)
+	if offset < -128 || offset > 127 {
+		return 0, fmt.Errorf("branch out of range: %s", expr)
+	}
+	return offset & 0xFF, nil
+}
+
+func modeSize(mode string) int {
+	switch mode {
+	case Implied, Accumulator:
+		return 1
+	case Absolute, AbsoluteX, AbsoluteY, Indirect:
+		return 3
+	}
+	return 2
+}
+
+// operandSyntax classifies operand. It returns the addressing mode for explicit syntax,
+// "X"/"Y" for indexed addresses and "" for a plain address, with the expression part.
+func operandSyntax(operand string) (string, string) {
+	upper := strings.ToUpper(operand)
+	switch {
+	case operand == "":
+		return Implied, ""
+	case upper == "A":
+		return Accumulator, ""
+	case strings.HasPrefix(operand, "#"):
+		return Immediate, operand[1:]
+	case strings.HasPrefix(operand, "(") && strings.HasSuffix(upper, ",X)"):
+		return IndirectX, operand[1 : len(operand)-3]
+	case strings.HasPrefix(operand, "(") && strings.HasSuffix(upper, "),Y"):
+		return IndirectY, operand[1 : len(operand)-3]
+	case strings.HasPrefix(operand, "(") && strings.HasSuffix(operand, ")"):
+		return Indirect, operand[1 : len(operand)-1]
+	case strings.HasSuffix(upper, ",X"):
+		return "X", operand[:len(operand)-2]
+	case strings.HasSuffix(upper, ",Y"):
+		return "Y", operand[:len(operand)-2]
+	}
+	return "", operand
+}
+
+// parse splits src into statements.
+func parse(src string) []*statement {
+	var stmts []*statement
+	for i, text := range strings.Split(src, "\n") {
+		s := &statement{line: i + 1}
+		text = strings.TrimSpace(stripComment(text))
+
+		// ラベル
+		if n := strings.Index(text, ":"); n > 0 && isIdent(text[:n]) {
+			s.label, text = text[:n], strings.TrimSpace(text[n+1:])
+		}
+		if text == "" {
+			if s.label != "" {
+				stmts = append(stmts, s)
+			}
+			continue
+		}
+
+		// 定数 name = expr
+		if n := strings.Index(text, "="); n > 0 && isIdent(strings.TrimSpace(text[:n])) {
+			s.name, s.operand, s.isConst = strings.TrimSpace(text[:n]), strings.TrimSpace(text[n+1:]), true
+			stmts = append(stmts, s)
+			continue
+		}
+
+		name, operand := text, ""
+		if n := strings.IndexAny(text, " \t"); n >= 0 {
+			name, operand = text[:n], strings.TrimSpace(text[n+1:])
+		}
+		s.name = strings.ToUpper(name)
+		if strings.HasPrefix(s.name, ".") {
+			s.operand = operand
+		} else {
+			// 命令のオペランドは空白を含まない
+			s.operand = strings.Join(strings.Fields(operand), "")
+		}
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+// stripComment removes a comment outside of string literals.
+func stripComment(text string) string {
+	quoted := false
+	for i, r := range text {
+		switch r {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return text[:i]
+			}
+		}
+	}
+	return text
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case i > 0 && '0' <= r && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// splitArgs splits comma separated arguments of directives.
+func splitArgs(operand string) ([]string, error) {
+	var args []string
+	quoted, start := false, 0
+	for i, r := range operand {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			args = append(args, strings.TrimSpace(operand[start:i]))
+			start = i + 1
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated string: %s", operand)
+	}
+	args = append(args, strings.TrimSpace(operand[start:]))
+	for _, arg := range args {
+		if arg == "" {
+			return nil, fmt.Errorf("empty argument: %q", operand)
+		}
+	}
+	return args, nil
+}
+
+func unquote(arg string) (string, bool) {
+	if len(arg) >= 2 && strings.HasPrefix(arg, `"`) && strings.HasSuffix(arg, `"`) {
+		return arg[1 : len(arg)-1], true
+	}
+	return "", false
+}
+
+// eval evaluates expr. known is false if expr refers to an undefined symbol.
+func eval(expr string, symbols map[string]uint16, pc uint16) (v int, known bool, err error) {
+	expr = strings.Join(strings.Fields(expr), "")
+	if expr == "" {
+		return 0, false, fmt.Errorf("missing expression")
+	}
+	// <は下位byte, >は上位byte
+	var byteSelect byte
+	if expr[0] == '<' || expr[0] == '>' {
+		byteSelect, expr = expr[0], expr[1:]
+	}
+
+	known = true
+	sign, start := 1, 0
+	for i := 0; i <= len(expr); i++ {
+		// 先頭以外の+と-で項を区切る
+		if i < len(expr) && (i == start || (expr[i] != '+' && expr[i] != '-')) {
+			continue
+		}
+		term, ok, err := evalTerm(expr[start:i], symbols, pc)
+		if err != nil {
+			return 0, false, err
+		}
+		known = known && ok
+		v += sign * term
+		if i < len(expr) {
+			sign = 1
+			if expr[i] == '-' {
+				sign = -1
+			}
+		}
+		start = i + 1
+	}
+
+	switch byteSelect {
+	case '<':
+		v &= 0xFF
+	case '>':
+		v = v >> 8 & 0xFF
+	}
+	return v, known, nil
+}
+
+func evalTerm(term string, symbols map[string]uint16, pc uint16) (int, bool, error) {
+	var base int
+	digits := term
+	switch {
+	case term == "":
+		return 0, false, fmt.Errorf("missing operand")
+	case term == "*":
+		return int(pc), true, nil
+	case term[0] == '-':
+		v, known, err := evalTerm(term[1:], symbols, pc)
+		return -v, known, err
+	case term[0] == '$':
+		base, digits = 16, term[1:]
+	case term[0] == '%':
+		base, digits = 2, term[1:]
+	case '0' <= term[0] && term[0] <= '9':
+		base = 10
+	case isIdent(term):
+		v, ok := symbols[term]
+		return int(v), ok, nil
+	default:
+		return 0, false, fmt.Errorf("invalid expression: %s", term)
+	}
+	v, err := strconv.ParseUint(digits, base, 16)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid number: %s", term)
+	}
+	return int(v), true, nil
+}
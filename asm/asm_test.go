@@ -0,0 +1,175 @@
+package asm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/yusukemisa/gones/asm"
+	"github.com/yusukemisa/gones/cpu"
+)
+
+func opcodes() []asm.Opcode {
+	var set []asm.Opcode
+	for _, op := range cpu.Opcodes() {
+		set = append(set, asm.Opcode(op))
+	}
+	return set
+}
+
+func TestAssembler_Assemble(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name       string
+		src        string
+		unofficial bool
+		wantOrigin uint16
+		want       []byte
+	}{
+		{"implied", "NOP", false, 0x8000, []byte{0xEA}},
+		{"accumulator", "ASL A\nLSR", false, 0x8000, []byte{0x0A, 0x4A}},
+		{"immediate", "LDA #$10", false, 0x8000, []byte{0xA9, 0x10}},
+		{"immediate low/high", "LDA #<$1234\nLDX #>$1234", false, 0x8000, []byte{0xA9, 0x34, 0xA2, 0x12}},
+		{"zeroPage", "STA $10", false, 0x8000, []byte{0x85, 0x10}},
+		{"zeroPageX", "LDA $10,X", false, 0x8000, []byte{0xB5, 0x10}},
+		{"zeroPageY", "LDX $10,Y", false, 0x8000, []byte{0xB6, 0x10}},
+		{"absolute", "STA $0200", false, 0x8000, []byte{0x8D, 0x00, 0x02}},
+		{"absoluteX", "LDA $0300,X", false, 0x8000, []byte{0xBD, 0x00, 0x03}},
+		{"absoluteY", "LDA $0300, y", false, 0x8000, []byte{0xB9, 0x00, 0x03}},
+		{"absoluteY only", "LDA $10,Y", false, 0x8000, []byte{0xB9, 0x10, 0x00}},
+		{"indirect", "JMP ($0200)", false, 0x8000, []byte{0x6C, 0x00, 0x02}},
+		{"indirectX", "LDA ($80,X)", false, 0x8000, []byte{0xA1, 0x80}},
+		{"indirectY", "LDA ($89),Y", false, 0x8000, []byte{0xB1, 0x89}},
+		{"relative", "loop: DEX\nBNE loop", false, 0x8000, []byte{0xCA, 0xD0, 0xFD}},
+		{"relative forward", "BEQ done\nNOP\ndone: RTS", false, 0x8000, []byte{0xF0, 0x01, 0xEA, 0x60}},
+		{"forward reference is absolute", "LDA zp\nzp = $10", false, 0x8000, []byte{0xAD, 0x10, 0x00}},
+		{"constant", "PPUCTRL = $2000\nSTA PPUCTRL", false, 0x8000, []byte{0x8D, 0x00, 0x20}},
+		{"constant zeroPage", "ptr = $10\nLDA (ptr),Y\nSTA ptr+1", false, 0x8000, []byte{0xB1, 0x10, 0x85, 0x11}},
+		{"current address", "JMP *", false, 0x8000, []byte{0x4C, 0x00, 0x80}},
+		{"org", ".org $C000\nRTS", false, 0xC000, []byte{0x60}},
+		{"byte", `.byte $01, 2, %11, "NES", -1`, false, 0x8000, []byte{0x01, 0x02, 0x03, 'N', 'E', 'S', 0xFF}},
+		{"word", "start: .word start, $1234", false, 0x8000, []byte{0x00, 0x80, 0x34, 0x12}},
+		{"gap", ".org $8000\nNOP\n.org $8003\nRTS", false, 0x8000, []byte{0xEA, 0x00, 0x00, 0x60}},
+		{"comment", "NOP ; LDA #$10\n; RTS", false, 0x8000, []byte{0xEA}},
+		{"lower case", "lda #$10\nsta $0200,x", false, 0x8000, []byte{0xA9, 0x10, 0x9D, 0x00, 0x02}},
+		{"unofficial", "LAX $10\nNOP $10\nSBC #$01", true, 0x8000, []byte{0xA7, 0x10, 0x04, 0x10, 0xE9, 0x01}},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var opts []asm.Option
+			if tt.unofficial {
+				opts = append(opts, asm.WithUnofficial())
+			}
+			p, err := asm.New(opcodes(), opts...).Assemble(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, got := tt.wantOrigin, p.Origin; want != got {
+				t.Errorf("origin: want=%#04x, got=%#04x", want, got)
+			}
+			if diff := cmp.Diff(tt.want, p.Bytes); diff != "" {
+				t.Errorf("bytes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAssembler_Assemble_symbols(t *testing.T) {
+	t.Parallel()
+	p, err := asm.New(opcodes()).Assemble(`
+		.org $C000
+reset:	LDX #$00
+loop:	INX
+		BNE loop
+		JMP reset
+nmi:	RTI
+		.org $FFFA
+		.word nmi, reset, reset
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]uint16{"reset": 0xC000, "loop": 0xC002, "nmi": 0xC008}
+	if diff := cmp.Diff(want, p.Symbols); diff != "" {
+		t.Errorf("symbols mismatch (-want +got):\n%s", diff)
+	}
+	if want, got := 0x10000-0xC000, len(p.Bytes); want != got {
+		t.Errorf("size: want=%v, got=%v", want, got)
+	}
+	if diff := cmp.Diff([]byte{0x08, 0xC0, 0x00, 0xC0, 0x00, 0xC0}, p.Bytes[len(p.Bytes)-6:]); diff != "" {
+		t.Errorf("vectors mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAssembler_Assemble_error(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name     string
+		src      string
+		wantLine int
+	}{
+		{"unknown instruction", "NOP\nFOO", 2},
+		{"unofficial disabled", "LAX $10", 1},
+		{"unsupported mode", "STA #$10", 1},
+		{"undefined symbol", "JMP nowhere", 1},
+		{"duplicate label", "a: NOP\na: NOP", 2},
+		{"branch out of range", "BNE far\n.org $8100\nfar: RTS", 1},
+		{"value out of range", "LDA #$100", 1},
+		{"unknown directive", ".foo 1", 1},
+		{"invalid number", "LDA #$GG", 1},
+		{"unterminated string", `.byte "abc`, 1},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := asm.New(opcodes()).Assemble(tt.src)
+			var asmErr *asm.Error
+			if !errors.As(err, &asmErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want, got := tt.wantLine, asmErr.Line; want != got {
+				t.Errorf("line: want=%v, got=%v (%v)", want, got, err)
+			}
+		})
+	}
+}
+
+func TestProgram_INES(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name    string
+		src     string
+		wantPRG int
+	}{
+		{"16KB", ".org $C000\nRTS\n.org $FFFC\n.word $C000", 1},
+		{"32KB", ".org $8000\nRTS\n.org $FFFC\n.word $8000", 2},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := asm.New(opcodes()).Assemble(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			image, err := p.INES(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, got := 0x10+tt.wantPRG*0x4000+0x2000, len(image); want != got {
+				t.Fatalf("size: want=%v, got=%v", want, got)
+			}
+			if diff := cmp.Diff([]byte{'N', 'E', 'S', 0x1A, byte(tt.wantPRG), 1}, image[:6]); diff != "" {
+				t.Errorf("header mismatch (-want +got):\n%s", diff)
+			}
+			if want, got := byte(0x60), image[0x10]; want != got {
+				t.Errorf("first byte: want=%#02x, got=%#02x", want, got)
+			}
+			// リセットベクタはPRGの末尾
+			prgEnd := 0x10 + tt.wantPRG*0x4000
+			if diff := cmp.Diff([]byte{byte(p.Origin), byte(p.Origin >> 8)}, image[prgEnd-4:prgEnd-2]); diff != "" {
+				t.Errorf("reset vector mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package asm
+
+import "fmt"
+
+// INES returns an iNES image with mapper 0 (NROM) whose PRG-ROM is the program.
+// The program must be in $8000-$FFFF. PRG-ROM is 16KB if the program fits in $C000-$FFFF, otherwise 32KB.
+// chr is padded to 8KB.
+func (p *Program) INES(chr []byte) ([]byte, error) {
+	end := int(p.Origin) + len(p.Bytes)
+	if len(p.Bytes) == 0 || p.Origin < 0x8000 {
+		return nil, fmt.Errorf("program must be in $8000-$FFFF: origin=$%04X", p.Origin)
+	}
+	if len(chr) > 0x2000 {
+		return nil, fmt.Errorf("CHR-ROM exceeds 8KB: %d bytes", len(chr))
+	}
+
+	base, banks := 0x8000, 2
+	if p.Origin >= 0xC000 {
+		base, banks = 0xC000, 1
+	}
+	// 0-3: "NES" $1A, 4: PRGのサイズ(16KB単位), 5: CHRのサイズ(8KB単位)
+	header := []byte{0x4E, 0x45, 0x53, 0x1A, byte(banks), 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	prg := make([]byte, banks*0x4000)
+	copy(prg[int(p.Origin)-base:end-base], p.Bytes)
+	image := append(header, prg...)
+	return append(image, append(chr, make([]byte, 0x2000-len(chr))...)...), nil
+}
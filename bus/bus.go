@@ -32,6 +32,26 @@ type Bus struct {
 	ppu    *ppu.PPU
 
 	joyPad1 *joypad.Joypad
+
+	// 無視したPRG-ROMへの最後の書き込み. IgnoredWriteで取り出す
+	ignoredWrite error
+	// OAM DMAでCPUを停止させるcycle数. CPUがTakeStallで取り出す
+	stall int
+}
+
+// oamDMACycles is the number of cycles CPU is suspended by OAM DMA($4014).
+// 256回の読み込みと書き込みに512cycle、開始前に1cycle待つ. 奇数cycleで始まる場合はCPUがさらに1cycle足す
+const oamDMACycles = 513
+
+// ErrPRGWrite records a write to PRG-ROM. The write is ignored and CPU keeps running
+// because real cartridges ignore it too, or latch it in mapper registers.
+type ErrPRGWrite struct {
+	Address uint16
+	Data    byte
+}
+
+func (e *ErrPRGWrite) Error() string {
+	return fmt.Sprintf("attempt to write %#02x to PRG-ROM at %#04x", e.Data, e.Address)
 }
 
 func NewBus(rom *rom.Rom, ppu *ppu.PPU) *Bus {
@@ -43,6 +63,23 @@ func NewBus(rom *rom.Rom, ppu *ppu.PPU) *Bus {
 	}
 }
 
+// PowerOn initializes RAM and PPU to the power-up state.
+func (b *Bus) PowerOn() {
+	for i := range b.cpuRAM {
+		b.cpuRAM[i] = 0
+	}
+	if b.ppu != nil {
+		b.ppu.PowerOn()
+	}
+}
+
+// Reset resets devices connected to the reset line. RAM is not changed.
+func (b *Bus) Reset() {
+	if b.ppu != nil {
+		b.ppu.Reset()
+	}
+}
+
 func (b *Bus) Read(address uint16) byte {
 	// 0x0000～0x07FF	0x0800	WRAM
 	// 0x0100～0x01FF   スタックポインタ
@@ -57,7 +94,11 @@ func (b *Bus) Read(address uint16) byte {
 	// 0x2000～0x2007	0x0008	PPU レジスタ
 	// 0x2008～0x3FFF	-	    PPUレジスタのミラー
 	if 0x2000 <= address && address < 0x4000 {
-		switch address {
+		switch address & 0b0010_0000_0000_0111 {
+		case 0x2002:
+			return b.ppu.ReadStatus()
+		case 0x2004:
+			return b.ppu.ReadOAMData()
 		case 0x2007:
 			return b.ppu.Read()
 		}
@@ -74,14 +115,50 @@ func (b *Bus) Read(address uint16) byte {
 	}
 	// 0x8000～0xBFFF	0x4000	PRG-ROM
 	// 0xC000～0xFFFF	0x4000	PRG-ROM
-	if 0x8000 <= address && address < 0xFFFF {
-		address -= 0x8000
-		mirrorDownAddress := address & 0b0011_1111_1111_1111
+	if 0x8000 <= address {
+		// PRGが16KBの場合0xC000～は0x8000～のミラー
+		mirrorDownAddress := (address - 0x8000) % uint16(len(b.rom.PRG))
 		return b.rom.ReadPRG(mirrorDownAddress)
 	}
 	return 0
 }
 
+// Peek reads address without side effects.
+// PPUレジスタやジョイパッドは状態を変えずに読める値のみ返す
+func (b *Bus) Peek(address uint16) byte {
+	if 0x2000 <= address && address < 0x4000 {
+		switch address & 0b0010_0000_0000_0111 {
+		case 0x2002:
+			return b.ppu.PeekStatus()
+		case 0x2004:
+			// OAMDATAは読んでも状態が変わらない
+			return b.ppu.ReadOAMData()
+		}
+		return 0
+	}
+	if address == 0x4016 || address == 0x4017 {
+		return 0
+	}
+	return b.Read(address)
+}
+
+// PPUPosition returns the scanline and dot of PPU for CPU trace.
+func (b *Bus) PPUPosition() (scanline, dot int) {
+	if b.ppu == nil {
+		return 0, 0
+	}
+	return b.ppu.Position()
+}
+
+// Bank returns the 16KB PRG bank mapped at address, or -1 if address is not PRG.
+func (b *Bus) Bank(address uint16) int {
+	if address < 0x8000 || b.rom == nil || len(b.rom.PRG) == 0 {
+		return -1
+	}
+	// PRGが16KBの場合0xC000～はミラー
+	return int(address-0x8000) / 0x4000 % (len(b.rom.PRG) / 0x4000)
+}
+
 func (b *Bus) Write(address uint16, data byte) {
 	if 0 <= address && address < 0x2000 {
 		mirrorDownAddress := address & 0b0000_0111_1111_1111
@@ -89,29 +166,63 @@ func (b *Bus) Write(address uint16, data byte) {
 		return
 	}
 	if 0x2000 <= address && address < 0x4000 {
-		switch address {
+		// 0x2008～0x3FFFは8byteごとのミラー
+		switch address & 0b0010_0000_0000_0111 {
 		case 0x2000:
 			b.ppu.WriteControl(data)
 		case 0x2001:
 			b.ppu.WriteMask(data)
+		case 0x2002:
+			// PPUSTATUSは読み込み専用なので書き込みは無視する
+		case 0x2003:
+			b.ppu.WriteOAMAddress(data)
+		case 0x2004:
+			b.ppu.WriteOAMData(data)
 		case 0x2005:
 			b.ppu.WriteScroll(data)
 		case 0x2006:
 			b.ppu.WriteAddress(data)
 		case 0x2007:
 			b.ppu.WriteData(data)
-		default:
-			mirrorDownAddress := address & 0b0010_0000_0000_0111
-			//fmt.Printf("mirrorDownAddress:%#04x,%#04x\n", mirrorDownAddress, address)
-			b.Write(mirrorDownAddress, data)
 		}
 		return
 	}
-	if address == 0x4016 {
+	switch address {
+	case 0x4014:
+		b.writeOAMDMA(data)
+		return
+	case 0x4016:
 		b.joyPad1.Write(data)
+		return
+	}
+	if 0x8000 <= address {
+		b.ignoredWrite = &ErrPRGWrite{Address: address, Data: data}
 	}
-	if 0x8000 <= address && address < 0xFFFF {
-		panic(fmt.Sprintf("attempt to write to PRG rom:%#04v", address))
+	// APUのレジスタ、拡張ROM、拡張RAMは未実装なので書き込みは捨てる
+}
+
+// writeOAMDMA copies 256 bytes from page $XX00 to OAM through OAMDATA($2004).
+// 転送はOAMADDRの位置から始まり、CPUはその間停止する.
+// DMAの読み込みはCPUを経由しないため、CPUのメモリフック(ウォッチポイントやCDL)には通知されない
+func (b *Bus) writeOAMDMA(page byte) {
+	base := uint16(page) << 8
+	for i := uint16(0); i < 0x100; i++ {
+		b.ppu.WriteOAMData(b.Read(base | i))
 	}
-	fmt.Printf("unexpected memory addresses=%#04v, data=%#02x\n", address, data)
+	b.stall = oamDMACycles
+}
+
+// TakeStall returns and clears cycles of OAM DMA started since the last call. It implements cpu.Staller.
+func (b *Bus) TakeStall() int {
+	stall := b.stall
+	b.stall = 0
+	return stall
+}
+
+// IgnoredWrite returns and clears the last write ignored by PRG-ROM, or nil.
+// CPUは止まらないので、実行ループやデバッガが命令ごとに確認する
+func (b *Bus) IgnoredWrite() error {
+	err := b.ignoredWrite
+	b.ignoredWrite = nil
+	return err
 }
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/yusukemisa/gones/cpu"
 	"github.com/yusukemisa/gones/ppu"
+	"github.com/yusukemisa/gones/rom"
 )
 
 func TestBus_Read(t *testing.T) {
@@ -47,7 +50,11 @@ func TestBus_Write(t *testing.T) {
 	} {
 		tt := tt
 		t.Run(fmt.Sprintf("Write:address=%#04x,data=%#02x", tt.address, tt.data), func(t *testing.T) {
-			bus := NewBus(nil, ppu.NewPPU([]byte{}, true))
+			p, err := ppu.NewPPU([]byte{}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bus := NewBus(nil, p)
 			if want, got := byte(0), bus.Read(tt.address); want != got {
 				t.Errorf("want=%v, got=%v", want, got)
 			}
@@ -58,3 +65,209 @@ func TestBus_Write(t *testing.T) {
 		})
 	}
 }
+
+func TestBus_Write_PPU(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		address  uint16
+		data     byte
+		wantCTRL byte
+	}{
+		{"PPUCTRL", 0x2000, 0x80, 0x80},
+		{"PPUCTRL mirror", 0x3FF8, 0x80, 0x80},
+		// PPUSTATUSへの書き込みは無視する
+		{"PPUSTATUS", 0x2002, 0xFF, 0x00},
+		{"PPUSTATUS mirror", 0x200A, 0xFF, 0x00},
+		{"PPUSTATUS last mirror", 0x3FFA, 0xFF, 0x00},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := ppu.NewPPU([]byte{}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bus := NewBus(nil, p)
+			bus.Write(tt.address, tt.data)
+			state := p.State()
+			if want, got := tt.wantCTRL, state.CTRL; want != got {
+				t.Errorf("PPUCTRL: want=%#02x, got=%#02x", want, got)
+			}
+			if want, got := byte(0), state.STATUS; want != got {
+				t.Errorf("PPUSTATUS: want=%#02x, got=%#02x", want, got)
+			}
+		})
+	}
+}
+
+func TestBus_Peek(t *testing.T) {
+	t.Parallel()
+
+	p, err := ppu.NewPPU([]byte{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Run(241*341 + 1) // vblank開始
+	bus := NewBus(nil, p)
+	bus.Write(0x0010, 0xAA)
+
+	if want, got := byte(0xAA), bus.Peek(0x0810); want != got {
+		t.Errorf("RAM: want=%#02x, got=%#02x", want, got)
+	}
+	// Peekではvblankフラグはクリアされない
+	for i := 0; i < 2; i++ {
+		if got := bus.Peek(0x2002); got&0x80 == 0 {
+			t.Errorf("PPUSTATUS: vblank flag should be set, got=%#02x", got)
+		}
+	}
+	if got := bus.Read(0x2002); got&0x80 == 0 {
+		t.Errorf("PPUSTATUS: vblank flag should be set, got=%#02x", got)
+	}
+	if got := bus.Peek(0x2002); got&0x80 != 0 {
+		t.Errorf("PPUSTATUS: vblank flag should be cleared by Read, got=%#02x", got)
+	}
+}
+
+func TestBus_Bank(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name    string
+		prg     int
+		address uint16
+		want    int
+	}{
+		{"RAM", 1, 0x0000, -1},
+		{"16KB:0x8000", 1, 0x8000, 0},
+		{"16KB:mirror", 1, 0xC000, 0},
+		{"32KB:0xBFFF", 2, 0xBFFF, 0},
+		{"32KB:0xC000", 2, 0xC000, 1},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			bus := NewBus(&rom.Rom{PRG: make([]byte, tt.prg*0x4000)}, nil)
+			if want, got := tt.want, bus.Bank(tt.address); want != got {
+				t.Errorf("want=%v, got=%v", want, got)
+			}
+		})
+	}
+}
+
+func TestBus_Read_PRG(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name    string
+		prg     int
+		address uint16
+		want    byte
+	}{
+		{"16KB:0x8000", 1, 0x8000, 0x00},
+		{"16KB:mirror", 1, 0xFFFF, 0x3F},
+		{"32KB:0xBFFF", 2, 0xBFFF, 0x3F},
+		{"32KB:0xFFFF", 2, 0xFFFF, 0x7F},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			prg := make([]byte, tt.prg*0x4000)
+			for i := range prg {
+				prg[i] = byte(i >> 8)
+			}
+			bus := NewBus(&rom.Rom{PRG: prg}, nil)
+			if want, got := tt.want, bus.Read(tt.address); want != got {
+				t.Errorf("want=%#02x, got=%#02x", want, got)
+			}
+		})
+	}
+}
+
+func TestBus_Write_OAMDMA(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name       string
+		oamAddress byte
+		page       byte
+	}{
+		{"OAMADDR=0", 0x00, 0x02},
+		// 転送はOAMADDRから始まり、256byteで一周する
+		{"OAMADDR=4", 0x04, 0x02},
+		{"OAMADDR=FC", 0xFC, 0x02},
+		// $0A00は$0200のミラー
+		{"mirrored RAM", 0x04, 0x0A},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := ppu.NewPPU(make([]byte, 0x2000), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bus := NewBus(nil, p)
+			for i := 0; i < 0x100; i++ {
+				bus.Write(0x0200+uint16(i), byte(i))
+			}
+			bus.Write(0x2003, tt.oamAddress)
+			bus.Write(0x4014, tt.page)
+
+			var want [0x100]byte
+			for i := range want {
+				want[byte(i+int(tt.oamAddress))] = byte(i)
+			}
+			if diff := cmp.Diff(want, p.OAM()); diff != "" {
+				t.Errorf("OAM mismatch (-want +got):\n%s", diff)
+			}
+			// 256回書き込んだのでOAMADDRは元の位置に戻る
+			if want, got := byte(0x00), bus.Read(0x2004); want != got {
+				t.Errorf("OAMDATA: want=%#02x, got=%#02x", want, got)
+			}
+			if want, got := 513, bus.TakeStall(); want != got {
+				t.Errorf("stall: want=%v, got=%v", want, got)
+			}
+			if want, got := 0, bus.TakeStall(); want != got {
+				t.Errorf("stall must be cleared: want=%v, got=%v", want, got)
+			}
+		})
+	}
+}
+
+func TestBus_Write_PRG(t *testing.T) {
+	t.Parallel()
+
+	bus := NewBus(&rom.Rom{PRG: make([]byte, 0x4000)}, nil)
+	bus.Write(0x8010, 0xAA)
+	if want, got := byte(0), bus.Read(0x8010); want != got {
+		t.Errorf("PRG-ROM must not be written: want=%#02x, got=%#02x", want, got)
+	}
+	if diff := cmp.Diff(&ErrPRGWrite{Address: 0x8010, Data: 0xAA}, bus.IgnoredWrite()); diff != "" {
+		t.Errorf("ignored write mismatch (-want +got):\n%s", diff)
+	}
+	if err := bus.IgnoredWrite(); err != nil {
+		t.Errorf("IgnoredWrite must be cleared: %v", err)
+	}
+}
+
+func TestBus_Write_PRG_cpu(t *testing.T) {
+	t.Parallel()
+
+	prg := make([]byte, 0x4000)
+	copy(prg, []byte{
+		0x8D, 0x00, 0x80, // STA $8000
+		0xEA, // NOP
+	})
+	c := cpu.NewCPU(NewBus(&rom.Rom{PRG: prg}, nil))
+	c.Register().PC = 0x8000
+	c.Run()
+	c.Run()
+	// PRG-ROMへの書き込みではCPUを止めない
+	if err := c.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := uint16(0x8004), c.Register().PC; want != got {
+		t.Errorf("PC: want=%#04x, got=%#04x", want, got)
+	}
+}
@@ -3,7 +3,6 @@ package canvas
 import (
 	"fmt"
 	"image/color"
-	"os"
 	"unsafe"
 
 	"github.com/veandco/go-sdl2/sdl"
@@ -26,8 +25,10 @@ type SDL2Canvas struct {
 }
 
 // Setup Window / Renderer / texture
-func (s *SDL2Canvas) Setup(title string, windowWidth int, windowHeight int) {
-	sdl.Init(sdl.INIT_EVERYTHING)
+func (s *SDL2Canvas) Setup(title string, windowWidth int, windowHeight int) error {
+	if s.err = sdl.Init(sdl.INIT_EVERYTHING); s.err != nil {
+		return fmt.Errorf("failed to initialize SDL: %w", s.err)
+	}
 
 	var flags uint32 = sdl.WINDOW_SHOWN
 
@@ -43,22 +44,19 @@ func (s *SDL2Canvas) Setup(title string, windowWidth int, windowHeight int) {
 		flags,
 	)
 	if s.err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create Window: %s\n", s.err)
-		os.Exit(1)
+		return fmt.Errorf("failed to create window: %w", s.err)
 	}
 
 	s.Renderer, s.err = sdl.CreateRenderer(s.window, -1, sdl.RENDERER_ACCELERATED)
 	if s.err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create Renderer: %s\n", s.err)
-		os.Exit(1)
+		return fmt.Errorf("failed to create renderer: %w", s.err)
 	}
 
 	s.texture, s.err = s.Renderer.CreateTexture(
 		sdl.PIXELFORMAT_RGB24, sdl.TEXTUREACCESS_STREAMING,
 		int32(windowWidth), int32(windowHeight))
 	if s.err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create texture: %s\n", s.texture)
-		os.Exit(1)
+		return fmt.Errorf("failed to create texture: %w", s.err)
 	}
 
 	//s.pixels = InitPixels()
@@ -82,6 +80,7 @@ func (s *SDL2Canvas) Setup(title string, windowWidth int, windowHeight int) {
 	//s.Update()
 	//s.Render()
 	s.Running = true
+	return nil
 }
 
 func (s *SDL2Canvas) SetPixel(x int, y int, c *color.RGBA) {
@@ -0,0 +1,207 @@
+// Package cdl implements a code/data logger which writes the .cdl format of FCEUX.
+//
+// A .cdl file has a byte per PRG-ROM byte followed by a byte per CHR-ROM byte.
+// https://fceux.com/web/help/CodeDataLogger.html
+package cdl
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/ppu"
+)
+
+// Flag is a byte of a .cdl file.
+type Flag byte
+
+// PRG-ROM flags
+//
+//	xPdcAADC
+const (
+	// Code is set for opcodes and operands of executed instructions.
+	Code Flag = 0x01
+	// Data is set for bytes read by instructions.
+	Data Flag = 0x02
+	// bankMask is the 8KB window where the byte was mapped when it was last accessed.
+	// 00: $8000-$9FFF, 01: $A000-$BFFF, 10: $C000-$DFFF, 11: $E000-$FFFF
+	bankMask Flag = 0x0C
+	// IndirectCode is set for the destination of JMP ($nnnn).
+	IndirectCode Flag = 0x10
+	// IndirectData is set for bytes read by ($nn,X) and ($nn),Y.
+	IndirectData Flag = 0x20
+)
+
+// CHR-ROM flags
+//
+//	xxxxxxRD
+const (
+	// Rendered is set for pattern bytes fetched by PPU to render.
+	Rendered Flag = 0x01
+	// Read is set for bytes read through PPUDATA($2007).
+	Read Flag = 0x02
+)
+
+// Logger marks PRG-ROM and CHR-ROM bytes as code or data.
+type Logger struct {
+	prg    []Flag
+	chr    []Flag
+	opcode []bool // 命令の先頭のbyte. .cdlには保存されない
+
+	bank func(address uint16) int
+
+	inst         cpu.Instruction // 実行中の命令
+	indirectJump bool            // 直前の命令がJMP ($nnnn)
+}
+
+// New creates a logger for PRG-ROM and CHR-ROM of the size.
+func New(prgSize, chrSize int) *Logger {
+	return &Logger{
+		prg:    make([]Flag, prgSize),
+		chr:    make([]Flag, chrSize),
+		opcode: make([]bool, prgSize),
+	}
+}
+
+// Attach logs accesses of c and p. Hooks and the tracer already set are kept and called before the logger.
+// p may be nil.
+func (l *Logger) Attach(c *cpu.CPU, p *ppu.PPU) {
+	l.bank = c.Bank
+	if t := c.Tracer(); t != nil {
+		c.SetTracer(cpu.MultiTracer(t, l))
+	} else {
+		c.SetTracer(l)
+	}
+	c.SetMemoryHook(chain(c.MemoryHook(), l.CPUAccess))
+	if p != nil {
+		p.SetMemoryHook(ppu.MemoryHook(chain(cpu.MemoryHook(p.MemoryHook()), l.PPUAccess)))
+		p.SetRenderHook(l.Rendered)
+	}
+}
+
+func chain(prev cpu.MemoryHook, h cpu.MemoryHook) cpu.MemoryHook {
+	if prev == nil {
+		return h
+	}
+	return func(address uint16, data byte, write bool) {
+		prev(address, data, write)
+		h(address, data, write)
+	}
+}
+
+// offset returns the offset in PRG-ROM mapped at address.
+func (l *Logger) offset(address uint16) (int, bool) {
+	if address < 0x8000 || l.bank == nil {
+		return 0, false
+	}
+	bank := l.bank(address)
+	if bank < 0 {
+		return 0, false
+	}
+	// Bankは16KB単位
+	offset := bank*0x4000 + int(address&0x3FFF)
+	return offset, offset < len(l.prg)
+}
+
+// mark sets f to the PRG byte at address with the 8KB window.
+func (l *Logger) mark(address uint16, f Flag) (int, bool) {
+	offset, ok := l.offset(address)
+	if ok {
+		l.prg[offset] = l.prg[offset]&^bankMask | f | Flag(address>>13&0x03)<<2
+	}
+	return offset, ok
+}
+
+// Trace implements cpu.Tracer. It marks the executed instruction as code.
+func (l *Logger) Trace(s *cpu.TraceState) {
+	l.inst = s.Instruction
+	if offset, ok := l.mark(s.PC, Code); ok {
+		l.opcode[offset] = true
+	}
+	if l.indirectJump {
+		l.mark(s.PC, IndirectCode)
+	}
+	for i := 1; i < len(s.Instruction.Bytes); i++ {
+		l.mark(s.PC+uint16(i), Code)
+	}
+	l.indirectJump = s.Instruction.Name == "JMP" && s.Instruction.Mode == "Indirect"
+}
+
+// CPUAccess is a cpu.MemoryHook marking PRG bytes read by instructions as data.
+func (l *Logger) CPUAccess(address uint16, data byte, write bool) {
+	if write {
+		return
+	}
+	// 即値はオペランドのアドレスから読むのでデータとして数えない
+	if inst := l.inst; address-inst.Address < uint16(len(inst.Bytes)) {
+		return
+	}
+	f := Data
+	switch l.inst.Mode {
+	case "IndirectX", "IndirectY":
+		// ポインタはゼロページにあるのでPRGの読み込みは実効アドレス
+		f |= IndirectData
+	}
+	l.mark(address, f)
+}
+
+// PPUAccess is a ppu.MemoryHook marking CHR bytes read through PPUDATA.
+func (l *Logger) PPUAccess(address uint16, data byte, write bool) {
+	if !write && int(address) < len(l.chr) {
+		l.chr[address] |= Read
+	}
+}
+
+// Rendered is a ppu.RenderHook marking a 16 byte tile as rendered.
+func (l *Logger) Rendered(address uint16) {
+	for i := int(address); i < int(address)+0x10 && i < len(l.chr); i++ {
+		l.chr[i] |= Rendered
+	}
+}
+
+// PRG returns the flag of the PRG-ROM byte at offset.
+func (l *Logger) PRG(offset int) Flag {
+	return l.prg[offset]
+}
+
+// CHR returns the flag of the CHR-ROM byte at offset.
+func (l *Logger) CHR(offset int) Flag {
+	return l.chr[offset]
+}
+
+// Opcode reports whether the PRG-ROM byte at offset was executed as an opcode.
+// .cdl cannot distinguish opcodes from operands, so this is not saved.
+func (l *Logger) Opcode(offset int) bool {
+	return l.opcode[offset]
+}
+
+// Load merges a .cdl file written before, e.g. to log over several sessions.
+func (l *Logger) Load(r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if want := len(l.prg) + len(l.chr); len(b) != want {
+		return fmt.Errorf("cdl size mismatch: want=%d, got=%d", want, len(b))
+	}
+	for i, f := range b[:len(l.prg)] {
+		l.prg[i] |= Flag(f)
+	}
+	for i, f := range b[len(l.prg):] {
+		l.chr[i] |= Flag(f)
+	}
+	return nil
+}
+
+// WriteTo writes the .cdl file.
+func (l *Logger) WriteTo(w io.Writer) (int64, error) {
+	b := make([]byte, 0, len(l.prg)+len(l.chr))
+	for _, f := range l.prg {
+		b = append(b, byte(f))
+	}
+	for _, f := range l.chr {
+		b = append(b, byte(f))
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
@@ -0,0 +1,164 @@
+package cdl
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/yusukemisa/gones/asm"
+	"github.com/yusukemisa/gones/bus"
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/ppu"
+	"github.com/yusukemisa/gones/rom"
+)
+
+const program = `
+		.org $8000
+reset:	LDX #$01
+		LDA table,X  ; $8051はデータ
+		LDA #$40
+		STA $00
+		LDA #$80
+		STA $01
+		LDY #$02
+		LDA ($00),Y  ; $8042は間接参照のデータ
+		LDA #$00
+		STA $2006
+		LDA #$10
+		STA $2006
+		LDA $2007    ; CHRの$0010, $0011を読む
+		LDA $2007
+		JMP (vector) ; $8052-$8053はデータ, $8024は間接参照のコード
+target:	JMP target
+		.org $8040
+table2:	.byte $01, $02, $03, $04
+		.org $8050
+table:	.byte $AA, $BB
+vector:	.word target
+		.org $FFFC
+		.word reset, reset
+`
+
+// run runs program until PPU renders a frame.
+func run(t *testing.T, hook cpu.MemoryHook) *Logger {
+	t.Helper()
+	var set []asm.Opcode
+	for _, op := range cpu.Opcodes() {
+		set = append(set, asm.Opcode(op))
+	}
+	a, err := asm.New(set).Assemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prg := make([]byte, 0x8000)
+	copy(prg[a.Origin-0x8000:], a.Bytes)
+	chr := make([]byte, 0x2000)
+
+	p, err := ppu.NewPPU(chr, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cpu.NewCPU(bus.NewBus(&rom.Rom{PRG: prg, CHR: chr}, p))
+	c.SetMemoryHook(hook)
+	l := New(len(prg), len(chr))
+	l.Attach(c, p)
+	c.PowerOn()
+	for p.Frame() == 0 {
+		p.Run(c.Run() * 3)
+	}
+	return l
+}
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+	reads := 0
+	l := run(t, func(address uint16, data byte, write bool) {
+		if !write {
+			reads++
+		}
+	})
+	if reads == 0 {
+		t.Error("the memory hook set before Attach must be called")
+	}
+
+	t.Run("PRG", func(t *testing.T) {
+		t.Parallel()
+		for _, tt := range []struct {
+			name   string
+			offset int
+			want   Flag
+			opcode bool
+		}{
+			{"opcode", 0x0000, Code, true},
+			{"immediate operand", 0x0001, Code, false},
+			{"absolute operand", 0x0003, Code, false},
+			{"not executed", 0x0030, 0, false},
+			{"data", 0x0051, Data, false},
+			{"not read", 0x0050, 0, false},
+			{"indirect data", 0x0042, Data | IndirectData, false},
+			{"jump vector", 0x0052, Data, false},
+			{"indirect code", 0x0024, Code | IndirectCode, true},
+			{"reset vector in $E000-$FFFF", 0x7FFC, Data | 0x0C, false},
+		} {
+			if diff := cmp.Diff(tt.want, l.PRG(tt.offset)); diff != "" {
+				t.Errorf("%s: flag mismatch (-want +got):\n%s", tt.name, diff)
+			}
+			if want, got := tt.opcode, l.Opcode(tt.offset); want != got {
+				t.Errorf("%s: opcode: want=%v, got=%v", tt.name, want, got)
+			}
+		}
+	})
+
+	t.Run("CHR", func(t *testing.T) {
+		t.Parallel()
+		for _, tt := range []struct {
+			offset int
+			want   Flag
+		}{
+			{0x0000, Rendered}, // ネームテーブルは0なのでタイル0を描画する
+			{0x000F, Rendered},
+			{0x0010, Read},
+			{0x0011, Read},
+			{0x0012, 0},
+		} {
+			if want, got := tt.want, l.CHR(tt.offset); want != got {
+				t.Errorf("CHR(%#04x): want=%#02x, got=%#02x", tt.offset, want, got)
+			}
+		}
+	})
+}
+
+func TestLogger_WriteTo(t *testing.T) {
+	t.Parallel()
+	l := run(t, nil)
+	var buf bytes.Buffer
+	if _, err := l.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if want, got := 0x8000+0x2000, len(b); want != got {
+		t.Fatalf("size: want=%d, got=%d", want, got)
+	}
+	if want, got := byte(Data|IndirectData), b[0x0042]; want != got {
+		t.Errorf("PRG: want=%#02x, got=%#02x", want, got)
+	}
+	if want, got := byte(Read), b[0x8000+0x0010]; want != got {
+		t.Errorf("CHR: want=%#02x, got=%#02x", want, got)
+	}
+
+	loaded := New(0x8000, 0x2000)
+	if err := loaded.Load(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	var again bytes.Buffer
+	if _, err := loaded.WriteTo(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, again.Bytes()) {
+		t.Error("loaded cdl must be written as is")
+	}
+
+	if err := New(0x4000, 0).Load(bytes.NewReader(b)); err == nil {
+		t.Error("error expected for size mismatch")
+	}
+}
@@ -1,28 +1,164 @@
 package cpu
 
 import (
+	"errors"
 	"fmt"
-	"log"
 
-	"github.com/yusukemisa/gones/bus"
 	"github.com/yusukemisa/gones/util"
 )
 
 type CPU struct {
 	register *Register
-	bus      *bus.Bus
+	bus      Memory
+
+	// strict がtrueの場合、非公式命令を実行せずにエラーとして停止する
+	strict bool
+	// detectTrap がtrueの場合、自分自身へのジャンプ・分岐を検出して停止する
+	detectTrap bool
+	variant    Variant
+	err        error
+
+	// 実行中の命令. インデックス付きアドレッシングのダミーリードの判定に使う
+	current *instruction
+	// 実行中の命令で発生した追加cycle
+	pageCrossed bool // インデックス付きアドレッシングでページをまたいだか
+	extraCycle  int  // 分岐成立などによる追加cycle
+	// DMAなどでCPUが停止するcycle数
+	stall int
+	// 電源投入からの累計cycle数
+	cycles uint64
+	// 割り込みハンドラへ入った回数(NMI, IRQ, BRK). トレーサーやデバッガがコールスタックの追跡に使う
+	interrupts uint64
+
+	// 割り込み
+	nmi         bool      // NMI要求
+	irq         IRQSource // IRQ線をアサートしているデバイス
+	brkExecuted bool      // 直前に実行した命令がBRKか. NMIによるBRKの乗っ取りに使う
+	// CLI/SEI/PLPは次の命令の実行後までIRQの受付に反映されないため、
+	// 直後の1回だけ変更前のIフラグでIRQを判定する
+	delayI      bool
+	delayedIVal bool
+
+	// 命令実行前に呼ばれるトレーサー. nilの場合トレースしない
+	tracer Tracer
+	// デバッガー用のメモリアクセスの通知先
+	memoryHook MemoryHook
+	// DMAでCPUを停止させるMemory. 実装していなければnil
+	staller Staller
 }
 
-func NewCPU(bus *bus.Bus) *CPU {
+// IRQSource identifies a device driving the IRQ line shared by mappers and APU.
+type IRQSource byte
+
+const (
+	IRQMapper IRQSource = 1 << iota
+	IRQFrameCounter
+	IRQDMC
+	IRQExternal
+)
+
+// Variant is a kind of 6502 core.
+type Variant int
+
+const (
+	// NES2A03 is the CPU of NES. Decimal flag is stored but ignored in ADC/SBC.
+	NES2A03 Variant = iota
+	// NMOS6502 is the generic NMOS 6502 with BCD arithmetic in decimal mode.
+	NMOS6502
+)
+
+// Option configures CPU.
+type Option func(*CPU)
+
+// WithStrictOpcodes makes CPU trap unofficial opcodes instead of executing them.
+// The trapped opcode is reported by Err.
+func WithStrictOpcodes() Option {
+	return func(c *CPU) {
+		c.strict = true
+	}
+}
+
+// WithVariant sets the kind of 6502 core. The default is NES2A03.
+func WithVariant(v Variant) Option {
+	return func(c *CPU) {
+		c.variant = v
+	}
+}
+
+// WithTrapDetection makes CPU stop when an instruction jumps or branches to itself.
+// Test ROMs such as Klaus Dormann's 6502_functional_test signal the result in this way.
+// The trapped address is reported by Err.
+func WithTrapDetection() Option {
+	return func(c *CPU) {
+		c.detectTrap = true
+	}
+}
+
+func NewCPU(bus Memory, opts ...Option) *CPU {
 	cpu := &CPU{
 		register: &Register{
 			P: 0b0010_0000,
 		},
 		bus: bus,
 	}
+	cpu.staller, _ = bus.(Staller)
+	for _, opt := range opts {
+		opt(cpu)
+	}
 	return cpu
 }
 
+// ErrUnofficialOpcode is reported when CPU meets an unofficial opcode in strict mode.
+type ErrUnofficialOpcode struct {
+	PC   uint16
+	Code byte
+	Name string
+}
+
+func (e *ErrUnofficialOpcode) Error() string {
+	return fmt.Sprintf("unofficial opcode %s(%#02x) at %#04x", e.Name, e.Code, e.PC)
+}
+
+// ErrUnknownOpcode is reported when CPU meets an opcode which is not implemented.
+type ErrUnknownOpcode struct {
+	PC   uint16
+	Code byte
+}
+
+func (e *ErrUnknownOpcode) Error() string {
+	return fmt.Sprintf("unknown opcode %#02x at %#04x", e.Code, e.PC)
+}
+
+// ErrJammed is reported when CPU executes a KIL (JAM) opcode.
+// The real CPU stops until reset, so Run does nothing until Reset or PowerOn.
+type ErrJammed struct {
+	PC   uint16
+	Code byte
+}
+
+func (e *ErrJammed) Error() string {
+	return fmt.Sprintf("CPU jammed by KIL(%#02x) at %#04x", e.Code, e.PC)
+}
+
+// isKIL reports whether code halts the CPU.
+// $x2 (x=0-7, 9, B, D, F)
+func isKIL(code byte) bool {
+	switch code {
+	case 0x02, 0x12, 0x22, 0x32, 0x42, 0x52, 0x62, 0x72, 0x92, 0xB2, 0xD2, 0xF2:
+		return true
+	}
+	return false
+}
+
+// ErrTrapped is reported when CPU detects a jump or branch to itself with trap detection.
+type ErrTrapped struct {
+	PC uint16
+}
+
+func (e *ErrTrapped) Error() string {
+	return fmt.Sprintf("trapped at %#04x", e.PC)
+}
+
 type Register struct {
 	A byte // アキュムレータ
 	X byte // インデックスレジスタ
@@ -46,41 +182,249 @@ type Register struct {
 	//bit6	V	オーバーフロー	演算結果がオーバーフローを起こした時にセット
 	//bit5	R	予約済み	        常にセットされている
 	//bit4	B	ブレークモード	BRK発生時にセット、IRQ発生時にクリア
-	//bit3	D	デシマルモード	0:デフォルト、1:BCDモード (未実装)
+	//bit3	D	デシマルモード	0:デフォルト、1:BCDモード (NES 2A03では無視される)
 	//bit2	I	IRQ禁止	        0:IRQ許可、1:IRQ禁止
 	//bit1	Z	ゼロ	            演算結果が0の時にセット
 	//bit0	C	キャリー	        キャリー発生時にセット
 	P byte
 }
 
+// interrupt vectors
+const (
+	nmiVector   = 0xFFFA
+	resetVector = 0xFFFC
+	irqVector   = 0xFFFE // BRKも同じベクタを使う
+
+	// 割り込みシーケンスにかかるcycle数
+	interruptCycle = 7
+)
+
+// bit number of status register
+const (
+	carryFlag     = 0 // C
+	zeroFlag      = 1 // Z
+	interruptFlag = 2 // I
+	decimalFlag   = 3 // D
+	breakFlag     = 4 // B
+	reservedFlag  = 5 // R
+	overflowFlag  = 6 // V
+	negativeFlag  = 7 // N
+)
+
 type instruction struct {
 	code        byte
 	name        string
-	mode        string
+	mode        addressingMode
 	description string
 	cycle       int
+	pageCycle   bool // インデックス付きの読み込みでページをまたいだ場合+1cycle
+	unofficial  bool // 非公式命令
+	handler     handler
 }
 
-func (c *CPU) Reset() {
+// PowerOn initializes CPU to the power-up state and jumps to the reset vector.
+// https://www.nesdev.org/wiki/CPU_power_up_state
+func (c *CPU) PowerOn() {
+	c.register.A, c.register.X, c.register.Y = 0, 0, 0
+	// S=0x00からリセットシーケンスで3回デクリメントされ0xFD
+	c.register.S = 0xFD
+	// Iのみセット(bit5は常にセット)
+	c.register.P = 0b0010_0100
+	c.clearState()
 	// 開始アドレスを取得しPCにセット
-	//l, h := uint16(c.read(0xFFFC)), uint16(c.read(0xFFFD))
-	//fmt.Printf("%#02x,%#02x\n", l, h)
-	//c.register.PC = l | h<<8
-	// なんかうまく行かないので固定で0x8000
-	c.register.PC = 0x8000
+	c.register.PC = c.readAddress(resetVector)
+	// リセットシーケンスに7cycleかかる
+	c.cycles = interruptCycle
+}
+
+// Reset performs the soft reset sequence triggered by the reset button.
+// A, X, Y are not changed and S is decremented by 3 without writing the stack.
+func (c *CPU) Reset() {
+	c.register.S -= 3
+	c.register.P = util.SetBit(c.register.P, interruptFlag)
+	c.clearState()
+	c.register.PC = c.readAddress(resetVector)
+	c.cycles += interruptCycle
+}
+
+// clearState clears pending interrupts, stall and error.
+// IRQ線はデバイスがアサートしているのでそのまま
+func (c *CPU) clearState() {
+	c.err = nil
+	c.stall = 0
+	c.nmi, c.brkExecuted, c.delayI = false, false, false
 }
 
 // Run is main processing in CPU
+// 1命令を実行し、実際に消費したcycle数を返す.
+// OAM DMAで停止したcycle数はDMAを開始した命令のcycle数に含まれる.
+// strictモードで非公式命令を検出した場合や、未実装の命令、KILでは停止し0を返す. 停止理由はErrで取得できる
 func (c *CPU) Run() int {
+	cycle := c.step()
+	c.cycles += uint64(cycle)
+	return cycle
+}
+
+// Cycles returns the number of cycles elapsed since power-on.
+func (c *CPU) Cycles() uint64 {
+	return c.cycles
+}
+
+// Interrupts returns the number of times CPU entered an interrupt handler by NMI, IRQ or BRK.
+// 前後の値を比べると、その間に割り込みでスタックに3byte積まれたかが分かる
+func (c *CPU) Interrupts() uint64 {
+	return c.interrupts
+}
+
+func (c *CPU) step() int {
+	if c.err != nil {
+		return 0
+	}
+	// DMAなどでCPUが停止している間は命令を実行しない
+	if c.stall > 0 {
+		stall := c.stall
+		c.stall = 0
+		return stall
+	}
+	// NMI、IRQは命令の境界で受け付ける
+	// 割り込みシーケンスはオペコードのフェッチを捨てるため、PCを2回読んでから退避する
+	if c.nmi {
+		c.nmi = false
+		c.brkExecuted, c.delayI = false, false
+		c.dummyRead(c.register.PC)
+		c.dummyRead(c.register.PC)
+		c.interrupt(nmiVector, false)
+		return interruptCycle
+	}
+	if c.irq != 0 && !c.irqDisabled() {
+		c.brkExecuted = false
+		c.dummyRead(c.register.PC)
+		c.dummyRead(c.register.PC)
+		c.interrupt(irqVector, false)
+		return interruptCycle
+	}
+	c.delayI = false
+
 	code := c.fetch()
-	inst, ok := opecodes[code]
-	if !ok {
-		log.Fatalf("opecode not found:%#02x", code)
+	inst := opecodes[code]
+	if inst == nil {
+		c.register.PC--
+		if isKIL(code) {
+			c.err = &ErrJammed{PC: c.register.PC, Code: code}
+		} else {
+			c.err = &ErrUnknownOpcode{PC: c.register.PC, Code: code}
+		}
+		return 0
+	}
+	if inst.unofficial && c.strict {
+		c.register.PC--
+		c.err = &ErrUnofficialOpcode{PC: c.register.PC, Code: code, Name: inst.name}
+		return 0
+	}
+
+	pc := c.register.PC - 1
+	if c.tracer != nil {
+		c.tracer.Trace(c.traceState(pc))
 	}
 
+	c.current, c.pageCrossed, c.extraCycle = inst, false, 0
+	i := util.TestBit(c.register.P, interruptFlag)
 	c.exec(inst)
-	// 分岐でcycle数変わる場合があるのでexecが返した方が良い
-	return inst.cycle
+	if c.detectTrap && c.register.PC == pc {
+		c.err = &ErrTrapped{PC: pc}
+	}
+	switch code {
+	case 0x00: // BRK
+		c.brkExecuted = true
+	case 0x58, 0x78, 0x28: // CLI, SEI, PLP
+		c.brkExecuted = false
+		c.delayI, c.delayedIVal = true, i
+	default:
+		c.brkExecuted = false
+	}
+
+	cycle := inst.cycle + c.extraCycle
+	if inst.pageCycle && c.pageCrossed {
+		cycle++
+	}
+	if c.staller != nil {
+		if stall := c.staller.TakeStall(); stall > 0 {
+			// DMAは書き込みの次のcycleから始まり、奇数cycleの場合は読み込みに揃えるため1cycle待つ
+			// デバッガのステップ実行で命令を実行しないRunが挟まらないよう、書き込んだ命令のcycleに含めて返す
+			if (c.cycles+uint64(cycle))%2 == 1 {
+				stall++
+			}
+			cycle += stall
+		}
+	}
+	return cycle
+}
+
+// NMI asserts the NMI line of CPU. PPU raises it at the start of vblank.
+// NMIは次のRunで処理される.
+// BRKの実行直後にNMIが来た場合、BRKの割り込みシーケンスが乗っ取られ、
+// スタックに積んだ内容(Bフラグ付き)はそのままにNMIベクタへジャンプする.
+func (c *CPU) NMI() {
+	if c.brkExecuted {
+		c.brkExecuted = false
+		c.register.PC = c.readAddress(nmiVector)
+		return
+	}
+	c.nmi = true
+}
+
+// AssertIRQ drives the IRQ line low on behalf of src.
+// IRQはレベルトリガなので、ClearIRQされるまで(Iフラグが立っていなければ)割り込みが発生し続ける.
+func (c *CPU) AssertIRQ(src IRQSource) {
+	c.irq |= src
+}
+
+// ClearIRQ releases the IRQ line driven by src.
+func (c *CPU) ClearIRQ(src IRQSource) {
+	c.irq &^= src
+}
+
+// irqDisabled reports whether IRQ is masked by I flag.
+func (c *CPU) irqDisabled() bool {
+	if c.delayI {
+		c.delayI = false
+		return c.delayedIVal
+	}
+	return util.TestBit(c.register.P, interruptFlag)
+}
+
+// interrupt pushes PC and P to stack and jumps to the address in vector.
+// brkがtrueの場合、スタックに積むPのBフラグを立てる
+func (c *CPU) interrupt(vector uint16, brk bool) {
+	c.pushAddressToStack(c.register.PC)
+	p := util.SetBit(c.register.P, reservedFlag)
+	if brk {
+		p = util.SetBit(p, breakFlag)
+	} else {
+		p = util.ClearBit(p, breakFlag)
+	}
+	c.pushByteToStack(p)
+	c.register.P = util.SetBit(c.register.P, interruptFlag)
+	c.register.PC = c.readAddress(vector)
+	c.interrupts++
+}
+
+// Stall suspends CPU for cycles. DMA uses this to steal cycles from CPU.
+// 停止したcycle数は次のRunの戻り値として返る.
+func (c *CPU) Stall(cycles int) {
+	c.stall += cycles
+}
+
+// Err returns the reason why CPU stopped, or nil if it is running.
+// Reset and PowerOn clear it.
+func (c *CPU) Err() error {
+	return c.err
+}
+
+// Jammed reports whether CPU is halted by a KIL opcode.
+func (c *CPU) Jammed() bool {
+	var jammed *ErrJammed
+	return errors.As(c.err, &jammed)
 }
 
 func (c *CPU) fetch() byte {
@@ -90,201 +434,245 @@ func (c *CPU) fetch() byte {
 }
 
 func (c *CPU) exec(inst *instruction) {
-	fmt.Printf("%04X, %#v,\n", c.register.PC-1, inst)
-	switch inst.name {
-	case "NOP":
-	case "JMP":
-		l, h := uint16(c.fetch()), uint16(c.fetch())
-		c.register.PC = l | h<<8
-	case "JSR":
-		// 今のPCをスタックに退避し、PC=MI16にする
-		l, h := uint16(c.fetch()), uint16(c.fetch())
-		c.pushAddressToStack(c.register.PC)
-		c.register.PC = l | h<<8
-	case "RTS":
-		// スタックから戻り番地を取得しPCに格納する
-		c.register.PC = c.popAddressFromStack()
-	case "PHP":
-		// ステータスのコピーをスタックに退避
-		c.pushByteToStack(c.register.P)
-	case "PHA":
-		// アキュムレーターのコピーをスタックに退避
-		c.pushByteToStack(c.register.A)
-	case "PLA":
-		// スタックからAにPull
-		c.register.A = c.popByteFromStack()
-		c.updateStatusRegister(c.register.A)
-	case "PLP":
-		// スタックからPにPull
-		c.register.P = c.popByteFromStack()
-	case "AND":
-		if inst.mode == "Immediate" {
-			c.register.A = c.register.A & c.fetch()
-			c.updateStatusRegister(c.register.A)
-		}
-	case "CMP":
-		if inst.mode == "Immediate" {
-			result := c.register.A - c.fetch()
-			if result >= 0 {
-				c.register.P = util.SetBit(c.register.P, 0)
-			}
-			c.updateStatusRegister(result)
-		}
-	case "SEC":
-		c.register.P = util.SetBit(c.register.P, 0)
-	case "CLC":
-		c.register.P = util.ClearBit(c.register.P, 0)
-	case "CLD":
-		// デシマルモードをOFF
-		// bit3を消す
-		c.register.P = util.ClearBit(c.register.P, 3)
-	case "SED":
-		// デシマルモードをON
-		// bit3を立てる
-		c.register.P = util.SetBit(c.register.P, 3)
-	case "SEI":
-		// IRQ割り込み禁止
-		// bit2を立てる
-		c.register.P = util.SetBit(c.register.P, 2)
-	case "BIT":
-		l, h := c.fetch(), byte(0x00)
-		addr := uint16(l | h<<8)
-		and := c.register.A & c.read(addr)
-		//fmt.Printf("l=%#02x, h=%#02x, addr=%#04x,and=%#02x, A=%#02x\n", l, h, addr, and, c.register.A)
-		if util.TestBit(and, 6) {
-			c.register.P = util.SetBit(c.register.P, 6)
-		} else {
-			c.register.P = util.ClearBit(c.register.P, 6)
-		}
-		c.updateStatusRegister(and)
-	case "LDX":
-		if inst.mode == "Immediate" {
-			c.register.X = c.fetch()
-			c.updateStatusRegister(c.register.X)
-		}
-	case "LDY":
-		if inst.mode == "Immediate" {
-			c.register.Y = c.fetch()
-			c.updateStatusRegister(c.register.Y)
-		}
-	case "LDA":
-		switch inst.mode {
-		case "Immediate":
-			c.register.A = c.fetch()
-		case "AbsoluteX":
-			//(IM16+X)番地の値をAにロード
-			l, h := uint16(c.fetch()), uint16(c.fetch())
-			addr := l | h<<8 + uint16(c.register.X)
-			c.register.A = c.read(addr)
-		}
-		c.updateStatusRegister(c.register.A)
-	case "STA":
-		if inst.mode == "Absolute" {
-			l, h := uint16(c.fetch()), uint16(c.fetch())
-			c.write(l|h<<8, c.register.A)
-		}
-		if inst.mode == "ZeroPage" {
-			l, h := uint16(c.fetch()), uint16(0x00)
-			c.write(l|h<<8, c.register.A)
-		}
-	case "STX":
-		if inst.mode == "ZeroPage" {
-			l, h := uint16(c.fetch()), uint16(0x00)
-			c.write(l|h<<8, c.register.X)
-		}
-	case "TXS":
-		c.register.S = c.register.X
-	case "INX":
-		c.register.X++
-		c.updateStatusRegister(c.register.X)
-	case "INC":
-		if inst.mode == "ZeroPageX" {
-			//fmt.Printf("PC=%#04x,X=%#04x\n", c.register.PC, uint16(c.register.X))
-			addr := c.register.PC + uint16(c.register.X)
-			c.write(addr, c.read(addr)+1)
-			c.updateStatusRegister(c.read(addr))
-		}
-	case "DEY":
-		c.register.Y--
-		c.updateStatusRegister(c.register.Y)
-	case "BCS":
-		if inst.mode == "Relative" {
-			relAddr := int8(c.fetch())
-			if util.TestBit(c.register.P, 0) {
-				addr := int(relAddr) + int(c.register.PC)
-				c.register.PC = uint16(addr)
-			}
-		}
-	case "BCC":
-		if inst.mode == "Relative" {
-			relAddr := int8(c.fetch())
-			if !util.TestBit(c.register.P, 0) {
-				addr := int(relAddr) + int(c.register.PC)
-				c.register.PC = uint16(addr)
-			}
-		}
-	case "BVS":
-		if inst.mode == "Relative" {
-			relAddr := int8(c.fetch())
-			if util.TestBit(c.register.P, 6) {
-				addr := int(relAddr) + int(c.register.PC)
-				c.register.PC = uint16(addr)
-			}
-		}
-	case "BVC":
-		if inst.mode == "Relative" {
-			relAddr := int8(c.fetch())
-			if !util.TestBit(c.register.P, 6) {
-				addr := int(relAddr) + int(c.register.PC)
-				c.register.PC = uint16(addr)
-			}
-		}
-	case "BPL":
-		if inst.mode == "Relative" {
-			relAddr := int8(c.fetch())
-			if !util.TestBit(c.register.P, 7) {
-				addr := int(relAddr) + int(c.register.PC)
-				c.register.PC = uint16(addr)
-			}
-		}
-	case "BMI":
-		if inst.mode == "Relative" {
-			relAddr := int8(c.fetch())
-			if util.TestBit(c.register.P, 7) {
-				addr := int(relAddr) + int(c.register.PC)
-				c.register.PC = uint16(addr)
-			}
-		}
-	case "BNE":
-		if inst.mode == "Relative" {
-			// 分岐するしないに関係なくPCが2byte回る必要ある
-			relAddr := int8(c.fetch())
-			if !util.TestBit(c.register.P, 1) {
-				// uint8で取得した値を-128~127の範囲にキャストしてアドレスを計算
-				// 0xFFの場合アドレスを-1することになる
-				addr := int(relAddr) + int(c.register.PC)
-				c.register.PC = uint16(addr)
-			}
-		}
-	case "BEQ": // ステータスレジスタのZがセットされている場合アドレス「PC + IM8」へジャンプ"
-		if inst.mode == "Relative" {
-			relAddr := int8(c.fetch())
-			if util.TestBit(c.register.P, 1) {
-				addr := int(relAddr) + int(c.register.PC)
-				c.register.PC = uint16(addr)
-			}
+	// オペランドのない命令も2cycle目で次のbyteを読んで捨てる. BRKはこれをパディングとして読み飛ばす
+	if inst.mode == implied || inst.mode == accumulator {
+		c.dummyRead(c.register.PC)
+	}
+	inst.handler(c, inst.mode)
+}
+
+// getOperandAddress returns the effective address of the operand and advances PC past it.
+// Immediateの場合はオペランド自身のアドレスを返す.
+func (c *CPU) getOperandAddress(mode addressingMode) uint16 {
+	switch mode {
+	case immediate:
+		addr := c.register.PC
+		c.register.PC++
+		return addr
+	case zeroPage:
+		return uint16(c.fetch())
+	case zeroPageX:
+		// 0x00FFを超えた場合はゼロページ内で折り返す
+		// インデックスを加算する1cycleの間、加算前のアドレスを読む
+		base := c.fetch()
+		c.dummyRead(uint16(base))
+		return uint16(base + c.register.X)
+	case zeroPageY:
+		base := c.fetch()
+		c.dummyRead(uint16(base))
+		return uint16(base + c.register.Y)
+	case absolute:
+		return c.fetchAddress()
+	case absoluteX:
+		return c.addIndex(c.fetchAddress(), c.register.X)
+	case absoluteY:
+		return c.addIndex(c.fetchAddress(), c.register.Y)
+	case indirect:
+		// JMP($xxFF)の場合、上位byteはページをまたがず$xx00から読む(6502のバグ)
+		ptr := c.fetchAddress()
+		l := uint16(c.read(ptr))
+		h := uint16(c.read(ptr&0xFF00 | uint16(byte(ptr)+1)))
+		return l | h<<8
+	case indirectX:
+		// (IM8+X)番地とその次の番地からアドレスを読む. ゼロページ内で折り返す
+		base := c.fetch()
+		c.dummyRead(uint16(base))
+		ptr := base + c.register.X
+		l, h := uint16(c.read(uint16(ptr))), uint16(c.read(uint16(ptr+1)))
+		return l | h<<8
+	case indirectY:
+		// IM8番地とその次の番地から読んだアドレスにYを加算
+		ptr := c.fetch()
+		l, h := uint16(c.read(uint16(ptr))), uint16(c.read(uint16(ptr+1)))
+		return c.addIndex(l|h<<8, c.register.Y)
+	}
+	panic(fmt.Sprintf("unsupported addressing mode:%v", mode))
+}
+
+// addIndex adds index register to base address and records whether page is crossed.
+// 下位byteだけ加算したアドレスを先に読み、ページをまたいだ場合は上位byteを直して読み直す.
+// 書き込みとリードモディファイライトは結果に関係なく常にこのダミーリードを行う
+func (c *CPU) addIndex(base uint16, index byte) uint16 {
+	addr := base + uint16(index)
+	c.pageCrossed = base&0xFF00 != addr&0xFF00
+	if c.pageCrossed || c.current == nil || !c.current.pageCycle {
+		c.dummyRead(base&0xFF00 | addr&0x00FF)
+	}
+	return addr
+}
+
+// fetchAddress fetches 2 bytes from PC as little endian address.
+func (c *CPU) fetchAddress() uint16 {
+	l, h := uint16(c.fetch()), uint16(c.fetch())
+	return l | h<<8
+}
+
+// readAddress reads 2 bytes from address as little endian address.
+func (c *CPU) readAddress(address uint16) uint16 {
+	l, h := uint16(c.read(address)), uint16(c.read(address+1))
+	return l | h<<8
+}
+
+// branch jumps to 「PC + IM8」 if cond is true.
+// 分岐するしないに関係なくPCが2byte回る必要ある
+func (c *CPU) branch(cond bool) {
+	// uint8で取得した値を-128~127の範囲にキャストしてアドレスを計算
+	// 0xFFの場合アドレスを-1することになる
+	relAddr := int8(c.fetch())
+	if cond {
+		addr := uint16(int(relAddr) + int(c.register.PC))
+		// 分岐成立で+1cycle、さらにページをまたぐと+1cycle
+		// 追加cycleではそれぞれ次の命令と、上位byteを直す前の分岐先を読んで捨てる
+		c.extraCycle++
+		c.dummyRead(c.register.PC)
+		if addr&0xFF00 != c.register.PC&0xFF00 {
+			c.extraCycle++
+			c.dummyRead(c.register.PC&0xFF00 | addr&0x00FF)
 		}
-	default:
-		fmt.Printf("unknown code:%#v\n", inst)
+		c.register.PC = addr
+	}
+}
+
+// add executes ADC. デシマルモードが有効な場合はBCDで加算する
+func (c *CPU) add(m byte) {
+	if c.decimalMode() {
+		c.addDecimal(m)
+		return
+	}
+	c.addWithCarry(m)
+}
+
+// subtract executes SBC. デシマルモードが有効な場合はBCDで減算する
+func (c *CPU) subtract(m byte) {
+	a, carry := c.register.A, util.TestBit(c.register.P, carryFlag)
+	// A - M - (1 - C) = A + ^M + C
+	// NMOS 6502ではデシマルモードでもフラグはバイナリの結果から決まる
+	c.addWithCarry(^m)
+	if c.decimalMode() {
+		c.register.A = subtractDecimal(a, m, carry)
+	}
+}
+
+// decimalMode reports whether ADC/SBC should use BCD arithmetic.
+// NES 2A03はDフラグを保持するだけでBCD演算回路を持たない
+func (c *CPU) decimalMode() bool {
+	return c.variant == NMOS6502 && util.TestBit(c.register.P, decimalFlag)
+}
+
+// addDecimal adds m and carry to A as BCD in the same way as NMOS 6502.
+// Zはバイナリの結果、NとVは上位桁の補正前の値から決まる.
+// http://www.6502.org/tutorials/decimal_mode.html
+func (c *CPU) addDecimal(m byte) {
+	a := c.register.A
+	var carry byte
+	if util.TestBit(c.register.P, carryFlag) {
+		carry = 1
+	}
+
+	lo := a&0x0F + m&0x0F + carry
+	if lo > 0x09 {
+		lo += 0x06
+	}
+	hi := a>>4 + m>>4
+	if lo > 0x0F {
+		hi++
+	}
+	c.setFlag(zeroFlag, a+m+carry == 0)
+	c.setFlag(negativeFlag, util.TestBit(hi, 3))
+	c.setFlag(overflowFlag, (a^hi<<4)&^(a^m)&0x80 != 0)
+	if hi > 0x09 {
+		hi += 0x06
+	}
+	c.setFlag(carryFlag, hi > 0x0F)
+	c.register.A = hi<<4 | lo&0x0F
+}
+
+// subtractDecimal returns a - m - (1 - carry) as BCD in the same way as NMOS 6502.
+func subtractDecimal(a, m byte, carry bool) byte {
+	var borrow byte = 1
+	if carry {
+		borrow = 0
+	}
+	lo := a&0x0F - m&0x0F - borrow
+	hi := a>>4 - m>>4
+	if util.TestBit(lo, 4) {
+		lo -= 0x06
+		hi--
+	}
+	if util.TestBit(hi, 4) {
+		hi -= 0x06
+	}
+	return hi<<4 | lo&0x0F
+}
+
+// addWithCarry adds m and carry to A. SBC also uses this with inverted m.
+func (c *CPU) addWithCarry(m byte) {
+	a := c.register.A
+	sum := uint16(a) + uint16(m)
+	if util.TestBit(c.register.P, carryFlag) {
+		sum++
+	}
+	result := byte(sum)
+	c.setFlag(carryFlag, sum > 0xFF)
+	// 同符号同士の演算結果の符号が変わった場合オーバーフロー
+	c.setFlag(overflowFlag, (a^result)&(m^result)&0x80 != 0)
+	c.register.A = result
+	c.updateStatusRegister(result)
+}
+
+// compare sets C, Z, N flags according to register - m.
+func (c *CPU) compare(register, m byte) {
+	c.setFlag(carryFlag, register >= m)
+	c.updateStatusRegister(register - m)
+}
+
+// setFlag sets n bit of status register if cond is true, otherwise clears it.
+func (c *CPU) setFlag(n byte, cond bool) {
+	if cond {
+		c.register.P = util.SetBit(c.register.P, n)
+	} else {
+		c.register.P = util.ClearBit(c.register.P, n)
 	}
-	//fmt.Printf("A:%#02x,X:%#02x,Y:%#02x,PC:%#04x\n", c.register.A, c.register.X, c.register.Y, c.register.PC)
 }
 
 func (c *CPU) write(address uint16, data byte) {
 	c.bus.Write(address, data)
+	if c.memoryHook != nil {
+		c.memoryHook(address, data, true)
+	}
 }
 
 func (c *CPU) read(address uint16) byte {
+	data := c.bus.Read(address)
+	if c.memoryHook != nil {
+		c.memoryHook(address, data, false)
+	}
+	return data
+}
+
+// dummyRead reads address and discards the data.
+// 6502はcycle毎に必ずバスにアクセスするため、値を使わないcycleでも読み込みが発生し
+// $2002や$2007などのレジスタには副作用がある. フェッチと同じくメモリフックには通知しない
+func (c *CPU) dummyRead(address uint16) {
+	c.bus.Read(address)
+}
+
+// dummyWrite writes data which is overwritten in the next cycle.
+// リードモディファイライトは変更前の値を書き戻してから結果を書く
+func (c *CPU) dummyWrite(address uint16, data byte) {
+	c.bus.Write(address, data)
+}
+
+// dummyReadStack reads the top of stack while S is updated.
+func (c *CPU) dummyReadStack() {
+	c.dummyRead(stackBase | uint16(c.register.S))
+}
+
+// peek reads address without side effects if the memory supports it.
+func (c *CPU) peek(address uint16) byte {
+	if p, ok := c.bus.(Peeker); ok {
+		return p.Peek(address)
+	}
 	return c.bus.Read(address)
 }
 
@@ -296,47 +684,45 @@ func (c *CPU) read(address uint16) byte {
 // TODO: 他のbitは0にも1にも更新しなくて良い？
 func (c *CPU) updateStatusRegister(result byte) {
 	// bit1	Z
-	if result == 0 {
-		c.register.P = util.SetBit(c.register.P, 1)
-	} else {
-		c.register.P = util.ClearBit(c.register.P, 1)
-	}
+	c.setFlag(zeroFlag, result == 0)
 
 	// Bit7 N
 	// Aの最上部bitの値とのORをとる
-	if util.TestBit(result, 7) {
-		c.register.P = util.SetBit(c.register.P, 7)
-	} else {
-		c.register.P = util.ClearBit(c.register.P, 7)
-	}
+	c.setFlag(negativeFlag, util.TestBit(result, 7))
 	//fmt.Printf("result=%#02x,Z=%v,N=%v\n", result, testBit(c.register.P, 1), testBit(c.register.P, 7))
 }
 
+// スタックは0x0100～0x01FFの256byte.
+// 0x0100|Sに書き込んでからSをデクリメントし、取り出す時はSをインクリメントしてから読む.
+// Sは0x00～0xFFで折り返すので1ページ内に収まる
+const stackBase = 0x0100
+
 func (c *CPU) pushByteToStack(b byte) {
-	c.write(0x0100+uint16(c.register.S), b)
-	c.register.S++
+	c.write(stackBase|uint16(c.register.S), b)
+	c.register.S--
 }
 
+// pushAddressToStack pushes address in order of high byte, low byte.
 func (c *CPU) pushAddressToStack(address uint16) {
-	l, h := address&0x00FF, address>>8
-	c.register.S++
-	c.write(0x00FF+uint16(c.register.S), byte(h))
-	c.register.S++
-	c.write(0x00FF+uint16(c.register.S), byte(l))
-	//fmt.Printf("pushAddressToStack:S=%#02x,l=%#02x,h=%#02x\n", c.register.S, l, h)
+	c.pushByteToStack(byte(address >> 8))
+	c.pushByteToStack(byte(address & 0x00FF))
 }
 
 func (c *CPU) popAddressFromStack() uint16 {
-	l := uint16(c.read(0x00FF + uint16(c.register.S)))
-	c.register.S--
-	h := uint16(c.read(0x00FF + uint16(c.register.S))) // l|h<<8
-	c.register.S--
-	//fmt.Printf("popAddressFromStack:S=%#02x,l=%#02x,h=%#02x\n", c.register.S, l, h)
+	l := uint16(c.popByteFromStack())
+	h := uint16(c.popByteFromStack())
 	return l | h<<8
 }
 
 func (c *CPU) popByteFromStack() byte {
-	b := c.read(0x0100 + uint16(c.register.S-1))
-	c.register.S--
-	return b
+	c.register.S++
+	return c.read(stackBase | uint16(c.register.S))
+}
+
+// popStatusFromStack pulls P from stack.
+// Bフラグはレジスタ上には存在しないので無視し、bit5は常にセットする
+func (c *CPU) popStatusFromStack() byte {
+	p := c.popByteFromStack()
+	p = util.ClearBit(p, breakFlag)
+	return util.SetBit(p, reservedFlag)
 }
@@ -0,0 +1,51 @@
+package cpu
+
+import (
+	"testing"
+	"time"
+)
+
+// nesClockRate is the CPU clock rate of NTSC NES (Hz).
+const nesClockRate = 1_789_773
+
+// benchProgram is a loop which uses common instructions and addressing modes.
+const benchProgram = `
+reset:	LDX #$00
+loop:	LDA $0200,X
+		ADC #$01
+		STA $0200,X
+		INX
+		BNE loop
+		JMP reset
+`
+
+// BenchmarkCPU_Run measures instructions per second and the speed ratio to the real NES.
+//
+// 命令の振り分けを文字列のswitchから256要素のテーブルに変えた際の比較.
+// 当時のexecは1命令ごとにfmt.Printfしていたため、その行を消して同じマシンで計測した:
+//
+//	$ git worktree add /tmp/switch 540ae39 && git worktree add /tmp/table 22cd7d1
+//	$ git show 22cd7d1:cpu/cpu_bench_test.go > /tmp/switch/cpu/cpu_bench_test.go
+//	$ sed -i '/fmt.Printf("%04X, %#v,\\n", c.register.PC-1, inst)/d' /tmp/switch/cpu/cpu.go /tmp/table/cpu/cpu.go
+//	$ cd /tmp/switch && go test -cpu 1 -run '^$' -bench CPU_Run -count 9 ./cpu/
+//
+//	switch (540ae39): ~50 ns/op, ~35x realtime
+//	table  (22cd7d1): ~23 ns/op, ~77x realtime
+//
+// Printfを残すとどちらも~2.9µs/opになり、フォーマットの時間しか測れない.
+func BenchmarkCPU_Run(b *testing.B) {
+	mem := &RAM{}
+	loadProgram(b, mem, benchProgram)
+	cpu := NewCPU(mem)
+	cpu.register.PC = 0x8000
+
+	b.ResetTimer()
+	start := time.Now()
+	var cycles int
+	for i := 0; i < b.N; i++ {
+		cycles += cpu.Run()
+	}
+	elapsed := time.Since(start).Seconds()
+	b.ReportMetric(float64(b.N)/elapsed, "inst/s")
+	b.ReportMetric(float64(cycles)/elapsed/nesClockRate, "x-realtime")
+}
@@ -1,13 +1,12 @@
 package cpu
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
-
-	"github.com/yusukemisa/gones/bus"
-	"github.com/yusukemisa/gones/rom"
+	"github.com/yusukemisa/gones/asm"
 )
 
 func TestCPU_memory(t *testing.T) {
@@ -28,13 +27,14 @@ func TestCPU_memory(t *testing.T) {
 			init: func(cpu *CPU) {
 				cpu.register.PC = 0x8000
 				cpu.register.P = 0b0000_0000
+				cpu.register.S = 0xFD
 			},
 			wantRegister: &Register{
 				PC: 0x8010,
-				S:  0x02,
+				S:  0xFB,
 			},
-			address:  []uint16{0x0100, 0x0101},
-			wantData: []byte{0x80, 0x02},
+			address:  []uint16{0x01FD, 0x01FC},
+			wantData: []byte{0x80, 0x01},
 		},
 		{
 			opecode: 0x60,
@@ -43,13 +43,15 @@ func TestCPU_memory(t *testing.T) {
 			init: func(cpu *CPU) {
 				cpu.register.PC = 0x8100
 				cpu.register.P = 0b0000_0000
-				cpu.pushAddressToStack(0x8010)
+				cpu.register.S = 0xFD
+				cpu.pushAddressToStack(0x800F)
 			},
 			wantRegister: &Register{
 				PC: 0x8010,
+				S:  0xFD,
 			},
-			address:  []uint16{0x0100, 0x0101},
-			wantData: []byte{0x80, 0x10},
+			address:  []uint16{0x01FD, 0x01FC},
+			wantData: []byte{0x80, 0x0F},
 		},
 		{
 			opecode: 0x08,
@@ -58,14 +60,15 @@ func TestCPU_memory(t *testing.T) {
 			init: func(cpu *CPU) {
 				cpu.register.PC = 0x8000
 				cpu.register.P = 0b0101_0101
+				cpu.register.S = 0xFD
 			},
 			wantRegister: &Register{
 				PC: 0x8000,
 				P:  0b0101_0101,
-				S:  0x01,
+				S:  0xFC,
 			},
-			address:  []uint16{0x0100},
-			wantData: []byte{0b0101_0101},
+			address:  []uint16{0x01FD},
+			wantData: []byte{0b0111_0101}, // BフラグとBit5がセットされる
 		},
 		{
 			opecode: 0x48,
@@ -75,11 +78,30 @@ func TestCPU_memory(t *testing.T) {
 				cpu.register.PC = 0x8000
 				cpu.register.A = 0x10
 				cpu.register.P = 0b0000_0000
+				cpu.register.S = 0xFD
+			},
+			wantRegister: &Register{
+				PC: 0x8000,
+				A:  0x10,
+				S:  0xFC,
+			},
+			address:  []uint16{0x01FD},
+			wantData: []byte{0x10},
+		},
+		{
+			opecode: 0x48,
+			name:    "PHA(wrap around)", // Sは1ページ内で折り返す
+			param:   []byte{},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.A = 0x10
+				cpu.register.P = 0b0000_0000
+				cpu.register.S = 0x00
 			},
 			wantRegister: &Register{
 				PC: 0x8000,
 				A:  0x10,
-				S:  0x01,
+				S:  0xFF,
 			},
 			address:  []uint16{0x0100},
 			wantData: []byte{0x10},
@@ -115,18 +137,220 @@ func TestCPU_memory(t *testing.T) {
 			},
 			wantRegister: &Register{
 				PC: 0x8000,
-				P:  0b1111_0000,
+				P:  0b1110_0000, // Bフラグは無視され、bit5はセットされる
 				S:  0x10,
 			},
 			address:  []uint16{0x0110},
 			wantData: []byte{0b1111_0000},
 		},
+		{
+			opecode: 0x40,
+			name:    "RTI", // スタックからPとPCを復帰
+			param:   []byte{},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8100
+				cpu.register.P = 0b0000_0100
+				cpu.register.S = 0xFD
+				cpu.pushAddressToStack(0x8010)
+				cpu.pushByteToStack(0b1101_0011)
+			},
+			wantRegister: &Register{
+				PC: 0x8010,
+				P:  0b1110_0011,
+				S:  0xFD,
+			},
+			address:  []uint16{0x01FD, 0x01FC, 0x01FB},
+			wantData: []byte{0x80, 0x10, 0b1101_0011},
+		},
+		{
+			opecode: 0x6C,
+			name:    "JMP_Indirect(page boundary bug)",
+			param:   []byte{0xFF, 0x02},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.P = 0b0000_0000
+				cpu.write(0x02FF, 0x34)
+				cpu.write(0x0200, 0x12)
+				cpu.write(0x0300, 0xFF)
+			},
+			wantRegister: &Register{
+				PC: 0x1234,
+			},
+			address:  []uint16{0x02FF, 0x0200},
+			wantData: []byte{0x34, 0x12},
+		},
+		{
+			opecode: 0xA1,
+			name:    "LDA_IndirectX",
+			param:   []byte{0xFE},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.P = 0b0000_0000
+				cpu.register.X = 0x01
+				cpu.write(0x00FF, 0x00)
+				cpu.write(0x0000, 0x02) // ゼロページ内で折り返す
+				cpu.write(0x0200, 0x80)
+			},
+			wantRegister: &Register{
+				A:  0x80,
+				X:  0x01,
+				PC: 0x8001,
+				P:  0b1000_0000,
+			},
+			address:  []uint16{0x0200},
+			wantData: []byte{0x80},
+		},
+		{
+			opecode: 0xB1,
+			name:    "LDA_IndirectY",
+			param:   []byte{0x10},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.P = 0b0000_0000
+				cpu.register.Y = 0x10
+				cpu.write(0x0010, 0xF8)
+				cpu.write(0x0011, 0x01)
+				cpu.write(0x0208, 0x42)
+			},
+			wantRegister: &Register{
+				A:  0x42,
+				Y:  0x10,
+				PC: 0x8001,
+			},
+			address:  []uint16{0x0208},
+			wantData: []byte{0x42},
+		},
+		{
+			opecode: 0x91,
+			name:    "STA_IndirectY",
+			param:   []byte{0x10},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.P = 0b0000_0000
+				cpu.register.A = 0x55
+				cpu.register.Y = 0x02
+				cpu.write(0x0010, 0x00)
+				cpu.write(0x0011, 0x03)
+			},
+			wantRegister: &Register{
+				A:  0x55,
+				Y:  0x02,
+				PC: 0x8001,
+			},
+			address:  []uint16{0x0302},
+			wantData: []byte{0x55},
+		},
+		{
+			opecode: 0x0E,
+			name:    "ASL_Absolute",
+			param:   []byte{0x00, 0x02},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.P = 0b0000_0000
+				cpu.write(0x0200, 0b1100_0001)
+			},
+			wantRegister: &Register{
+				PC: 0x8002,
+				P:  0b1000_0001,
+			},
+			address:  []uint16{0x0200},
+			wantData: []byte{0b1000_0010},
+		},
+		{
+			opecode: 0xC7, // DEC + CMP
+			name:    "DCP_ZeroPage",
+			param:   []byte{0x20},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.P = 0b0000_0000
+				cpu.register.A = 0x10
+				cpu.write(0x0020, 0x11)
+			},
+			wantRegister: &Register{
+				A:  0x10,
+				PC: 0x8001,
+				P:  0b0000_0011,
+			},
+			address:  []uint16{0x0020},
+			wantData: []byte{0x10},
+		},
+		{
+			opecode: 0xE7, // INC + SBC
+			name:    "ISB_ZeroPage",
+			param:   []byte{0x20},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.P = 0b0000_0001
+				cpu.register.A = 0x10
+				cpu.write(0x0020, 0x0F)
+			},
+			wantRegister: &Register{
+				A:  0x00,
+				PC: 0x8001,
+				P:  0b0000_0011,
+			},
+			address:  []uint16{0x0020},
+			wantData: []byte{0x10},
+		},
+		{
+			opecode: 0x07, // ASL + ORA
+			name:    "SLO_ZeroPage",
+			param:   []byte{0x20},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.P = 0b0000_0000
+				cpu.register.A = 0b0000_0001
+				cpu.write(0x0020, 0b1100_0000)
+			},
+			wantRegister: &Register{
+				A:  0b1000_0001,
+				PC: 0x8001,
+				P:  0b1000_0001,
+			},
+			address:  []uint16{0x0020},
+			wantData: []byte{0b1000_0000},
+		},
+		{
+			opecode: 0x87, // Store A AND X
+			name:    "SAX_ZeroPage",
+			param:   []byte{0x20},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.P = 0b0000_0000
+				cpu.register.A = 0b1111_0000
+				cpu.register.X = 0b0011_1100
+			},
+			wantRegister: &Register{
+				A:  0b1111_0000,
+				X:  0b0011_1100,
+				PC: 0x8001,
+			},
+			address:  []uint16{0x0020},
+			wantData: []byte{0b0011_0000},
+		},
+		{
+			opecode: 0xC6,
+			name:    "DEC_ZeroPage",
+			param:   []byte{0x20},
+			init: func(cpu *CPU) {
+				cpu.register.PC = 0x8000
+				cpu.register.P = 0b0000_0000
+				cpu.write(0x0020, 0x01)
+			},
+			wantRegister: &Register{
+				PC: 0x8001,
+				P:  0b0000_0010,
+			},
+			address:  []uint16{0x0020},
+			wantData: []byte{0x00},
+		},
 	} {
 		tt := tt
 		t.Run(fmt.Sprintf("code=%#02x:%s", tt.opecode, tt.name), func(t *testing.T) {
-			rom := &rom.Rom{PRG: tt.param}
+			mem := &RAM{}
+			mem.Load(0x8000, tt.param)
 
-			cpu := NewCPU(bus.NewBus(rom, nil))
+			cpu := NewCPU(mem)
 			tt.init(cpu)
 
 			cpu.exec(opecodes[tt.opecode])
@@ -440,7 +664,7 @@ func TestCPU_exec(t *testing.T) {
 			},
 			wantRegister: &Register{
 				X:  0x01,
-				PC: 0x0000,
+				PC: 0x0001,
 			},
 			address:  0x0001,
 			wantData: 0x01,
@@ -601,24 +825,1096 @@ func TestCPU_exec(t *testing.T) {
 				PC: 0x8011,
 			},
 		},
-	} {
-		tt := tt
-		t.Run(fmt.Sprintf("code=%#02x:%s", tt.opecode, tt.name), func(t *testing.T) {
-			rom := &rom.Rom{PRG: tt.param}
-
-			cpu := NewCPU(bus.NewBus(rom, nil))
-			cpu.register = tt.orgRegister
-
-			cpu.exec(opecodes[tt.opecode])
-			if diff := cmp.Diff(tt.wantRegister, cpu.register); diff != "" {
-				t.Errorf("register mismatch (-want +got):\n%s", diff)
-			}
-
-			if tt.address != 0 && tt.wantData != 0 {
-				if diff := cmp.Diff(tt.wantData, cpu.read(tt.address)); diff != "" {
-					t.Errorf("memory data mismatch (-want +got):\n%s", diff)
-				}
-			}
-		})
-	}
+		{
+			opecode: 0xD0,
+			name:    "BNE_Relative_backward",
+			param:   []byte{0xFC},
+			orgRegister: &Register{
+				PC: 0x8000,
+			},
+			wantRegister: &Register{
+				PC: 0x7FFD,
+			},
+		},
+		{
+			opecode: 0x69, // Add with Carry
+			name:    "ADC_Immediate(set V)",
+			param:   []byte{0x50},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0x50,
+			},
+			wantRegister: &Register{
+				A:  0xA0,
+				PC: 0x8001,
+				P:  0b1100_0000,
+			},
+		},
+		{
+			opecode: 0x69,
+			name:    "ADC_Immediate(with carry)",
+			param:   []byte{0x01},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0xFE,
+				P:  0b0000_0001,
+			},
+			wantRegister: &Register{
+				A:  0x00,
+				PC: 0x8001,
+				P:  0b0000_0011,
+			},
+		},
+		{
+			opecode: 0xE9, // Subtract with Carry
+			name:    "SBC_Immediate",
+			param:   []byte{0x01},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0x00,
+				P:  0b0000_0001,
+			},
+			wantRegister: &Register{
+				A:  0xFF,
+				PC: 0x8001,
+				P:  0b1000_0000,
+			},
+		},
+		{
+			opecode: 0xE9,
+			name:    "SBC_Immediate(set V)",
+			param:   []byte{0x01},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0x80,
+			},
+			wantRegister: &Register{
+				A:  0x7E,
+				PC: 0x8001,
+				P:  0b0100_0001,
+			},
+		},
+		{
+			opecode: 0x09, // Logical Inclusive OR
+			name:    "ORA_Immediate",
+			param:   []byte{0b0000_1111},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0b1111_0000,
+			},
+			wantRegister: &Register{
+				A:  0xFF,
+				PC: 0x8001,
+				P:  0b1000_0000,
+			},
+		},
+		{
+			opecode: 0x49, // Exclusive OR
+			name:    "EOR_Immediate",
+			param:   []byte{0xFF},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0xFF,
+			},
+			wantRegister: &Register{
+				A:  0x00,
+				PC: 0x8001,
+				P:  0b0000_0010,
+			},
+		},
+		{
+			opecode: 0x0A, // Arithmetic Shift Left
+			name:    "ASL_Accumulator",
+			param:   []byte{},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0b1000_0001,
+			},
+			wantRegister: &Register{
+				A:  0b0000_0010,
+				PC: 0x8000,
+				P:  0b0000_0001,
+			},
+		},
+		{
+			opecode: 0x4A, // Logical Shift Right
+			name:    "LSR_Accumulator",
+			param:   []byte{},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0b0000_0001,
+			},
+			wantRegister: &Register{
+				A:  0x00,
+				PC: 0x8000,
+				P:  0b0000_0011,
+			},
+		},
+		{
+			opecode: 0x2A, // Rotate Left
+			name:    "ROL_Accumulator",
+			param:   []byte{},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0b0100_0000,
+				P:  0b0000_0001,
+			},
+			wantRegister: &Register{
+				A:  0b1000_0001,
+				PC: 0x8000,
+				P:  0b1000_0000,
+			},
+		},
+		{
+			opecode: 0x6A, // Rotate Right
+			name:    "ROR_Accumulator",
+			param:   []byte{},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0b0000_0001,
+				P:  0b0000_0001,
+			},
+			wantRegister: &Register{
+				A:  0b1000_0000,
+				PC: 0x8000,
+				P:  0b1000_0001,
+			},
+		},
+		{
+			opecode: 0xE0, // Compare X Register
+			name:    "CPX_Immediate",
+			param:   []byte{0x10},
+			orgRegister: &Register{
+				PC: 0x8000,
+				X:  0x01,
+			},
+			wantRegister: &Register{
+				X:  0x01,
+				PC: 0x8001,
+				P:  0b1000_0000,
+			},
+		},
+		{
+			opecode: 0xC0, // Compare Y Register
+			name:    "CPY_Immediate",
+			param:   []byte{0x10},
+			orgRegister: &Register{
+				PC: 0x8000,
+				Y:  0x20,
+			},
+			wantRegister: &Register{
+				Y:  0x20,
+				PC: 0x8001,
+				P:  0b0000_0001,
+			},
+		},
+		{
+			opecode: 0xAA, // Transfer Accumulator to X
+			name:    "TAX_Implied",
+			param:   []byte{},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0x80,
+			},
+			wantRegister: &Register{
+				A:  0x80,
+				X:  0x80,
+				PC: 0x8000,
+				P:  0b1000_0000,
+			},
+		},
+		{
+			opecode: 0xBA, // Transfer Stack Pointer to X
+			name:    "TSX_Implied",
+			param:   []byte{},
+			orgRegister: &Register{
+				PC: 0x8000,
+				S:  0x00,
+				X:  0xFF,
+			},
+			wantRegister: &Register{
+				PC: 0x8000,
+				P:  0b0000_0010,
+			},
+		},
+		{
+			opecode: 0xB8, // Clear Overflow Flag
+			name:    "CLV_Implied",
+			param:   []byte{},
+			orgRegister: &Register{
+				PC: 0x8000,
+				P:  0b0100_0000,
+			},
+			wantRegister: &Register{
+				PC: 0x8000,
+			},
+		},
+		{
+			opecode: 0xAF, // LDA + LDX
+			name:    "LAX_Absolute",
+			param:   []byte{0x03, 0x80, 0x00, 0x80},
+			orgRegister: &Register{
+				PC: 0x8000,
+			},
+			wantRegister: &Register{
+				A:  0x80,
+				X:  0x80,
+				PC: 0x8002,
+				P:  0b1000_0000,
+			},
+		},
+		{
+			opecode: 0x0B, // AND + set C
+			name:    "ANC_Immediate",
+			param:   []byte{0x80},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0xFF,
+			},
+			wantRegister: &Register{
+				A:  0x80,
+				PC: 0x8001,
+				P:  0b1000_0001,
+			},
+		},
+		{
+			opecode: 0x4B, // AND + LSR
+			name:    "ALR_Immediate",
+			param:   []byte{0x03},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0xFF,
+			},
+			wantRegister: &Register{
+				A:  0x01,
+				PC: 0x8001,
+				P:  0b0000_0001,
+			},
+		},
+		{
+			opecode: 0x6B, // AND + ROR
+			name:    "ARR_Immediate",
+			param:   []byte{0xFF},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0x80,
+				P:  0b0000_0001,
+			},
+			wantRegister: &Register{
+				A:  0xC0,
+				PC: 0x8001,
+				P:  0b1100_0001,
+			},
+		},
+		{
+			opecode: 0xCB, // (A AND X) - M to X
+			name:    "AXS_Immediate",
+			param:   []byte{0x01},
+			orgRegister: &Register{
+				PC: 0x8000,
+				A:  0x0F,
+				X:  0xFF,
+			},
+			wantRegister: &Register{
+				A:  0x0F,
+				X:  0x0E,
+				PC: 0x8001,
+				P:  0b0000_0001,
+			},
+		},
+		{
+			opecode: 0x04, // No Operation
+			name:    "NOP_ZeroPage",
+			param:   []byte{0xFF},
+			orgRegister: &Register{
+				PC: 0x8000,
+			},
+			wantRegister: &Register{
+				PC: 0x8001,
+			},
+		},
+		{
+			opecode: 0xB6, // Load X Register
+			name:    "LDX_ZeroPageY",
+			param:   []byte{0x84},
+			orgRegister: &Register{
+				PC: 0x8000,
+				X:  0xFF,
+				Y:  0x01,
+			},
+			wantRegister: &Register{
+				Y:  0x01,
+				PC: 0x8001,
+				P:  0b0000_0010,
+			},
+		},
+	} {
+		tt := tt
+		t.Run(fmt.Sprintf("code=%#02x:%s", tt.opecode, tt.name), func(t *testing.T) {
+			mem := &RAM{}
+			mem.Load(0x8000, tt.param)
+
+			cpu := NewCPU(mem)
+			cpu.register = tt.orgRegister
+
+			cpu.exec(opecodes[tt.opecode])
+			if diff := cmp.Diff(tt.wantRegister, cpu.register); diff != "" {
+				t.Errorf("register mismatch (-want +got):\n%s", diff)
+			}
+
+			if tt.address != 0 && tt.wantData != 0 {
+				if diff := cmp.Diff(tt.wantData, cpu.read(tt.address)); diff != "" {
+					t.Errorf("memory data mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCPU_Run_unofficial(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name      string
+		opts      []Option
+		wantCycle int
+		wantPC    uint16
+		wantErr   bool
+	}{
+		{
+			name:      "execute",
+			wantCycle: 3,
+			wantPC:    0x8002,
+		},
+		{
+			name:      "strict",
+			opts:      []Option{WithStrictOpcodes()},
+			wantCycle: 0,
+			wantPC:    0x8000,
+			wantErr:   true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mem := &RAM{}
+			mem.Load(0x8000, []byte{0xA7, 0x10}) // LAX $10
+
+			cpu := NewCPU(mem, tt.opts...)
+			cpu.register.PC = 0x8000
+
+			if want, got := tt.wantCycle, cpu.Run(); want != got {
+				t.Errorf("cycle: want=%v, got=%v", want, got)
+			}
+			if want, got := tt.wantPC, cpu.register.PC; want != got {
+				t.Errorf("PC: want=%#04x, got=%#04x", want, got)
+			}
+
+			var opErr *ErrUnofficialOpcode
+			if got := errors.As(cpu.Err(), &opErr); got != tt.wantErr {
+				t.Fatalf("unexpected error: %v", cpu.Err())
+			}
+			if tt.wantErr {
+				if diff := cmp.Diff(&ErrUnofficialOpcode{PC: 0x8000, Code: 0xA7, Name: "LAX"}, opErr); diff != "" {
+					t.Errorf("error mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCPU_Run_unknown(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name       string
+		code       byte
+		wantErr    error
+		wantJammed bool
+	}{
+		{"KIL", 0x02, &ErrJammed{PC: 0x8000, Code: 0x02}, true},
+		{"KIL $F2", 0xF2, &ErrJammed{PC: 0x8000, Code: 0xF2}, true},
+		{"unknown", 0x8B, &ErrUnknownOpcode{PC: 0x8000, Code: 0x8B}, false},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mem := &RAM{}
+			mem.Load(0x8000, []byte{tt.code})
+			mem.Write(0xC000, 0xEA)              // NOP
+			mem.Load(0xFFFC, []byte{0x00, 0xC0}) // reset vector
+
+			cpu := NewCPU(mem)
+			cpu.register.PC = 0x8000
+			// 止まった後は何度Runしても進まない
+			for i := 0; i < 2; i++ {
+				if want, got := 0, cpu.Run(); want != got {
+					t.Errorf("cycle: want=%v, got=%v", want, got)
+				}
+			}
+			if want, got := uint16(0x8000), cpu.register.PC; want != got {
+				t.Errorf("PC: want=%#04x, got=%#04x", want, got)
+			}
+			if diff := cmp.Diff(tt.wantErr, cpu.Err()); diff != "" {
+				t.Errorf("error mismatch (-want +got):\n%s", diff)
+			}
+			if want, got := tt.wantJammed, cpu.Jammed(); want != got {
+				t.Errorf("Jammed: want=%v, got=%v", want, got)
+			}
+
+			cpu.Reset()
+			if cpu.Err() != nil || cpu.Jammed() {
+				t.Fatalf("reset must clear the error: %v", cpu.Err())
+			}
+			if want, got := 2, cpu.Run(); want != got {
+				t.Errorf("cycle after reset: want=%v, got=%v", want, got)
+			}
+		})
+	}
+}
+
+// dmaMemory starts DMA of 513 cycles on writes to $4014 like OAM DMA.
+type dmaMemory struct {
+	RAM
+	stall int
+}
+
+func (m *dmaMemory) Write(address uint16, data byte) {
+	if address == 0x4014 {
+		m.stall = 513
+	}
+	m.RAM.Write(address, data)
+}
+
+func (m *dmaMemory) TakeStall() int {
+	stall := m.stall
+	m.stall = 0
+	return stall
+}
+
+func TestCPU_Run_dma(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name      string
+		cycles    uint64
+		wantCycle int
+	}{
+		// STA $4014の4cycleにDMAの513cycleを足す
+		{name: "even", cycles: 0, wantCycle: 4 + 513},
+		// 奇数cycleで始まる場合は1cycle多く待つ
+		{name: "odd", cycles: 1, wantCycle: 4 + 514},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mem := &dmaMemory{}
+			mem.Load(0x8000, []byte{
+				0x8D, 0x14, 0x40, // STA $4014
+				0xEA, // NOP
+			})
+			cpu := NewCPU(mem)
+			cpu.register.PC = 0x8000
+			cpu.cycles = tt.cycles
+
+			if want, got := tt.wantCycle, cpu.Run(); want != got {
+				t.Errorf("cycle: want=%v, got=%v", want, got)
+			}
+			if want, got := 2, cpu.Run(); want != got {
+				t.Errorf("cycle after DMA: want=%v, got=%v", want, got)
+			}
+		})
+	}
+}
+
+func TestCPU_Run_cycle(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name      string
+		program   []byte
+		register  *Register
+		stall     int
+		wantCycle int
+	}{
+		{
+			name:      "LDA_AbsoluteX",
+			program:   []byte{0xBD, 0x00, 0x02},
+			register:  &Register{PC: 0x8000, X: 0xFF},
+			wantCycle: 4,
+		},
+		{
+			name:      "LDA_AbsoluteX(page crossed)",
+			program:   []byte{0xBD, 0x01, 0x02},
+			register:  &Register{PC: 0x8000, X: 0xFF},
+			wantCycle: 5,
+		},
+		{
+			name:      "LDA_IndirectY",
+			program:   []byte{0xB1, 0x00}, // $0000=0x00,$0001=0x00
+			register:  &Register{PC: 0x8000, Y: 0x00},
+			wantCycle: 5,
+		},
+		{
+			name:      "STA_AbsoluteX(no penalty)",
+			program:   []byte{0x9D, 0x01, 0x02},
+			register:  &Register{PC: 0x8000, X: 0xFF},
+			wantCycle: 5,
+		},
+		{
+			name:      "BNE(not taken)",
+			program:   []byte{0xD0, 0x10},
+			register:  &Register{PC: 0x8000, P: 0b0000_0010},
+			wantCycle: 2,
+		},
+		{
+			name:      "BNE(taken)",
+			program:   []byte{0xD0, 0x10},
+			register:  &Register{PC: 0x8000},
+			wantCycle: 3,
+		},
+		{
+			name:      "BNE(taken, page crossed)",
+			program:   []byte{0xD0, 0x10},
+			register:  &Register{PC: 0x80F0},
+			wantCycle: 4,
+		},
+		{
+			name:      "BNE(taken backward, page crossed)",
+			program:   []byte{0xD0, 0xF0},
+			register:  &Register{PC: 0x8100},
+			wantCycle: 4,
+		},
+		{
+			name:      "stall",
+			program:   []byte{0xEA},
+			register:  &Register{PC: 0x8000},
+			stall:     513,
+			wantCycle: 513,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mem := &RAM{}
+			mem.Load(tt.register.PC, tt.program)
+
+			cpu := NewCPU(mem)
+			cpu.register = tt.register
+			cpu.Stall(tt.stall)
+
+			if want, got := tt.wantCycle, cpu.Run(); want != got {
+				t.Errorf("cycle: want=%v, got=%v", want, got)
+			}
+		})
+	}
+}
+
+func TestCPU_Run_busActivity(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name     string
+		program  []byte
+		register *Register
+		ram      map[uint16]byte
+		want     []busCycle
+	}{
+		{
+			name:     "TAX", // オペランドがなくても次のbyteを読む
+			program:  []byte{0xAA, 0xE8},
+			register: &Register{PC: 0x8000},
+			want: []busCycle{
+				{0x8000, 0xAA, "read"},
+				{0x8001, 0xE8, "read"},
+			},
+		},
+		{
+			name:     "INC_ZeroPage", // 変更前の値を書き戻してから結果を書く
+			program:  []byte{0xE6, 0x10},
+			register: &Register{PC: 0x8000},
+			ram:      map[uint16]byte{0x0010: 0x41},
+			want: []busCycle{
+				{0x8000, 0xE6, "read"},
+				{0x8001, 0x10, "read"},
+				{0x0010, 0x41, "read"},
+				{0x0010, 0x41, "write"},
+				{0x0010, 0x42, "write"},
+			},
+		},
+		{
+			name:     "LDA_AbsoluteX(page crossed)", // 上位byteを直す前のアドレスを読む
+			program:  []byte{0xBD, 0xF0, 0x02},
+			register: &Register{PC: 0x8000, X: 0x20},
+			ram:      map[uint16]byte{0x0210: 0x01, 0x0310: 0x02},
+			want: []busCycle{
+				{0x8000, 0xBD, "read"},
+				{0x8001, 0xF0, "read"},
+				{0x8002, 0x02, "read"},
+				{0x0210, 0x01, "read"},
+				{0x0310, 0x02, "read"},
+			},
+		},
+		{
+			name:     "LDA_AbsoluteX",
+			program:  []byte{0xBD, 0x00, 0x02},
+			register: &Register{PC: 0x8000, X: 0x10},
+			ram:      map[uint16]byte{0x0210: 0x01},
+			want: []busCycle{
+				{0x8000, 0xBD, "read"},
+				{0x8001, 0x00, "read"},
+				{0x8002, 0x02, "read"},
+				{0x0210, 0x01, "read"},
+			},
+		},
+		{
+			name:     "STA_AbsoluteX", // 書き込みはページをまたがなくても読む
+			program:  []byte{0x9D, 0x00, 0x02},
+			register: &Register{PC: 0x8000, A: 0x55, X: 0x10},
+			want: []busCycle{
+				{0x8000, 0x9D, "read"},
+				{0x8001, 0x00, "read"},
+				{0x8002, 0x02, "read"},
+				{0x0210, 0x00, "read"},
+				{0x0210, 0x55, "write"},
+			},
+		},
+		{
+			name:     "LDA_ZeroPageX",
+			program:  []byte{0xB5, 0xF0},
+			register: &Register{PC: 0x8000, X: 0x20},
+			ram:      map[uint16]byte{0x00F0: 0x01, 0x0010: 0x02},
+			want: []busCycle{
+				{0x8000, 0xB5, "read"},
+				{0x8001, 0xF0, "read"},
+				{0x00F0, 0x01, "read"},
+				{0x0010, 0x02, "read"},
+			},
+		},
+		{
+			name:     "JSR", // 上位byteはスタックに積んだ後に読む
+			program:  []byte{0x20, 0x10, 0x80},
+			register: &Register{PC: 0x8000, S: 0xFD},
+			want: []busCycle{
+				{0x8000, 0x20, "read"},
+				{0x8001, 0x10, "read"},
+				{0x01FD, 0x00, "read"},
+				{0x01FD, 0x80, "write"},
+				{0x01FC, 0x02, "write"},
+				{0x8002, 0x80, "read"},
+			},
+		},
+		{
+			name:     "PLA",
+			program:  []byte{0x68},
+			register: &Register{PC: 0x8000, S: 0xFC},
+			ram:      map[uint16]byte{0x01FD: 0x33},
+			want: []busCycle{
+				{0x8000, 0x68, "read"},
+				{0x8001, 0x00, "read"},
+				{0x01FC, 0x00, "read"},
+				{0x01FD, 0x33, "read"},
+			},
+		},
+		{
+			name:     "BNE(taken, page crossed)",
+			program:  []byte{0xD0, 0x10},
+			register: &Register{PC: 0x80F0},
+			want: []busCycle{
+				{0x80F0, 0xD0, "read"},
+				{0x80F1, 0x10, "read"},
+				{0x80F2, 0x00, "read"},
+				{0x8002, 0x00, "read"},
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mem := &recordingMemory{}
+			mem.Load(tt.register.PC, tt.program)
+			for addr, data := range tt.ram {
+				mem.RAM[addr] = data
+			}
+
+			cpu := NewCPU(mem)
+			cpu.register = tt.register
+			cycle := cpu.Run()
+
+			if diff := cmp.Diff(tt.want, mem.cycles); diff != "" {
+				t.Errorf("bus activity mismatch (-want +got):\n%s", diff)
+			}
+			if want, got := len(tt.want), cycle; want != got {
+				t.Errorf("cycle: want=%v, got=%v", want, got)
+			}
+		})
+	}
+}
+
+// TestCPU_Run_busCycles checks that every instruction accesses the bus once per cycle.
+func TestCPU_Run_busCycles(t *testing.T) {
+	t.Parallel()
+	for _, inst := range opecodes {
+		if inst == nil {
+			continue
+		}
+		// X,Y=0xFFでインデックス付きはページをまたぎ、P=0x00/0xFFで分岐の成立・不成立を両方試す
+		for _, r := range []Register{
+			{PC: 0x8000, S: 0xFD, P: 0x00},
+			{PC: 0x80F0, S: 0xFD, X: 0xFF, Y: 0xFF, P: 0xFF},
+			{PC: 0x80F0, S: 0xFD, X: 0xFF, Y: 0xFF, P: 0x00},
+		} {
+			r := r
+			mem := &recordingMemory{}
+			mem.Load(r.PC, []byte{inst.code, 0x80, 0x02})
+			mem.RAM[0x0080], mem.RAM[0x0081] = 0x80, 0x02
+
+			cpu := NewCPU(mem)
+			cpu.register = &r
+			cycle := cpu.Run()
+			if want, got := cycle, len(mem.cycles); want != got {
+				t.Errorf("%#02x %s %s P=%#02x: bus accesses: want=%v, got=%v", inst.code, inst.name, inst.mode, r.P, want, got)
+			}
+		}
+	}
+}
+
+func TestCPU_NMI(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name         string
+		program      []byte
+		hijack       bool // BRKの実行直後にNMIが来る
+		wantCycle    int
+		wantRegister *Register
+		wantStack    []byte
+	}{
+		{
+			name:      "NMI",
+			program:   []byte{0xEA}, // NOP
+			wantCycle: 7,
+			wantRegister: &Register{
+				PC: 0x9000,
+				S:  0xFA,
+				P:  0b0010_0100,
+			},
+			wantStack: []byte{0x80, 0x00, 0b0010_0000},
+		},
+		{
+			name:      "BRK hijacked by NMI",
+			program:   []byte{0x00, 0x00}, // BRK
+			hijack:    true,
+			wantCycle: 7,
+			wantRegister: &Register{
+				PC: 0x9000,
+				S:  0xFA,
+				P:  0b0010_0100,
+			},
+			wantStack: []byte{0x80, 0x02, 0b0011_0000},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mem := &RAM{}
+			mem.Load(0x8000, tt.program)
+			mem.Load(0xFFFA, []byte{0x00, 0x90}) // NMI vector
+			mem.Load(0xFFFE, []byte{0x00, 0xA0}) // IRQ vector
+
+			cpu := NewCPU(mem)
+			cpu.register.PC = 0x8000
+			cpu.register.S = 0xFD
+			var cycle int
+			if tt.hijack {
+				cycle = cpu.Run()
+				cpu.NMI()
+			} else {
+				cpu.NMI()
+				cycle = cpu.Run()
+			}
+
+			if want, got := tt.wantCycle, cycle; want != got {
+				t.Errorf("cycle: want=%v, got=%v", want, got)
+			}
+			if diff := cmp.Diff(tt.wantRegister, cpu.register); diff != "" {
+				t.Errorf("register mismatch (-want +got):\n%s", diff)
+			}
+			for i, want := range tt.wantStack {
+				if got := cpu.read(0x01FD - uint16(i)); want != got {
+					t.Errorf("stack[%d]: want=%#02x, got=%#02x", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCPU_Interrupts(t *testing.T) {
+	t.Parallel()
+	mem := &RAM{}
+	mem.Load(0x8000, []byte{
+		0x9A, // TXS. スタックを動かしても割り込みとは数えない
+		0x00, // BRK
+	})
+	mem.Load(0x9000, []byte{0xEA})       // NOP
+	mem.Load(0xA000, []byte{0xEA})       // NOP
+	mem.Load(0xFFFE, []byte{0x00, 0x90}) // IRQ/BRKベクタ
+	mem.Load(0xFFFA, []byte{0x00, 0xA0}) // NMIベクタ
+	cpu := NewCPU(mem)
+	cpu.register.PC = 0x8000
+	cpu.register.S = 0xFD
+	cpu.register.X = 0xFA
+
+	for _, want := range []uint64{0, 1, 1} {
+		cpu.Run()
+		if got := cpu.Interrupts(); want != got {
+			t.Errorf("interrupts after PC=%#04x: want=%v, got=%v", cpu.register.PC, want, got)
+		}
+	}
+	cpu.NMI()
+	if want, got := interruptCycle, cpu.Run(); want != got {
+		t.Errorf("NMI cycle: want=%v, got=%v", want, got)
+	}
+	if want, got := uint64(2), cpu.Interrupts(); want != got {
+		t.Errorf("interrupts after NMI: want=%v, got=%v", want, got)
+	}
+}
+
+func TestCPU_IRQ(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name       string
+		program    string
+		p          byte
+		clear      bool // アサート後にClearIRQする
+		assertAt   int  // 何命令目の実行前にIRQをアサートするか
+		wantCycles []int
+		wantPC     uint16
+	}{
+		{
+			name:       "IRQ",
+			program:    "NOP",
+			p:          0b0010_0000,
+			wantCycles: []int{7},
+			wantPC:     0xA000,
+		},
+		{
+			name:       "masked by I flag",
+			program:    "NOP",
+			p:          0b0010_0100,
+			wantCycles: []int{2},
+			wantPC:     0x8001,
+		},
+		{
+			name:       "cleared",
+			program:    "NOP",
+			p:          0b0010_0000,
+			clear:      true,
+			wantCycles: []int{2},
+			wantPC:     0x8001,
+		},
+		{
+			name:       "CLI takes effect after next instruction",
+			program:    "CLI\nNOP\nNOP",
+			p:          0b0010_0100,
+			wantCycles: []int{2, 2, 7},
+			wantPC:     0xA000,
+		},
+		{
+			name:       "IRQ right after SEI",
+			program:    "SEI\nNOP",
+			p:          0b0010_0000,
+			assertAt:   1,
+			wantCycles: []int{2, 7},
+			wantPC:     0xA000,
+		},
+		{
+			name:       "PLP takes effect after next instruction",
+			program:    "PLP\nNOP\nNOP",
+			p:          0b0010_0100,
+			wantCycles: []int{4, 2, 7},
+			wantPC:     0xA000,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mem := &RAM{}
+			loadProgram(t, mem, tt.program+"\n.org $FFFE\n.word $A000") // IRQ vector
+
+			cpu := NewCPU(mem)
+			cpu.register.PC = 0x8000
+			cpu.register.P = tt.p
+			cpu.pushByteToStack(0b0010_0000) // PLPで取り出すP
+
+			for i, want := range tt.wantCycles {
+				if i == tt.assertAt {
+					cpu.AssertIRQ(IRQMapper)
+					if tt.clear {
+						cpu.ClearIRQ(IRQMapper)
+					}
+				}
+				if got := cpu.Run(); want != got {
+					t.Errorf("cycle[%d]: want=%v, got=%v", i, want, got)
+				}
+			}
+			if want, got := tt.wantPC, cpu.register.PC; want != got {
+				t.Errorf("PC: want=%#04x, got=%#04x", want, got)
+			}
+		})
+	}
+}
+
+func TestCPU_Reset(t *testing.T) {
+	t.Parallel()
+
+	mem := &RAM{}
+	mem.Write(0xC000, 0xEA)              // NOP
+	mem.Load(0xFFFC, []byte{0x00, 0xC0}) // reset vector
+
+	cpu := NewCPU(mem)
+	cpu.register = &Register{A: 0x01, X: 0x02, Y: 0x03, S: 0x10, PC: 0x1234}
+
+	cpu.PowerOn()
+	if diff := cmp.Diff(&Register{S: 0xFD, P: 0b0010_0100, PC: 0xC000}, cpu.register); diff != "" {
+		t.Errorf("power on: register mismatch (-want +got):\n%s", diff)
+	}
+
+	cpu.register = &Register{A: 0x01, X: 0x02, Y: 0x03, S: 0xF0, P: 0b1010_0001, PC: 0x1234}
+	cpu.NMI()
+
+	cpu.Reset()
+	if diff := cmp.Diff(&Register{A: 0x01, X: 0x02, Y: 0x03, S: 0xED, P: 0b1010_0101, PC: 0xC000}, cpu.register); diff != "" {
+		t.Errorf("reset: register mismatch (-want +got):\n%s", diff)
+	}
+	// リセットで保留中のNMIは破棄される
+	if want, got := 2, cpu.Run(); want != got {
+		t.Errorf("cycle: want=%v, got=%v", want, got)
+	}
+}
+
+func TestCPU_decimal(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name    string
+		variant Variant
+		program []byte
+		a       byte
+		p       byte
+		wantA   byte
+		wantP   byte
+	}{
+		{
+			name:    "ADC(NES ignores D)",
+			variant: NES2A03,
+			program: []byte{0x69, 0x01}, // ADC #$01
+			a:       0x09,
+			p:       0b0000_1000,
+			wantA:   0x0A,
+			wantP:   0b0000_1000,
+		},
+		{
+			name:    "ADC(BCD)",
+			variant: NMOS6502,
+			program: []byte{0x69, 0x01}, // ADC #$01
+			a:       0x09,
+			p:       0b0000_1000,
+			wantA:   0x10,
+			wantP:   0b0000_1000,
+		},
+		{
+			name:    "ADC(BCD with carry)",
+			variant: NMOS6502,
+			program: []byte{0x69, 0x49}, // ADC #$49
+			a:       0x50,
+			p:       0b0000_1001,
+			wantA:   0x00,
+			wantP:   0b1100_1001, // N,Vは補正前の上位桁から決まる
+		},
+		{
+			name:    "ADC(binary on NMOS)",
+			variant: NMOS6502,
+			program: []byte{0x69, 0x01}, // ADC #$01
+			a:       0x09,
+			p:       0b0000_0000,
+			wantA:   0x0A,
+			wantP:   0b0000_0000,
+		},
+		{
+			name:    "SBC(BCD)",
+			variant: NMOS6502,
+			program: []byte{0xE9, 0x01}, // SBC #$01
+			a:       0x10,
+			p:       0b0000_1001,
+			wantA:   0x09,
+			wantP:   0b0000_1001,
+		},
+		{
+			name:    "SBC(BCD with borrow)",
+			variant: NMOS6502,
+			program: []byte{0xE9, 0x01}, // SBC #$01
+			a:       0x00,
+			p:       0b0000_1001,
+			wantA:   0x99,
+			wantP:   0b1000_1000,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mem := &RAM{}
+			mem.Load(0x8000, tt.program)
+
+			cpu := NewCPU(mem, WithVariant(tt.variant))
+			cpu.register = &Register{PC: 0x8000, A: tt.a, P: tt.p}
+			cpu.Run()
+
+			if want, got := tt.wantA, cpu.register.A; want != got {
+				t.Errorf("A: want=%#02x, got=%#02x", want, got)
+			}
+			if want, got := tt.wantP, cpu.register.P; want != got {
+				t.Errorf("P: want=%#08b, got=%#08b", want, got)
+			}
+		})
+	}
+}
+
+func TestCPU_Run_trap(t *testing.T) {
+	t.Parallel()
+
+	mem := &RAM{}
+	loadProgram(t, mem, `
+		LDX #$02
+loop:	DEX
+		BNE loop
+trap:	JMP trap
+`)
+
+	cpu := NewCPU(mem, WithTrapDetection())
+	cpu.register.PC = 0x8000
+	for i := 0; i < 10 && cpu.Err() == nil; i++ {
+		cpu.Run()
+	}
+
+	var trapErr *ErrTrapped
+	if !errors.As(cpu.Err(), &trapErr) {
+		t.Fatalf("unexpected error: %v", cpu.Err())
+	}
+	if want, got := uint16(0x8005), trapErr.PC; want != got {
+		t.Errorf("PC: want=%#04x, got=%#04x", want, got)
+	}
+	if want, got := 0, cpu.Run(); want != got {
+		t.Errorf("cycle: want=%v, got=%v", want, got)
+	}
+}
+
+// assemble assembles src with unofficial opcodes enabled.
+func assemble(t testing.TB, src string) *asm.Program {
+	t.Helper()
+	var set []asm.Opcode
+	for _, op := range Opcodes() {
+		set = append(set, asm.Opcode(op))
+	}
+	p, err := asm.New(set, asm.WithUnofficial()).Assemble(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// loadProgram assembles src and loads it into mem. The default origin is $8000.
+func loadProgram(t testing.TB, mem *RAM, src string) *asm.Program {
+	t.Helper()
+	p := assemble(t, src)
+	mem.Load(p.Origin, p.Bytes)
+	return p
 }
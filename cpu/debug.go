@@ -0,0 +1,45 @@
+package cpu
+
+import "fmt"
+
+// MemoryHook is called on every data read and write of CPU.
+// Opcode and operand fetches are not reported.
+type MemoryHook func(address uint16, data byte, write bool)
+
+// SetMemoryHook sets a hook for debuggers. nil removes the hook.
+func (c *CPU) SetMemoryHook(h MemoryHook) {
+	c.memoryHook = h
+}
+
+// MemoryHook returns the hook set by SetMemoryHook, or nil.
+func (c *CPU) MemoryHook() MemoryHook {
+	return c.memoryHook
+}
+
+// Register returns the registers. Debuggers can change them directly.
+func (c *CPU) Register() *Register {
+	return c.register
+}
+
+// Peek reads address without side effects if the memory supports it.
+func (c *CPU) Peek(address uint16) byte {
+	return c.peek(address)
+}
+
+// Bank returns the PRG bank mapped at address, or -1 if it is unknown.
+func (c *CPU) Bank(address uint16) int {
+	if b, ok := c.bus.(Banker); ok {
+		return b.Bank(address)
+	}
+	return -1
+}
+
+// String formats registers for debuggers, e.g. "PC:C000 A:00 X:00 Y:00 P:24(nvUbdIzc) SP:FD".
+func (r Register) String() string {
+	return fmt.Sprintf("PC:%04X A:%02X X:%02X Y:%02X P:%02X(%s) SP:%02X", r.PC, r.A, r.X, r.Y, r.P, flagString(r.P), r.S)
+}
+
+// Poke writes data to address for debuggers. The write is not reported to the memory hook.
+func (c *CPU) Poke(address uint16, data byte) {
+	c.bus.Write(address, data)
+}
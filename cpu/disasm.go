@@ -0,0 +1,276 @@
+package cpu
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Instruction is a disassembled instruction.
+type Instruction struct {
+	Address uint16
+	Bytes   []byte // opcodeとオペランド
+	Name    string
+	Mode    string
+	Operand string // アドレッシングモードに従って整形したオペランド. 例: "$0300,X"
+	// Annotation is the effective address and the value at it, e.g. "@ 0300 = 89".
+	// It is set only by CPU.Disassemble because it depends on registers and memory.
+	Annotation string
+	Unofficial bool
+	// Known is false if the opcode is not supported. Such byte is shown as ".byte".
+	Known bool
+}
+
+// String returns the instruction in the style of nestest.log, e.g. "LDA $0300,X @ 0300 = 89".
+func (i Instruction) String() string {
+	if !i.Known {
+		return fmt.Sprintf(".byte $%02X", i.Bytes[0])
+	}
+	s := i.Name
+	if i.Operand != "" {
+		s += " " + i.Operand
+	}
+	if i.Annotation != "" {
+		s += " " + i.Annotation
+	}
+	return s
+}
+
+// FormatBytes formats instruction bytes as "4C F5 C5".
+func (i Instruction) FormatBytes() string {
+	bytes := make([]string, len(i.Bytes))
+	for n, b := range i.Bytes {
+		bytes[n] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(bytes, " ")
+}
+
+// Target returns the destination address of JMP absolute, JSR and branches.
+func (i Instruction) Target() (uint16, bool) {
+	if !i.Known {
+		return 0, false
+	}
+	switch {
+	case i.Mode == relative.String():
+		return i.Address + 2 + uint16(int8(i.Bytes[1])), true
+	case i.Mode == absolute.String() && (i.Name == "JMP" || i.Name == "JSR"):
+		return le16(i.Bytes[1:]), true
+	}
+	return 0, false
+}
+
+// Symbolize returns the instruction whose operand address is replaced by its label,
+// e.g. "JSR $C123" to "JSR update_player". Immediate values are not replaced.
+func (i Instruction) Symbolize(label func(address uint16) (string, bool)) Instruction {
+	addr, text, ok := i.operandAddress()
+	if !ok {
+		return i
+	}
+	if name, found := label(addr); found {
+		i.Operand = strings.Replace(i.Operand, text, name, 1)
+	}
+	return i
+}
+
+// operandAddress returns the address in the operand and how it is written in Operand.
+func (i Instruction) operandAddress() (uint16, string, bool) {
+	if !i.Known {
+		return 0, "", false
+	}
+	switch i.Mode {
+	case zeroPage.String(), zeroPageX.String(), zeroPageY.String(), indirectX.String(), indirectY.String():
+		return uint16(i.Bytes[1]), fmt.Sprintf("$%02X", i.Bytes[1]), true
+	case absolute.String(), absoluteX.String(), absoluteY.String(), indirect.String():
+		addr := le16(i.Bytes[1:])
+		return addr, fmt.Sprintf("$%04X", addr), true
+	case relative.String():
+		addr, _ := i.Target()
+		return addr, fmt.Sprintf("$%04X", addr), true
+	}
+	return 0, "", false
+}
+
+// endsFlow reports whether execution does not fall through to the next instruction.
+func (i Instruction) endsFlow() bool {
+	if !i.Known {
+		return true
+	}
+	switch i.Name {
+	case "JMP", "RTS", "RTI", "BRK":
+		return true
+	}
+	return false
+}
+
+// Disassemble decodes an instruction at pc.
+func Disassemble(mem Peeker, pc uint16) Instruction {
+	return disassemble(mem.Peek, pc)
+}
+
+// DisassembleRange decodes instructions linearly from `from` to `to` (inclusive).
+func DisassembleRange(mem Peeker, from, to uint16) []Instruction {
+	var result []Instruction
+	for pc := uint32(from); pc <= uint32(to); {
+		inst := disassemble(mem.Peek, uint16(pc))
+		result = append(result, inst)
+		pc += uint32(len(inst.Bytes))
+	}
+	return result
+}
+
+// DisassembleCode decodes instructions reachable from entries by following jumps, calls and branches.
+// Addresses outside [from, to] are not followed. The result is sorted by address.
+// データとして使われるbyteを命令として解釈しないように、線形に読まず実行経路をたどる
+func DisassembleCode(mem Peeker, from, to uint16, entries ...uint16) []Instruction {
+	visited := map[uint16]Instruction{}
+	queue := append([]uint16{}, entries...)
+	for len(queue) > 0 {
+		pc := queue[0]
+		queue = queue[1:]
+		for pc >= from && pc <= to {
+			if _, ok := visited[pc]; ok {
+				break
+			}
+			inst := disassemble(mem.Peek, pc)
+			visited[pc] = inst
+			if target, ok := inst.Target(); ok {
+				queue = append(queue, target)
+			}
+			if inst.endsFlow() {
+				break
+			}
+			next := pc + uint16(len(inst.Bytes))
+			if next < pc {
+				break
+			}
+			pc = next
+		}
+	}
+
+	result := make([]Instruction, 0, len(visited))
+	for _, inst := range visited {
+		result = append(result, inst)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+	return result
+}
+
+// Disassemble decodes an instruction at pc with the effective address annotated
+// from the current registers. Memory is read without side effects.
+func (c *CPU) Disassemble(pc uint16) Instruction {
+	inst := disassemble(c.peek, pc)
+	if op := opecodes[inst.Bytes[0]]; op != nil {
+		inst.Annotation = c.annotate(op, inst.Bytes[1:])
+	}
+	return inst
+}
+
+// annotate formats the effective address and the value of operand like nestest.log.
+func (c *CPU) annotate(op *instruction, operand []byte) string {
+	switch op.mode {
+	case zeroPage:
+		return fmt.Sprintf("= %02X", c.peek(uint16(operand[0])))
+	case zeroPageX:
+		addr := operand[0] + c.register.X
+		return fmt.Sprintf("@ %02X = %02X", addr, c.peek(uint16(addr)))
+	case zeroPageY:
+		addr := operand[0] + c.register.Y
+		return fmt.Sprintf("@ %02X = %02X", addr, c.peek(uint16(addr)))
+	case absolute:
+		if op.name == "JMP" || op.name == "JSR" {
+			return ""
+		}
+		return fmt.Sprintf("= %02X", c.peek(le16(operand)))
+	case absoluteX:
+		addr := le16(operand) + uint16(c.register.X)
+		return fmt.Sprintf("@ %04X = %02X", addr, c.peek(addr))
+	case absoluteY:
+		addr := le16(operand) + uint16(c.register.Y)
+		return fmt.Sprintf("@ %04X = %02X", addr, c.peek(addr))
+	case indirect:
+		ptr := le16(operand)
+		l, h := uint16(c.peek(ptr)), uint16(c.peek(ptr&0xFF00|uint16(byte(ptr)+1)))
+		return fmt.Sprintf("= %04X", l|h<<8)
+	case indirectX:
+		ptr := operand[0] + c.register.X
+		addr := uint16(c.peek(uint16(ptr))) | uint16(c.peek(uint16(ptr+1)))<<8
+		return fmt.Sprintf("@ %02X = %04X = %02X", ptr, addr, c.peek(addr))
+	case indirectY:
+		ptr := operand[0]
+		base := uint16(c.peek(uint16(ptr))) | uint16(c.peek(uint16(ptr+1)))<<8
+		addr := base + uint16(c.register.Y)
+		return fmt.Sprintf("= %04X @ %04X = %02X", base, addr, c.peek(addr))
+	}
+	return ""
+}
+
+func disassemble(peek func(uint16) byte, pc uint16) Instruction {
+	code := peek(pc)
+	op := opecodes[code]
+	if op == nil {
+		return Instruction{Address: pc, Bytes: []byte{code}}
+	}
+	inst := Instruction{
+		Address:    pc,
+		Bytes:      make([]byte, op.mode.size()),
+		Name:       op.name,
+		Mode:       op.mode.String(),
+		Unofficial: op.unofficial,
+		Known:      true,
+	}
+	for i := range inst.Bytes {
+		inst.Bytes[i] = peek(pc + uint16(i))
+	}
+	inst.Operand = formatOperand(op.mode, pc, inst.Bytes[1:])
+	return inst
+}
+
+// size returns the length of an instruction including the opcode.
+func (m addressingMode) size() int {
+	switch m {
+	case implied, accumulator:
+		return 1
+	case absolute, absoluteX, absoluteY, indirect:
+		return 3
+	}
+	return 2
+}
+
+// formatOperand formats operand bytes of an instruction at pc per addressing mode.
+func formatOperand(mode addressingMode, pc uint16, operand []byte) string {
+	switch mode {
+	case accumulator:
+		return "A"
+	case immediate:
+		return fmt.Sprintf("#$%02X", operand[0])
+	case zeroPage:
+		return fmt.Sprintf("$%02X", operand[0])
+	case zeroPageX:
+		return fmt.Sprintf("$%02X,X", operand[0])
+	case zeroPageY:
+		return fmt.Sprintf("$%02X,Y", operand[0])
+	case absolute:
+		return fmt.Sprintf("$%04X", le16(operand))
+	case absoluteX:
+		return fmt.Sprintf("$%04X,X", le16(operand))
+	case absoluteY:
+		return fmt.Sprintf("$%04X,Y", le16(operand))
+	case indirect:
+		return fmt.Sprintf("($%04X)", le16(operand))
+	case indirectX:
+		return fmt.Sprintf("($%02X,X)", operand[0])
+	case indirectY:
+		return fmt.Sprintf("($%02X),Y", operand[0])
+	case relative:
+		// 分岐先は次の命令のアドレス + 符号付きオフセット
+		return fmt.Sprintf("$%04X", pc+2+uint16(int8(operand[0])))
+	}
+	return ""
+}
+
+// le16 decodes little endian 16bit value.
+func le16(b []byte) uint16 {
+	return uint16(b[1])<<8 | uint16(b[0])
+}
@@ -0,0 +1,228 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCPU_Disassemble(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name    string
+		program []byte
+		init    func(cpu *CPU, mem *RAM)
+		want    string
+	}{
+		{"implied", []byte{0xEA}, nil, "NOP"},
+		{"accumulator", []byte{0x4A}, nil, "LSR A"},
+		{"immediate", []byte{0xA9, 0x10}, nil, "LDA #$10"},
+		{
+			name:    "zeroPage",
+			program: []byte{0x86, 0x00},
+			init: func(cpu *CPU, mem *RAM) {
+				mem[0x0000] = 0x5A
+			},
+			want: "STX $00 = 5A",
+		},
+		{
+			name:    "zeroPageX wraps",
+			program: []byte{0xB5, 0xFF},
+			init: func(cpu *CPU, mem *RAM) {
+				cpu.register.X = 0x02
+				mem[0x0001] = 0x33
+			},
+			want: "LDA $FF,X @ 01 = 33",
+		},
+		{
+			name:    "zeroPageY",
+			program: []byte{0xB6, 0x10},
+			init: func(cpu *CPU, mem *RAM) {
+				cpu.register.Y = 0x01
+			},
+			want: "LDX $10,Y @ 11 = 00",
+		},
+		{
+			name:    "absolute",
+			program: []byte{0x8D, 0x00, 0x02},
+			init: func(cpu *CPU, mem *RAM) {
+				mem[0x0200] = 0x7F
+			},
+			want: "STA $0200 = 7F",
+		},
+		{"JMP absolute", []byte{0x4C, 0xF5, 0xC5}, nil, "JMP $C5F5"},
+		{"JSR", []byte{0x20, 0x00, 0x90}, nil, "JSR $9000"},
+		{
+			name:    "absoluteX",
+			program: []byte{0xBD, 0x00, 0x03},
+			init: func(cpu *CPU, mem *RAM) {
+				cpu.register.X = 0x89
+				mem[0x0389] = 0x89
+			},
+			want: "LDA $0300,X @ 0389 = 89",
+		},
+		{
+			name:    "absoluteY",
+			program: []byte{0xB9, 0xFF, 0x02},
+			init: func(cpu *CPU, mem *RAM) {
+				cpu.register.Y = 0x01
+			},
+			want: "LDA $02FF,Y @ 0300 = 00",
+		},
+		{
+			name:    "indirect page bug",
+			program: []byte{0x6C, 0xFF, 0x02},
+			init: func(cpu *CPU, mem *RAM) {
+				mem[0x02FF], mem[0x0200], mem[0x0300] = 0x7E, 0xDB, 0xFF
+			},
+			want: "JMP ($02FF) = DB7E",
+		},
+		{
+			name:    "indirectX",
+			program: []byte{0xA1, 0x80},
+			init: func(cpu *CPU, mem *RAM) {
+				cpu.register.X = 0x02
+				mem[0x0082], mem[0x0083], mem[0x0200] = 0x00, 0x02, 0x5A
+			},
+			want: "LDA ($80,X) @ 82 = 0200 = 5A",
+		},
+		{
+			name:    "indirectY",
+			program: []byte{0xB1, 0x89},
+			init: func(cpu *CPU, mem *RAM) {
+				cpu.register.Y = 0x34
+				mem[0x0089], mem[0x008A], mem[0x0334] = 0x00, 0x03, 0x89
+			},
+			want: "LDA ($89),Y = 0300 @ 0334 = 89",
+		},
+		{"relative forward", []byte{0xB0, 0x04}, nil, "BCS $8006"},
+		{"relative backward", []byte{0xD0, 0xFE}, nil, "BNE $8000"},
+		{"unofficial", []byte{0xA7, 0x10}, nil, "LAX $10 = 00"},
+		{"unknown", []byte{0x02}, nil, ".byte $02"},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mem := &RAM{}
+			mem.Load(0x8000, tt.program)
+			cpu := NewCPU(mem)
+			if tt.init != nil {
+				tt.init(cpu, mem)
+			}
+			before := *mem
+			if want, got := tt.want, cpu.Disassemble(0x8000).String(); want != got {
+				t.Errorf("want=%q, got=%q", want, got)
+			}
+			if before != *mem {
+				t.Error("Disassemble must not change memory")
+			}
+			// 静的な逆アセンブルは実効アドレスを含まない
+			static := Disassemble(mem, 0x8000)
+			if static.Annotation != "" {
+				t.Errorf("static disassembly has annotation:%q", static.Annotation)
+			}
+			if want, got := len(tt.program), len(static.Bytes); want != got {
+				t.Errorf("size: want=%v, got=%v", want, got)
+			}
+		})
+	}
+}
+
+func TestInstruction_Target(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name    string
+		program []byte
+		want    uint16
+		wantOK  bool
+	}{
+		{"JMP", []byte{0x4C, 0x34, 0x12}, 0x1234, true},
+		{"JSR", []byte{0x20, 0x34, 0x12}, 0x1234, true},
+		{"BEQ", []byte{0xF0, 0x10}, 0x8012, true},
+		{"JMP indirect", []byte{0x6C, 0x34, 0x12}, 0, false},
+		{"LDA absolute", []byte{0xAD, 0x34, 0x12}, 0, false},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mem := &RAM{}
+			mem.Load(0x8000, tt.program)
+			got, ok := Disassemble(mem, 0x8000).Target()
+			if tt.want != got || tt.wantOK != ok {
+				t.Errorf("want=(%#04x, %v), got=(%#04x, %v)", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestInstruction_Symbolize(t *testing.T) {
+	t.Parallel()
+	labels := map[uint16]string{0x0010: "ptr", 0x0300: "buffer", 0x8012: "loop", 0xC123: "update_player"}
+	label := func(address uint16) (string, bool) {
+		name, ok := labels[address]
+		return name, ok
+	}
+	for _, tt := range []struct {
+		name    string
+		program []byte
+		want    string
+	}{
+		{"JSR", []byte{0x20, 0x23, 0xC1}, "JSR update_player"},
+		{"absoluteX", []byte{0xBD, 0x00, 0x03}, "LDA buffer,X"},
+		{"indirectY", []byte{0xB1, 0x10}, "LDA (ptr),Y"},
+		{"relative", []byte{0xD0, 0x10}, "BNE loop"},
+		{"immediate", []byte{0xA9, 0x10}, "LDA #$10"},
+		{"no label", []byte{0xAD, 0x01, 0x03}, "LDA $0301"},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mem := &RAM{}
+			mem.Load(0x8000, tt.program)
+			if got := Disassemble(mem, 0x8000).Symbolize(label).String(); tt.want != got {
+				t.Errorf("want=%q, got=%q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDisassembleRange(t *testing.T) {
+	t.Parallel()
+	mem := &RAM{}
+	mem.Load(0xFFFA, []byte{0xA9, 0x01, 0x8D, 0x00, 0x02, 0xEA})
+
+	var got []string
+	for _, inst := range DisassembleRange(mem, 0xFFFA, 0xFFFF) {
+		got = append(got, inst.String())
+	}
+	want := []string{"LDA #$01", "STA $0200", "NOP"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DisassembleRange mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDisassembleCode(t *testing.T) {
+	t.Parallel()
+	mem := &RAM{}
+	loadProgram(t, mem, `
+main:	JSR sub
+		BEQ done
+		JMP main
+done:	RTS
+		.byte $FF, $FF ; データ
+		.org $8010
+sub:	LDA #$00
+		RTS
+		.org $8020
+nmi:	RTI
+`)
+
+	var got []uint16
+	for _, inst := range DisassembleCode(mem, 0x8000, 0xFFFF, 0x8000, 0x8020) {
+		got = append(got, inst.Address)
+	}
+	want := []uint16{0x8000, 0x8003, 0x8005, 0x8008, 0x8010, 0x8012, 0x8020}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DisassembleCode mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,66 @@
+package cpu
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// Klaus Dormann's 6502 functional tests assembled with the default configuration.
+// https://github.com/Klaus2m5/6502_65C02_functional_tests
+// Put the binaries into testdata to run these tests.
+const (
+	functionalTestBin = "testdata/6502_functional_test.bin"
+	decimalTestBin    = "testdata/6502_decimal_test.bin"
+)
+
+// functionalTestLimit is the maximum number of instructions to run before giving up.
+const functionalTestLimit = 100_000_000
+
+// runUntilTrap loads bin into RAM at address, starts from pc and runs until CPU traps.
+func runUntilTrap(t *testing.T, bin string, address, pc uint16) (*RAM, uint16) {
+	t.Helper()
+
+	data, err := os.ReadFile(bin)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Skipf("%s not found", bin)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mem := &RAM{}
+	mem.Load(address, data)
+	cpu := NewCPU(mem, WithVariant(NMOS6502), WithTrapDetection())
+	cpu.register.PC = pc
+
+	for i := 0; i < functionalTestLimit && cpu.Err() == nil; i++ {
+		cpu.Run()
+	}
+
+	var trapErr *ErrTrapped
+	if !errors.As(cpu.Err(), &trapErr) {
+		t.Fatalf("not trapped: err=%v, PC=%#04x", cpu.Err(), cpu.register.PC)
+	}
+	return mem, trapErr.PC
+}
+
+func TestCPU_functional(t *testing.T) {
+	t.Parallel()
+
+	// 成功時は$3469でトラップする
+	_, pc := runUntilTrap(t, functionalTestBin, 0x0000, 0x0400)
+	if want, got := uint16(0x3469), pc; want != got {
+		t.Errorf("trapped at %#04x, want=%#04x", got, want)
+	}
+}
+
+func TestCPU_decimalFunctional(t *testing.T) {
+	t.Parallel()
+
+	// 終了時に$000B(ERROR)が0なら成功
+	mem, pc := runUntilTrap(t, decimalTestBin, 0x0200, 0x0200)
+	if got := mem[0x000B]; got != 0 {
+		t.Errorf("decimal test failed at %#04x: ERROR=%#02x", pc, got)
+	}
+}
@@ -0,0 +1,519 @@
+package cpu
+
+import (
+	"fmt"
+
+	"github.com/yusukemisa/gones/util"
+)
+
+// handler executes an instruction with the addressing mode.
+type handler func(c *CPU, mode addressingMode)
+
+// handlers maps mnemonic to handler. init sets them to opecodes.
+var handlers = map[string]handler{
+	"NOP": (*CPU).nop,
+	"BRK": (*CPU).brk,
+	"RTI": (*CPU).rti,
+	"JMP": (*CPU).jmp,
+	"JSR": (*CPU).jsr,
+	"RTS": (*CPU).rts,
+	"PHP": (*CPU).php,
+	"PHA": (*CPU).pha,
+	"PLA": (*CPU).pla,
+	"PLP": (*CPU).plp,
+	"LDA": (*CPU).lda,
+	"LDX": (*CPU).ldx,
+	"LDY": (*CPU).ldy,
+	"STA": (*CPU).sta,
+	"STX": (*CPU).stx,
+	"STY": (*CPU).sty,
+	"TAX": (*CPU).tax,
+	"TAY": (*CPU).tay,
+	"TSX": (*CPU).tsx,
+	"TXA": (*CPU).txa,
+	"TYA": (*CPU).tya,
+	"TXS": (*CPU).txs,
+	"AND": (*CPU).and,
+	"ORA": (*CPU).ora,
+	"EOR": (*CPU).eor,
+	"ADC": (*CPU).adc,
+	"SBC": (*CPU).sbc,
+	"CMP": (*CPU).cmp,
+	"CPX": (*CPU).cpx,
+	"CPY": (*CPU).cpy,
+	"BIT": (*CPU).bit,
+	"ASL": (*CPU).asl,
+	"LSR": (*CPU).lsr,
+	"ROL": (*CPU).rol,
+	"ROR": (*CPU).ror,
+	"INC": (*CPU).inc,
+	"DEC": (*CPU).dec,
+	"INX": (*CPU).inx,
+	"INY": (*CPU).iny,
+	"DEX": (*CPU).dex,
+	"DEY": (*CPU).dey,
+	"SEC": (*CPU).sec,
+	"CLC": (*CPU).clc,
+	"CLD": (*CPU).cld,
+	"SED": (*CPU).sed,
+	"SEI": (*CPU).sei,
+	"CLI": (*CPU).cli,
+	"CLV": (*CPU).clv,
+	"BCS": (*CPU).bcs,
+	"BCC": (*CPU).bcc,
+	"BVS": (*CPU).bvs,
+	"BVC": (*CPU).bvc,
+	"BPL": (*CPU).bpl,
+	"BMI": (*CPU).bmi,
+	"BNE": (*CPU).bne,
+	"BEQ": (*CPU).beq,
+
+	// 非公式命令
+	"LAX": (*CPU).lax,
+	"SAX": (*CPU).sax,
+	"DCP": (*CPU).dcp,
+	"ISB": (*CPU).isb,
+	"SLO": (*CPU).slo,
+	"RLA": (*CPU).rla,
+	"SRE": (*CPU).sre,
+	"RRA": (*CPU).rra,
+	"ANC": (*CPU).anc,
+	"ALR": (*CPU).alr,
+	"ARR": (*CPU).arr,
+	"AXS": (*CPU).axs,
+}
+
+func init() {
+	for _, inst := range opecodes {
+		if inst == nil {
+			continue
+		}
+		h, ok := handlers[inst.name]
+		if !ok {
+			panic(fmt.Sprintf("handler not found:%s", inst.name))
+		}
+		inst.handler = h
+	}
+}
+
+func (c *CPU) nop(mode addressingMode) {
+	// 非公式のNOPはオペランドを読んで捨てる
+	if mode != implied {
+		c.read(c.getOperandAddress(mode))
+	}
+}
+
+func (c *CPU) brk(addressingMode) {
+	// パディングの1byte(execで読み捨て済み)を読み飛ばしてからPCとPをスタックに退避し、IRQベクタへジャンプ
+	c.register.PC++
+	c.interrupt(irqVector, true)
+}
+
+func (c *CPU) rti(addressingMode) {
+	// スタックからPとPCを復帰
+	c.dummyReadStack()
+	c.register.P = c.popStatusFromStack()
+	c.register.PC = c.popAddressFromStack()
+}
+
+func (c *CPU) jmp(mode addressingMode) {
+	c.register.PC = c.getOperandAddress(mode)
+}
+
+func (c *CPU) jsr(addressingMode) {
+	// 戻り番地-1(JSR命令の最後のbyte)をスタックに退避し、PC=IM16にする
+	// 上位byteはスタックに積んだ後にフェッチする
+	l := uint16(c.fetch())
+	c.dummyReadStack()
+	c.pushAddressToStack(c.register.PC)
+	h := uint16(c.fetch())
+	c.register.PC = l | h<<8
+}
+
+func (c *CPU) rts(addressingMode) {
+	// スタックから戻り番地-1を取得しPCに格納する
+	// 戻り番地-1を読んで捨ててからインクリメントする
+	c.dummyReadStack()
+	addr := c.popAddressFromStack()
+	c.dummyRead(addr)
+	c.register.PC = addr + 1
+}
+
+func (c *CPU) php(addressingMode) {
+	// ステータスのコピーをスタックに退避. 積んだコピーはBフラグとbit5が立つ
+	c.pushByteToStack(c.register.P | 1<<breakFlag | 1<<reservedFlag)
+}
+
+func (c *CPU) pha(addressingMode) {
+	// アキュムレーターのコピーをスタックに退避
+	c.pushByteToStack(c.register.A)
+}
+
+func (c *CPU) pla(addressingMode) {
+	// スタックからAにPull
+	c.dummyReadStack()
+	c.register.A = c.popByteFromStack()
+	c.updateStatusRegister(c.register.A)
+}
+
+func (c *CPU) plp(addressingMode) {
+	// スタックからPにPull
+	c.dummyReadStack()
+	c.register.P = c.popStatusFromStack()
+}
+
+func (c *CPU) lda(mode addressingMode) {
+	c.register.A = c.read(c.getOperandAddress(mode))
+	c.updateStatusRegister(c.register.A)
+}
+
+func (c *CPU) ldx(mode addressingMode) {
+	c.register.X = c.read(c.getOperandAddress(mode))
+	c.updateStatusRegister(c.register.X)
+}
+
+func (c *CPU) ldy(mode addressingMode) {
+	c.register.Y = c.read(c.getOperandAddress(mode))
+	c.updateStatusRegister(c.register.Y)
+}
+
+func (c *CPU) sta(mode addressingMode) {
+	c.write(c.getOperandAddress(mode), c.register.A)
+}
+
+func (c *CPU) stx(mode addressingMode) {
+	c.write(c.getOperandAddress(mode), c.register.X)
+}
+
+func (c *CPU) sty(mode addressingMode) {
+	c.write(c.getOperandAddress(mode), c.register.Y)
+}
+
+func (c *CPU) tax(addressingMode) {
+	c.register.X = c.register.A
+	c.updateStatusRegister(c.register.X)
+}
+
+func (c *CPU) tay(addressingMode) {
+	c.register.Y = c.register.A
+	c.updateStatusRegister(c.register.Y)
+}
+
+func (c *CPU) tsx(addressingMode) {
+	c.register.X = c.register.S
+	c.updateStatusRegister(c.register.X)
+}
+
+func (c *CPU) txa(addressingMode) {
+	c.register.A = c.register.X
+	c.updateStatusRegister(c.register.A)
+}
+
+func (c *CPU) tya(addressingMode) {
+	c.register.A = c.register.Y
+	c.updateStatusRegister(c.register.A)
+}
+
+func (c *CPU) txs(addressingMode) {
+	c.register.S = c.register.X
+}
+
+func (c *CPU) and(mode addressingMode) {
+	c.register.A &= c.read(c.getOperandAddress(mode))
+	c.updateStatusRegister(c.register.A)
+}
+
+func (c *CPU) ora(mode addressingMode) {
+	c.register.A |= c.read(c.getOperandAddress(mode))
+	c.updateStatusRegister(c.register.A)
+}
+
+func (c *CPU) eor(mode addressingMode) {
+	c.register.A ^= c.read(c.getOperandAddress(mode))
+	c.updateStatusRegister(c.register.A)
+}
+
+func (c *CPU) adc(mode addressingMode) {
+	c.add(c.read(c.getOperandAddress(mode)))
+}
+
+func (c *CPU) sbc(mode addressingMode) {
+	c.subtract(c.read(c.getOperandAddress(mode)))
+}
+
+func (c *CPU) cmp(mode addressingMode) {
+	c.compare(c.register.A, c.read(c.getOperandAddress(mode)))
+}
+
+func (c *CPU) cpx(mode addressingMode) {
+	c.compare(c.register.X, c.read(c.getOperandAddress(mode)))
+}
+
+func (c *CPU) cpy(mode addressingMode) {
+	c.compare(c.register.Y, c.read(c.getOperandAddress(mode)))
+}
+
+func (c *CPU) bit(mode addressingMode) {
+	m := c.read(c.getOperandAddress(mode))
+	c.setFlag(zeroFlag, c.register.A&m == 0)
+	c.setFlag(overflowFlag, util.TestBit(m, 6))
+	c.setFlag(negativeFlag, util.TestBit(m, 7))
+}
+
+func (c *CPU) asl(mode addressingMode) {
+	c.modify(mode, c.shiftLeft)
+}
+
+func (c *CPU) lsr(mode addressingMode) {
+	c.modify(mode, c.shiftRight)
+}
+
+func (c *CPU) rol(mode addressingMode) {
+	c.modify(mode, c.rotateLeft)
+}
+
+func (c *CPU) ror(mode addressingMode) {
+	c.modify(mode, c.rotateRight)
+}
+
+func (c *CPU) inc(mode addressingMode) {
+	c.modify(mode, func(v byte) byte {
+		v++
+		c.updateStatusRegister(v)
+		return v
+	})
+}
+
+func (c *CPU) dec(mode addressingMode) {
+	c.modify(mode, func(v byte) byte {
+		v--
+		c.updateStatusRegister(v)
+		return v
+	})
+}
+
+func (c *CPU) inx(addressingMode) {
+	c.register.X++
+	c.updateStatusRegister(c.register.X)
+}
+
+func (c *CPU) iny(addressingMode) {
+	c.register.Y++
+	c.updateStatusRegister(c.register.Y)
+}
+
+func (c *CPU) dex(addressingMode) {
+	c.register.X--
+	c.updateStatusRegister(c.register.X)
+}
+
+func (c *CPU) dey(addressingMode) {
+	c.register.Y--
+	c.updateStatusRegister(c.register.Y)
+}
+
+func (c *CPU) sec(addressingMode) {
+	c.register.P = util.SetBit(c.register.P, carryFlag)
+}
+
+func (c *CPU) clc(addressingMode) {
+	c.register.P = util.ClearBit(c.register.P, carryFlag)
+}
+
+func (c *CPU) cld(addressingMode) {
+	// デシマルモードをOFF
+	// bit3を消す
+	c.register.P = util.ClearBit(c.register.P, decimalFlag)
+}
+
+func (c *CPU) sed(addressingMode) {
+	// デシマルモードをON
+	// bit3を立てる
+	c.register.P = util.SetBit(c.register.P, decimalFlag)
+}
+
+func (c *CPU) sei(addressingMode) {
+	// IRQ割り込み禁止
+	// bit2を立てる
+	c.register.P = util.SetBit(c.register.P, interruptFlag)
+}
+
+func (c *CPU) cli(addressingMode) {
+	// IRQ割り込み許可
+	c.register.P = util.ClearBit(c.register.P, interruptFlag)
+}
+
+func (c *CPU) clv(addressingMode) {
+	c.register.P = util.ClearBit(c.register.P, overflowFlag)
+}
+
+func (c *CPU) bcs(addressingMode) {
+	c.branch(util.TestBit(c.register.P, carryFlag))
+}
+
+func (c *CPU) bcc(addressingMode) {
+	c.branch(!util.TestBit(c.register.P, carryFlag))
+}
+
+func (c *CPU) bvs(addressingMode) {
+	c.branch(util.TestBit(c.register.P, overflowFlag))
+}
+
+func (c *CPU) bvc(addressingMode) {
+	c.branch(!util.TestBit(c.register.P, overflowFlag))
+}
+
+func (c *CPU) bpl(addressingMode) {
+	c.branch(!util.TestBit(c.register.P, negativeFlag))
+}
+
+func (c *CPU) bmi(addressingMode) {
+	c.branch(util.TestBit(c.register.P, negativeFlag))
+}
+
+func (c *CPU) bne(addressingMode) {
+	c.branch(!util.TestBit(c.register.P, zeroFlag))
+}
+
+// ステータスレジスタのZがセットされている場合アドレス「PC + IM8」へジャンプ
+func (c *CPU) beq(addressingMode) {
+	c.branch(util.TestBit(c.register.P, zeroFlag))
+}
+
+func (c *CPU) lax(mode addressingMode) {
+	c.register.A = c.read(c.getOperandAddress(mode))
+	c.register.X = c.register.A
+	c.updateStatusRegister(c.register.A)
+}
+
+func (c *CPU) sax(mode addressingMode) {
+	c.write(c.getOperandAddress(mode), c.register.A&c.register.X)
+}
+
+func (c *CPU) dcp(mode addressingMode) {
+	c.modify(mode, func(v byte) byte {
+		v--
+		c.compare(c.register.A, v)
+		return v
+	})
+}
+
+func (c *CPU) isb(mode addressingMode) {
+	c.modify(mode, func(v byte) byte {
+		v++
+		c.subtract(v)
+		return v
+	})
+}
+
+func (c *CPU) slo(mode addressingMode) {
+	c.modify(mode, func(v byte) byte {
+		v = c.shiftLeft(v)
+		c.register.A |= v
+		c.updateStatusRegister(c.register.A)
+		return v
+	})
+}
+
+func (c *CPU) rla(mode addressingMode) {
+	c.modify(mode, func(v byte) byte {
+		v = c.rotateLeft(v)
+		c.register.A &= v
+		c.updateStatusRegister(c.register.A)
+		return v
+	})
+}
+
+func (c *CPU) sre(mode addressingMode) {
+	c.modify(mode, func(v byte) byte {
+		v = c.shiftRight(v)
+		c.register.A ^= v
+		c.updateStatusRegister(c.register.A)
+		return v
+	})
+}
+
+func (c *CPU) rra(mode addressingMode) {
+	c.modify(mode, func(v byte) byte {
+		v = c.rotateRight(v)
+		c.add(v)
+		return v
+	})
+}
+
+func (c *CPU) anc(mode addressingMode) {
+	c.register.A &= c.read(c.getOperandAddress(mode))
+	c.updateStatusRegister(c.register.A)
+	c.setFlag(carryFlag, util.TestBit(c.register.A, 7))
+}
+
+func (c *CPU) alr(mode addressingMode) {
+	c.register.A &= c.read(c.getOperandAddress(mode))
+	c.register.A = c.shiftRight(c.register.A)
+}
+
+func (c *CPU) arr(mode addressingMode) {
+	c.register.A &= c.read(c.getOperandAddress(mode))
+	c.register.A = c.rotateRight(c.register.A)
+	// C = bit6, V = bit6 xor bit5
+	c.setFlag(carryFlag, util.TestBit(c.register.A, 6))
+	c.setFlag(overflowFlag, util.TestBit(c.register.A, 6) != util.TestBit(c.register.A, 5))
+}
+
+func (c *CPU) axs(mode addressingMode) {
+	ax, m := c.register.A&c.register.X, c.read(c.getOperandAddress(mode))
+	c.register.X = ax - m
+	c.setFlag(carryFlag, ax >= m)
+	c.updateStatusRegister(c.register.X)
+}
+
+// modify reads the operand, applies f and writes back the result.
+// Accumulatorモードの場合はAを対象にする
+func (c *CPU) modify(mode addressingMode, f func(byte) byte) {
+	if mode == accumulator {
+		c.register.A = f(c.register.A)
+		return
+	}
+	addr := c.getOperandAddress(mode)
+	v := c.read(addr)
+	c.dummyWrite(addr, v)
+	c.write(addr, f(v))
+}
+
+// shiftLeft executes ASL to v and returns result.
+func (c *CPU) shiftLeft(v byte) byte {
+	c.setFlag(carryFlag, util.TestBit(v, 7))
+	result := v << 1
+	c.updateStatusRegister(result)
+	return result
+}
+
+// shiftRight executes LSR to v and returns result.
+func (c *CPU) shiftRight(v byte) byte {
+	c.setFlag(carryFlag, util.TestBit(v, 0))
+	result := v >> 1
+	c.updateStatusRegister(result)
+	return result
+}
+
+// rotateLeft executes ROL to v and returns result.
+func (c *CPU) rotateLeft(v byte) byte {
+	result := v << 1
+	if util.TestBit(c.register.P, carryFlag) {
+		result = util.SetBit(result, 0)
+	}
+	c.setFlag(carryFlag, util.TestBit(v, 7))
+	c.updateStatusRegister(result)
+	return result
+}
+
+// rotateRight executes ROR to v and returns result.
+func (c *CPU) rotateRight(v byte) byte {
+	result := v >> 1
+	if util.TestBit(c.register.P, carryFlag) {
+		result = util.SetBit(result, 7)
+	}
+	c.setFlag(carryFlag, util.TestBit(v, 0))
+	c.updateStatusRegister(result)
+	return result
+}
@@ -0,0 +1,43 @@
+package cpu
+
+// Memory is the 16bit address space seen from CPU.
+// *bus.Bus implements this for NES, and RAM can be used to run 6502 programs without NES hardware.
+type Memory interface {
+	Read(address uint16) byte
+	Write(address uint16, data byte)
+}
+
+// Peeker is implemented by Memory which can read without side effects.
+// PPUレジスタのように読み込みで状態が変わるアドレスを、デバッガやトレースから安全に読むために使う.
+// Peekerを実装していないMemoryはReadで代用する.
+type Peeker interface {
+	Peek(address uint16) byte
+}
+
+// Staller is implemented by Memory which suspends CPU for DMA.
+// CPU calls TakeStall after every instruction and adds the cycles to the return value of Run.
+type Staller interface {
+	// TakeStall returns cycles of DMA started since the last call, or 0.
+	// DMAが奇数cycleで始まる場合の1cycleはCPUが足す
+	TakeStall() int
+}
+
+// RAM is flat 64KB memory without memory mapped devices.
+type RAM [0x10000]byte
+
+func (r *RAM) Read(address uint16) byte {
+	return r[address]
+}
+
+func (r *RAM) Write(address uint16, data byte) {
+	r[address] = data
+}
+
+func (r *RAM) Peek(address uint16) byte {
+	return r[address]
+}
+
+// Load copies data into RAM from address.
+func (r *RAM) Load(address uint16, data []byte) {
+	copy(r[address:], data)
+}
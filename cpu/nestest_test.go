@@ -0,0 +1,164 @@
+package cpu
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/yusukemisa/gones/bus"
+	"github.com/yusukemisa/gones/ppu"
+	"github.com/yusukemisa/gones/rom"
+)
+
+// nestest.nes and nestest.log are distributed at https://www.qmtpro.com/~nes/misc/
+// Put them into testdata to run TestCPU_nestest.
+const (
+	nestestROM = "testdata/nestest.nes"
+	nestestLog = "testdata/nestest.log"
+)
+
+// nestestState is CPU state before executing each instruction.
+//
+// C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7
+type nestestState struct {
+	PC    uint16
+	Bytes []byte
+	A     byte
+	X     byte
+	Y     byte
+	P     byte
+	SP    byte
+	CYC   uint64
+}
+
+var nestestRegisterPattern = regexp.MustCompile(`A:([0-9A-F]{2}) X:([0-9A-F]{2}) Y:([0-9A-F]{2}) P:([0-9A-F]{2}) SP:([0-9A-F]{2}).*CYC:(\d+)`)
+
+func parseNestestLine(line string) (*nestestState, error) {
+	if len(line) < 16 {
+		return nil, fmt.Errorf("too short line: %q", line)
+	}
+	pc, err := strconv.ParseUint(line[0:4], 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PC: %w", err)
+	}
+	state := &nestestState{PC: uint16(pc)}
+
+	// 6～15桁目に命令のbyte列が並ぶ
+	for _, f := range strings.Fields(line[6:16]) {
+		b, err := strconv.ParseUint(f, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bytes: %w", err)
+		}
+		state.Bytes = append(state.Bytes, byte(b))
+	}
+
+	m := nestestRegisterPattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, errors.New("registers not found")
+	}
+	regs := make([]byte, 5)
+	for i := range regs {
+		v, _ := strconv.ParseUint(m[i+1], 16, 8)
+		regs[i] = byte(v)
+	}
+	state.A, state.X, state.Y, state.P, state.SP = regs[0], regs[1], regs[2], regs[3], regs[4]
+	if state.CYC, err = strconv.ParseUint(m[6], 10, 64); err != nil {
+		return nil, fmt.Errorf("failed to parse CYC: %w", err)
+	}
+	return state, nil
+}
+
+func (c *CPU) nestestState(size int) *nestestState {
+	state := &nestestState{
+		PC:  c.register.PC,
+		A:   c.register.A,
+		X:   c.register.X,
+		Y:   c.register.Y,
+		P:   c.register.P,
+		SP:  c.register.S,
+		CYC: c.cycles,
+	}
+	for i := 0; i < size; i++ {
+		state.Bytes = append(state.Bytes, c.peek(c.register.PC+uint16(i)))
+	}
+	return state
+}
+
+// TestCPU_nestest runs nestest.nes in automation mode from $C000
+// and compares CPU state with nestest.log line by line.
+func TestCPU_nestest(t *testing.T) {
+	f, err := os.Open(nestestROM)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Skipf("%s not found", nestestROM)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	logFile, err := os.Open(nestestLog)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Skipf("%s not found", nestestLog)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logFile.Close()
+
+	r, err := rom.NewRom(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ppu.NewPPU(r.CHR, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu := NewCPU(bus.NewBus(r, p))
+	cpu.PowerOn()
+	// automation modeは$C000から開始する
+	cpu.register.PC = 0xC000
+
+	scanner := bufio.NewScanner(logFile)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		want, err := parseNestestLine(line)
+		if err != nil {
+			t.Fatalf("line %d: %v", n, err)
+		}
+
+		got := cpu.nestestState(len(want.Bytes))
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("line %d: state mismatch (-want +got):\n%s\n%s", n, diff, line)
+		}
+		cpu.Run()
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseNestestLine(t *testing.T) {
+	t.Parallel()
+
+	got, err := parseNestestLine("C72E  B0 04     BCS $C734                       A:00 X:00 Y:00 P:27 SP:FB PPU:  1,  5 CYC:119")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &nestestState{
+		PC:    0xC72E,
+		Bytes: []byte{0xB0, 0x04},
+		P:     0x27,
+		SP:    0xFB,
+		CYC:   119,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
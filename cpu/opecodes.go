@@ -1,10 +1,80 @@
 package cpu
 
-var opecodes = map[byte]*instruction{
+import "fmt"
+
+// addressingMode is how an instruction specifies its operand.
+type addressingMode int
+
+const (
+	implied addressingMode = iota
+	accumulator
+	immediate
+	zeroPage
+	zeroPageX
+	zeroPageY
+	absolute
+	absoluteX
+	absoluteY
+	indirect
+	indirectX
+	indirectY
+	relative
+)
+
+var addressingModeNames = [...]string{
+	implied:     "Implied",
+	accumulator: "Accumulator",
+	immediate:   "Immediate",
+	zeroPage:    "ZeroPage",
+	zeroPageX:   "ZeroPageX",
+	zeroPageY:   "ZeroPageY",
+	absolute:    "Absolute",
+	absoluteX:   "AbsoluteX",
+	absoluteY:   "AbsoluteY",
+	indirect:    "Indirect",
+	indirectX:   "IndirectX",
+	indirectY:   "IndirectY",
+	relative:    "Relative",
+}
+
+func (m addressingMode) String() string {
+	if int(m) < len(addressingModeNames) {
+		return addressingModeNames[m]
+	}
+	return fmt.Sprintf("addressingMode(%d)", int(m))
+}
+
+// Opcode describes a supported instruction. It can be converted to asm.Opcode.
+type Opcode struct {
+	Code       byte
+	Name       string
+	Mode       string
+	Unofficial bool
+}
+
+// Opcodes returns the supported instructions in opcode order.
+func Opcodes() []Opcode {
+	var result []Opcode
+	for _, inst := range opecodes {
+		if inst == nil {
+			continue
+		}
+		result = append(result, Opcode{
+			Code:       inst.code,
+			Name:       inst.name,
+			Mode:       inst.mode.String(),
+			Unofficial: inst.unofficial,
+		})
+	}
+	return result
+}
+
+// opecodes is indexed by opcode. nil means the opcode is not supported.
+var opecodes = [256]*instruction{
 	0x00: {
 		code: 0x00,
 		name: "BRK",
-		mode: "Implied",
+		mode: implied,
 		description: "BRK命令は、強制的に割り込み要求を発生させます。プログラムカウンタとプロセッサステータスがスタックにプッシュされ、" +
 			"$FFFE/FのIRQ割り込みベクタがPCにロードされ、ステータス内のブレークフラグが1にセットされます。",
 		cycle: 7,
@@ -15,7 +85,7 @@ var opecodes = map[byte]*instruction{
 	0x08: {
 		code:        0x08,
 		name:        "PHP", // Push Processor Status
-		mode:        "Implied",
+		mode:        implied,
 		description: "Pushes a copy of the status flags on to the stack.",
 		cycle:       3,
 		// Z: not affected
@@ -25,7 +95,7 @@ var opecodes = map[byte]*instruction{
 	0x28: {
 		code: 0x28,
 		name: "PLP", // Pull Processor Status
-		mode: "Implied",
+		mode: implied,
 		description: "Pulls an 8 bit value from the stack and into the processor flags. " +
 			"The flags will take on new states as determined by the value pulled.",
 		cycle: 4,
@@ -41,7 +111,7 @@ var opecodes = map[byte]*instruction{
 	0x48: {
 		code:        0x48,
 		name:        "PHA", // Push Accumulator
-		mode:        "Implied",
+		mode:        implied,
 		description: "Pushes a copy of the accumulator on to the stack.",
 		cycle:       3,
 		// Z: not affected
@@ -51,7 +121,7 @@ var opecodes = map[byte]*instruction{
 	0x68: {
 		code:        0x68,
 		name:        "PLA", // Pull Accumulator
-		mode:        "Implied",
+		mode:        implied,
 		description: "Pulls an 8 bit value from the stack and into the accumulator. The zero and negative flags are set as appropriate.",
 		cycle:       4,
 		// Z: Set if A = 0
@@ -61,7 +131,7 @@ var opecodes = map[byte]*instruction{
 	0x18: {
 		code:        0x18,
 		name:        "CLC", // Clear carry flag
-		mode:        "Implied",
+		mode:        implied,
 		description: "Set the carry flag to 0",
 		cycle:       2,
 		// Z: not affected
@@ -72,7 +142,7 @@ var opecodes = map[byte]*instruction{
 	0x20: {
 		code:        0x20,
 		name:        "JSR", // Jump to subroutine
-		mode:        "Absolute",
+		mode:        absolute,
 		description: "サブルーチンを呼び出し",
 		cycle:       6,
 		// Z: not affected
@@ -82,7 +152,7 @@ var opecodes = map[byte]*instruction{
 	0x29: {
 		code:        0x29,
 		name:        "AND", // Logical AND
-		mode:        "Immediate",
+		mode:        immediate,
 		description: "A logical AND is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
 		cycle:       2,
 		// Z: Set if A = 0
@@ -92,7 +162,7 @@ var opecodes = map[byte]*instruction{
 	0xC9: {
 		code:        0xC9,
 		name:        "CMP", // Compare
-		mode:        "Immediate",
+		mode:        immediate,
 		description: "This instruction compares the contents of the accumulator with another memory held value and sets the zero and carry flags as appropriate.",
 		cycle:       2,
 		// C: Set if A >= M
@@ -103,7 +173,7 @@ var opecodes = map[byte]*instruction{
 	0x60: {
 		code:        0x60,
 		name:        "RTS", // Return from Subroutine
-		mode:        "Implied",
+		mode:        implied,
 		description: "サブルーチンから復帰",
 		cycle:       6,
 		// Z: not affected
@@ -113,7 +183,7 @@ var opecodes = map[byte]*instruction{
 	0x24: {
 		code:        0x24,
 		name:        "BIT", // Bit Test
-		mode:        "ZeroPage",
+		mode:        zeroPage,
 		description: "Aと0x00IM8番地の値をビット比較演算します",
 		cycle:       3,
 		// Z: Set if the result if the AND is zero
@@ -124,9 +194,9 @@ var opecodes = map[byte]*instruction{
 	0x38: {
 		code:        0x38,
 		name:        "SEC",
-		mode:        "Implied",
+		mode:        implied,
 		description: "Set carry flag",
-		cycle:       6,
+		cycle:       2,
 		// Z: not affected
 		// N: not affected
 		// bytes:1
@@ -134,7 +204,7 @@ var opecodes = map[byte]*instruction{
 	0x4C: {
 		code:        0x4C,
 		name:        "JMP",
-		mode:        "Absolute",
+		mode:        absolute,
 		description: "PCをIM16へジャンプ",
 		cycle:       3,
 		// Z: not affected
@@ -143,7 +213,7 @@ var opecodes = map[byte]*instruction{
 	0x10: {
 		code:        0x10,
 		name:        "BPL", // Branch if Positive
-		mode:        "Relative",
+		mode:        relative,
 		description: "ステータスレジスタのNがクリアされている場合アドレス「PC + IM8」へジャンプ",
 		cycle:       2, // 2 (+1 if branch succeeds +2 if to a new page)
 		// Z: not affected
@@ -153,7 +223,7 @@ var opecodes = map[byte]*instruction{
 	0x30: {
 		code:        0x30,
 		name:        "BMI", // Branch if Minus
-		mode:        "Relative",
+		mode:        relative,
 		description: "ステータスレジスタのNがセットされている場合アドレス「PC + IM8」へジャンプ",
 		cycle:       2, // 2 (+1 if branch succeeds +2 if to a new page)
 		// Z: not affected
@@ -163,7 +233,7 @@ var opecodes = map[byte]*instruction{
 	0x50: {
 		code:        0x50,
 		name:        "BVC", // Branch if Overflow Clear
-		mode:        "Relative",
+		mode:        relative,
 		description: "ステータスレジスタのVがクリアされている場合アドレス「PC + IM8」へジャンプ",
 		cycle:       2, // 2 (+1 if branch succeeds +2 if to a new page)
 		// Z: not affected
@@ -173,7 +243,7 @@ var opecodes = map[byte]*instruction{
 	0x70: {
 		code:        0x70,
 		name:        "BVS", // Branch if Overflow Set
-		mode:        "Relative",
+		mode:        relative,
 		description: "ステータスレジスタのVがセットされている場合アドレス「PC + IM8」へジャンプ",
 		cycle:       2, // 2 (+1 if branch succeeds +2 if to a new page)
 		// Z: not affected
@@ -183,7 +253,7 @@ var opecodes = map[byte]*instruction{
 	0x78: {
 		code:  0x78,
 		name:  "SEI",
-		mode:  "Implied",
+		mode:  implied,
 		cycle: 2,
 		// Z: not affected
 		// I: set to 1
@@ -192,7 +262,7 @@ var opecodes = map[byte]*instruction{
 	0x86: {
 		code:        0x86,
 		name:        "STX",
-		mode:        "ZeroPage", // 0x00を上位アドレス、PCに格納された値を下位アドレスとした番地を演算対象とする
+		mode:        zeroPage, // 0x00を上位アドレス、PCに格納された値を下位アドレスとした番地を演算対象とする
 		description: "Stores the contents of the X register into memory",
 		cycle:       3,
 		// Z: not affected
@@ -202,7 +272,7 @@ var opecodes = map[byte]*instruction{
 	0x90: {
 		code:        0x90,
 		name:        "BCC", // Branch if Carry Clear
-		mode:        "Relative",
+		mode:        relative,
 		description: "If the carry flag is clear then add the relative displacement to the program counter to cause a branch to a new location.",
 		cycle:       2, // 2 (+1 if branch succeeds +2 if to a new page)
 		// Z: not affected
@@ -212,7 +282,7 @@ var opecodes = map[byte]*instruction{
 	0x85: {
 		code:        0x85,
 		name:        "STA",
-		mode:        "ZeroPage",
+		mode:        zeroPage,
 		description: "Aの内容をアドレス「MI8 | 0x00<<8 」に書き込む",
 		cycle:       3,
 		// Z: not affected
@@ -222,7 +292,7 @@ var opecodes = map[byte]*instruction{
 	0x8D: {
 		code:        0x8D,
 		name:        "STA",
-		mode:        "Absolute",
+		mode:        absolute,
 		description: "Aの内容をアドレス「IM16」に書き込む",
 		cycle:       4,
 		// Z: not affected
@@ -231,7 +301,7 @@ var opecodes = map[byte]*instruction{
 	0x9A: {
 		code:        0x9A,
 		name:        "TXS",
-		mode:        "Implied",
+		mode:        implied,
 		description: "XをSへコピー",
 		cycle:       2,
 		// Z: not affected
@@ -240,7 +310,7 @@ var opecodes = map[byte]*instruction{
 	0xA0: {
 		code:        0xA0,
 		name:        "LDY",
-		mode:        "Immediate",
+		mode:        immediate,
 		description: "次アドレスの即値をYにロード",
 		cycle:       2,
 		// Z:Set if Y = 0
@@ -249,7 +319,7 @@ var opecodes = map[byte]*instruction{
 	0xA2: {
 		code:        0xA2,
 		name:        "LDX",
-		mode:        "Immediate",
+		mode:        immediate,
 		description: "次アドレスの即値をXにロード",
 		cycle:       2,
 		// Z:Set if X = 0
@@ -258,7 +328,7 @@ var opecodes = map[byte]*instruction{
 	0xA9: {
 		code:        0xA9,
 		name:        "LDA",
-		mode:        "Immediate",
+		mode:        immediate,
 		description: "次アドレスの即値をAにロード",
 		cycle:       2,
 		// Z:Set if A = 0
@@ -267,18 +337,19 @@ var opecodes = map[byte]*instruction{
 	0xB0: {
 		code:        0xB0,
 		name:        "BCS", // Branch if Carry Set
-		mode:        "Relative",
+		mode:        relative,
 		description: "If the carry flag is set then add the relative displacement to the program counter to cause a branch to a new location",
-		cycle:       2,
+		cycle:       2, // 2 (+1 if branch succeeds +2 if to a new page)
 		// Z: not affected
 		// N: not affected
 	},
 	0xBD: {
 		code:        0xBD,
 		name:        "LDA",
-		mode:        "AbsoluteX",
+		mode:        absoluteX,
 		description: "アドレス「IM16 + X」の8bit値をAにロード",
 		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
 		// Bytes:3
 		// Z:Set if A = 0
 		// N:Set if bit 7 of A is set
@@ -286,16 +357,16 @@ var opecodes = map[byte]*instruction{
 	0xD0: {
 		code:        0xD0,
 		name:        "BNE",
-		mode:        "Relative",
+		mode:        relative,
 		description: "Branch on not equal 0. ステータスレジスタのZがクリアされている場合アドレス「PC + IM8」へジャンプ",
-		cycle:       3, // 4の場合もある
+		cycle:       2, // 2 (+1 if branch succeeds +2 if to a new page)
 		// Z: not affected
 		// N: not affected
 	},
 	0xE8: {
 		code:        0xE8,
 		name:        "INX",
-		mode:        "Implied",
+		mode:        implied,
 		description: "Xをインクリメント",
 		cycle:       2,
 		// Z:Set if X = 0
@@ -304,7 +375,7 @@ var opecodes = map[byte]*instruction{
 	0xEA: {
 		code:        0xEA,
 		name:        "NOP",
-		mode:        "Implied",
+		mode:        implied,
 		description: "No operation",
 		cycle:       2,
 		// Z: not affected
@@ -314,7 +385,7 @@ var opecodes = map[byte]*instruction{
 	0x88: {
 		code:        0x88,
 		name:        "DEY",
-		mode:        "Implied",
+		mode:        implied,
 		description: "Yをデクリメント",
 		cycle:       2,
 		// Z:Set if Y = 0
@@ -323,9 +394,9 @@ var opecodes = map[byte]*instruction{
 	0xF0: {
 		code:        0xF0,
 		name:        "BEQ",
-		mode:        "Relative",
+		mode:        relative,
 		description: "Branch on equal 0. ステータスレジスタのZがセットされている場合アドレス「PC + IM8」へジャンプ",
-		cycle:       3, // 2 (+1 if branch succeeds +2 if to a new page)
+		cycle:       2, // 2 (+1 if branch succeeds +2 if to a new page)
 		// Z: not affected
 		// N: not affected
 		// bytes:2
@@ -333,16 +404,16 @@ var opecodes = map[byte]*instruction{
 	0xF6: {
 		code:        0xF6,
 		name:        "INC",
-		mode:        "ZeroPageX",
+		mode:        zeroPageX,
 		description: "Increment Memory by One. アドレス「IM8 + X」の値をインクリメント.",
 		cycle:       6,
 		// Z:Set if result = 0
 		// N:Set if bit 7 of result is set
 	},
 	0xD8: {
-		code:        0xF8, // Clear Decimal Flag
+		code:        0xD8, // Clear Decimal Flag
 		name:        "CLD",
-		mode:        "Implied",
+		mode:        implied,
 		description: "Set the decimal mode flag to 0.",
 		cycle:       2,
 		// Z: not affected
@@ -353,7 +424,7 @@ var opecodes = map[byte]*instruction{
 	0xF8: {
 		code:        0xF8, // Set Decimal Flag
 		name:        "SED",
-		mode:        "Implied",
+		mode:        implied,
 		description: "Set the decimal mode flag to one.",
 		cycle:       2,
 		// Z: not affected
@@ -361,4 +432,2255 @@ var opecodes = map[byte]*instruction{
 		// D: Set to 1
 		// bytes:1
 	},
+	0x69: {
+		code:        0x69,
+		name:        "ADC", // Add with Carry
+		mode:        immediate,
+		description: "This instruction adds the contents of a memory location to the accumulator together with the carry bit. If overflow occurs the carry bit is set, this enables multiple byte addition to be performed.",
+		cycle:       2,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x65: {
+		code:        0x65,
+		name:        "ADC", // Add with Carry
+		mode:        zeroPage,
+		description: "This instruction adds the contents of a memory location to the accumulator together with the carry bit. If overflow occurs the carry bit is set, this enables multiple byte addition to be performed.",
+		cycle:       3,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x75: {
+		code:        0x75,
+		name:        "ADC", // Add with Carry
+		mode:        zeroPageX,
+		description: "This instruction adds the contents of a memory location to the accumulator together with the carry bit. If overflow occurs the carry bit is set, this enables multiple byte addition to be performed.",
+		cycle:       4,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x6D: {
+		code:        0x6D,
+		name:        "ADC", // Add with Carry
+		mode:        absolute,
+		description: "This instruction adds the contents of a memory location to the accumulator together with the carry bit. If overflow occurs the carry bit is set, this enables multiple byte addition to be performed.",
+		cycle:       4,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x7D: {
+		code:        0x7D,
+		name:        "ADC", // Add with Carry
+		mode:        absoluteX,
+		description: "This instruction adds the contents of a memory location to the accumulator together with the carry bit. If overflow occurs the carry bit is set, this enables multiple byte addition to be performed.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x79: {
+		code:        0x79,
+		name:        "ADC", // Add with Carry
+		mode:        absoluteY,
+		description: "This instruction adds the contents of a memory location to the accumulator together with the carry bit. If overflow occurs the carry bit is set, this enables multiple byte addition to be performed.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x61: {
+		code:        0x61,
+		name:        "ADC", // Add with Carry
+		mode:        indirectX,
+		description: "This instruction adds the contents of a memory location to the accumulator together with the carry bit. If overflow occurs the carry bit is set, this enables multiple byte addition to be performed.",
+		cycle:       6,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x71: {
+		code:        0x71,
+		name:        "ADC", // Add with Carry
+		mode:        indirectY,
+		description: "This instruction adds the contents of a memory location to the accumulator together with the carry bit. If overflow occurs the carry bit is set, this enables multiple byte addition to be performed.",
+		cycle:       5,
+		pageCycle:   true, // +1 if page crossed
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x25: {
+		code:        0x25,
+		name:        "AND", // Logical AND
+		mode:        zeroPage,
+		description: "A logical AND is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       3,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x35: {
+		code:        0x35,
+		name:        "AND", // Logical AND
+		mode:        zeroPageX,
+		description: "A logical AND is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x2D: {
+		code:        0x2D,
+		name:        "AND", // Logical AND
+		mode:        absolute,
+		description: "A logical AND is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x3D: {
+		code:        0x3D,
+		name:        "AND", // Logical AND
+		mode:        absoluteX,
+		description: "A logical AND is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x39: {
+		code:        0x39,
+		name:        "AND", // Logical AND
+		mode:        absoluteY,
+		description: "A logical AND is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x21: {
+		code:        0x21,
+		name:        "AND", // Logical AND
+		mode:        indirectX,
+		description: "A logical AND is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       6,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x31: {
+		code:        0x31,
+		name:        "AND", // Logical AND
+		mode:        indirectY,
+		description: "A logical AND is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       5,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x0A: {
+		code:        0x0A,
+		name:        "ASL", // Arithmetic Shift Left
+		mode:        accumulator,
+		description: "This operation shifts all the bits of the accumulator or memory contents one bit left. Bit 0 is set to 0 and bit 7 is placed in the carry flag.",
+		cycle:       2,
+		// C: Set to contents of old bit 7
+		// Z: Set if result = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:1
+	},
+	0x06: {
+		code:        0x06,
+		name:        "ASL", // Arithmetic Shift Left
+		mode:        zeroPage,
+		description: "This operation shifts all the bits of the accumulator or memory contents one bit left. Bit 0 is set to 0 and bit 7 is placed in the carry flag.",
+		cycle:       5,
+		// C: Set to contents of old bit 7
+		// Z: Set if result = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0x16: {
+		code:        0x16,
+		name:        "ASL", // Arithmetic Shift Left
+		mode:        zeroPageX,
+		description: "This operation shifts all the bits of the accumulator or memory contents one bit left. Bit 0 is set to 0 and bit 7 is placed in the carry flag.",
+		cycle:       6,
+		// C: Set to contents of old bit 7
+		// Z: Set if result = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0x0E: {
+		code:        0x0E,
+		name:        "ASL", // Arithmetic Shift Left
+		mode:        absolute,
+		description: "This operation shifts all the bits of the accumulator or memory contents one bit left. Bit 0 is set to 0 and bit 7 is placed in the carry flag.",
+		cycle:       6,
+		// C: Set to contents of old bit 7
+		// Z: Set if result = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0x1E: {
+		code:        0x1E,
+		name:        "ASL", // Arithmetic Shift Left
+		mode:        absoluteX,
+		description: "This operation shifts all the bits of the accumulator or memory contents one bit left. Bit 0 is set to 0 and bit 7 is placed in the carry flag.",
+		cycle:       7,
+		// C: Set to contents of old bit 7
+		// Z: Set if result = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0x2C: {
+		code:        0x2C,
+		name:        "BIT", // Bit Test
+		mode:        absolute,
+		description: "This instructions is used to test if one or more bits are set in a target memory location. The mask pattern in A is ANDed with the value in memory to set or clear the zero flag, but the result is not kept. Bits 7 and 6 of the value from memory are copied into the N and V flags.",
+		cycle:       4,
+		// Z: Set if the result if the AND is zero
+		// V: Set to bit 6 of the memory value
+		// N: Set to bit 7 of the memory value
+		// bytes:3
+	},
+	0x58: {
+		code:        0x58,
+		name:        "CLI", // Clear Interrupt Disable
+		mode:        implied,
+		description: "Clears the interrupt disable flag allowing normal interrupt requests to be serviced.",
+		cycle:       2,
+		// I: Set to 0
+		// bytes:1
+	},
+	0xB8: {
+		code:        0xB8,
+		name:        "CLV", // Clear Overflow Flag
+		mode:        implied,
+		description: "Clears the overflow flag.",
+		cycle:       2,
+		// V: Set to 0
+		// bytes:1
+	},
+	0xC5: {
+		code:        0xC5,
+		name:        "CMP", // Compare
+		mode:        zeroPage,
+		description: "This instruction compares the contents of the accumulator with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       3,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xD5: {
+		code:        0xD5,
+		name:        "CMP", // Compare
+		mode:        zeroPageX,
+		description: "This instruction compares the contents of the accumulator with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       4,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xCD: {
+		code:        0xCD,
+		name:        "CMP", // Compare
+		mode:        absolute,
+		description: "This instruction compares the contents of the accumulator with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       4,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xDD: {
+		code:        0xDD,
+		name:        "CMP", // Compare
+		mode:        absoluteX,
+		description: "This instruction compares the contents of the accumulator with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xD9: {
+		code:        0xD9,
+		name:        "CMP", // Compare
+		mode:        absoluteY,
+		description: "This instruction compares the contents of the accumulator with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xC1: {
+		code:        0xC1,
+		name:        "CMP", // Compare
+		mode:        indirectX,
+		description: "This instruction compares the contents of the accumulator with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       6,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xD1: {
+		code:        0xD1,
+		name:        "CMP", // Compare
+		mode:        indirectY,
+		description: "This instruction compares the contents of the accumulator with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       5,
+		pageCycle:   true, // +1 if page crossed
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xE0: {
+		code:        0xE0,
+		name:        "CPX", // Compare X Register
+		mode:        immediate,
+		description: "This instruction compares the contents of the X register with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       2,
+		// C: Set if X >= M
+		// Z: Set if X = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xE4: {
+		code:        0xE4,
+		name:        "CPX", // Compare X Register
+		mode:        zeroPage,
+		description: "This instruction compares the contents of the X register with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       3,
+		// C: Set if X >= M
+		// Z: Set if X = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xEC: {
+		code:        0xEC,
+		name:        "CPX", // Compare X Register
+		mode:        absolute,
+		description: "This instruction compares the contents of the X register with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       4,
+		// C: Set if X >= M
+		// Z: Set if X = M
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xC0: {
+		code:        0xC0,
+		name:        "CPY", // Compare Y Register
+		mode:        immediate,
+		description: "This instruction compares the contents of the Y register with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       2,
+		// C: Set if Y >= M
+		// Z: Set if Y = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xC4: {
+		code:        0xC4,
+		name:        "CPY", // Compare Y Register
+		mode:        zeroPage,
+		description: "This instruction compares the contents of the Y register with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       3,
+		// C: Set if Y >= M
+		// Z: Set if Y = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xCC: {
+		code:        0xCC,
+		name:        "CPY", // Compare Y Register
+		mode:        absolute,
+		description: "This instruction compares the contents of the Y register with another memory held value and sets the zero and carry flags as appropriate.",
+		cycle:       4,
+		// C: Set if Y >= M
+		// Z: Set if Y = M
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xC6: {
+		code:        0xC6,
+		name:        "DEC", // Decrement Memory
+		mode:        zeroPage,
+		description: "Subtracts one from the value held at a specified memory location setting the zero and negative flags as appropriate.",
+		cycle:       5,
+		// Z: Set if result is zero
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xD6: {
+		code:        0xD6,
+		name:        "DEC", // Decrement Memory
+		mode:        zeroPageX,
+		description: "Subtracts one from the value held at a specified memory location setting the zero and negative flags as appropriate.",
+		cycle:       6,
+		// Z: Set if result is zero
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xCE: {
+		code:        0xCE,
+		name:        "DEC", // Decrement Memory
+		mode:        absolute,
+		description: "Subtracts one from the value held at a specified memory location setting the zero and negative flags as appropriate.",
+		cycle:       6,
+		// Z: Set if result is zero
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xDE: {
+		code:        0xDE,
+		name:        "DEC", // Decrement Memory
+		mode:        absoluteX,
+		description: "Subtracts one from the value held at a specified memory location setting the zero and negative flags as appropriate.",
+		cycle:       7,
+		// Z: Set if result is zero
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xCA: {
+		code:        0xCA,
+		name:        "DEX", // Decrement X Register
+		mode:        implied,
+		description: "Subtracts one from the X register setting the zero and negative flags as appropriate.",
+		cycle:       2,
+		// Z: Set if X is zero
+		// N: Set if bit 7 of X is set
+		// bytes:1
+	},
+	0x49: {
+		code:        0x49,
+		name:        "EOR", // Exclusive OR
+		mode:        immediate,
+		description: "An exclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       2,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x45: {
+		code:        0x45,
+		name:        "EOR", // Exclusive OR
+		mode:        zeroPage,
+		description: "An exclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       3,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x55: {
+		code:        0x55,
+		name:        "EOR", // Exclusive OR
+		mode:        zeroPageX,
+		description: "An exclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x4D: {
+		code:        0x4D,
+		name:        "EOR", // Exclusive OR
+		mode:        absolute,
+		description: "An exclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x5D: {
+		code:        0x5D,
+		name:        "EOR", // Exclusive OR
+		mode:        absoluteX,
+		description: "An exclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x59: {
+		code:        0x59,
+		name:        "EOR", // Exclusive OR
+		mode:        absoluteY,
+		description: "An exclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x41: {
+		code:        0x41,
+		name:        "EOR", // Exclusive OR
+		mode:        indirectX,
+		description: "An exclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       6,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x51: {
+		code:        0x51,
+		name:        "EOR", // Exclusive OR
+		mode:        indirectY,
+		description: "An exclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       5,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0xE6: {
+		code:        0xE6,
+		name:        "INC", // Increment Memory
+		mode:        zeroPage,
+		description: "Adds one to the value held at a specified memory location setting the zero and negative flags as appropriate.",
+		cycle:       5,
+		// Z: Set if result is zero
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xEE: {
+		code:        0xEE,
+		name:        "INC", // Increment Memory
+		mode:        absolute,
+		description: "Adds one to the value held at a specified memory location setting the zero and negative flags as appropriate.",
+		cycle:       6,
+		// Z: Set if result is zero
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xFE: {
+		code:        0xFE,
+		name:        "INC", // Increment Memory
+		mode:        absoluteX,
+		description: "Adds one to the value held at a specified memory location setting the zero and negative flags as appropriate.",
+		cycle:       7,
+		// Z: Set if result is zero
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xC8: {
+		code:        0xC8,
+		name:        "INY", // Increment Y Register
+		mode:        implied,
+		description: "Adds one to the Y register setting the zero and negative flags as appropriate.",
+		cycle:       2,
+		// Z: Set if Y is zero
+		// N: Set if bit 7 of Y is set
+		// bytes:1
+	},
+	0x6C: {
+		code:        0x6C,
+		name:        "JMP", // Jump
+		mode:        indirect,
+		description: "Sets the program counter to the address specified by the operand. An original 6502 does not correctly fetch the target address if the indirect vector falls on a page boundary (e.g. $xxFF).",
+		cycle:       5,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0xA5: {
+		code:        0xA5,
+		name:        "LDA", // Load Accumulator
+		mode:        zeroPage,
+		description: "Loads a byte of memory into the accumulator setting the zero and negative flags as appropriate.",
+		cycle:       3,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xB5: {
+		code:        0xB5,
+		name:        "LDA", // Load Accumulator
+		mode:        zeroPageX,
+		description: "Loads a byte of memory into the accumulator setting the zero and negative flags as appropriate.",
+		cycle:       4,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xAD: {
+		code:        0xAD,
+		name:        "LDA", // Load Accumulator
+		mode:        absolute,
+		description: "Loads a byte of memory into the accumulator setting the zero and negative flags as appropriate.",
+		cycle:       4,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0xB9: {
+		code:        0xB9,
+		name:        "LDA", // Load Accumulator
+		mode:        absoluteY,
+		description: "Loads a byte of memory into the accumulator setting the zero and negative flags as appropriate.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0xA1: {
+		code:        0xA1,
+		name:        "LDA", // Load Accumulator
+		mode:        indirectX,
+		description: "Loads a byte of memory into the accumulator setting the zero and negative flags as appropriate.",
+		cycle:       6,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xB1: {
+		code:        0xB1,
+		name:        "LDA", // Load Accumulator
+		mode:        indirectY,
+		description: "Loads a byte of memory into the accumulator setting the zero and negative flags as appropriate.",
+		cycle:       5,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xA6: {
+		code:        0xA6,
+		name:        "LDX", // Load X Register
+		mode:        zeroPage,
+		description: "Loads a byte of memory into the X register setting the zero and negative flags as appropriate.",
+		cycle:       3,
+		// Z: Set if X = 0
+		// N: Set if bit 7 of X is set
+		// bytes:2
+	},
+	0xB6: {
+		code:        0xB6,
+		name:        "LDX", // Load X Register
+		mode:        zeroPageY,
+		description: "Loads a byte of memory into the X register setting the zero and negative flags as appropriate.",
+		cycle:       4,
+		// Z: Set if X = 0
+		// N: Set if bit 7 of X is set
+		// bytes:2
+	},
+	0xAE: {
+		code:        0xAE,
+		name:        "LDX", // Load X Register
+		mode:        absolute,
+		description: "Loads a byte of memory into the X register setting the zero and negative flags as appropriate.",
+		cycle:       4,
+		// Z: Set if X = 0
+		// N: Set if bit 7 of X is set
+		// bytes:3
+	},
+	0xBE: {
+		code:        0xBE,
+		name:        "LDX", // Load X Register
+		mode:        absoluteY,
+		description: "Loads a byte of memory into the X register setting the zero and negative flags as appropriate.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if X = 0
+		// N: Set if bit 7 of X is set
+		// bytes:3
+	},
+	0xA4: {
+		code:        0xA4,
+		name:        "LDY", // Load Y Register
+		mode:        zeroPage,
+		description: "Loads a byte of memory into the Y register setting the zero and negative flags as appropriate.",
+		cycle:       3,
+		// Z: Set if Y = 0
+		// N: Set if bit 7 of Y is set
+		// bytes:2
+	},
+	0xB4: {
+		code:        0xB4,
+		name:        "LDY", // Load Y Register
+		mode:        zeroPageX,
+		description: "Loads a byte of memory into the Y register setting the zero and negative flags as appropriate.",
+		cycle:       4,
+		// Z: Set if Y = 0
+		// N: Set if bit 7 of Y is set
+		// bytes:2
+	},
+	0xAC: {
+		code:        0xAC,
+		name:        "LDY", // Load Y Register
+		mode:        absolute,
+		description: "Loads a byte of memory into the Y register setting the zero and negative flags as appropriate.",
+		cycle:       4,
+		// Z: Set if Y = 0
+		// N: Set if bit 7 of Y is set
+		// bytes:3
+	},
+	0xBC: {
+		code:        0xBC,
+		name:        "LDY", // Load Y Register
+		mode:        absoluteX,
+		description: "Loads a byte of memory into the Y register setting the zero and negative flags as appropriate.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if Y = 0
+		// N: Set if bit 7 of Y is set
+		// bytes:3
+	},
+	0x4A: {
+		code:        0x4A,
+		name:        "LSR", // Logical Shift Right
+		mode:        accumulator,
+		description: "Each of the bits in A or M is shift one place to the right. The bit that was in bit 0 is shifted into the carry flag. Bit 7 is set to zero.",
+		cycle:       2,
+		// C: Set to contents of old bit 0
+		// Z: Set if result = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:1
+	},
+	0x46: {
+		code:        0x46,
+		name:        "LSR", // Logical Shift Right
+		mode:        zeroPage,
+		description: "Each of the bits in A or M is shift one place to the right. The bit that was in bit 0 is shifted into the carry flag. Bit 7 is set to zero.",
+		cycle:       5,
+		// C: Set to contents of old bit 0
+		// Z: Set if result = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0x56: {
+		code:        0x56,
+		name:        "LSR", // Logical Shift Right
+		mode:        zeroPageX,
+		description: "Each of the bits in A or M is shift one place to the right. The bit that was in bit 0 is shifted into the carry flag. Bit 7 is set to zero.",
+		cycle:       6,
+		// C: Set to contents of old bit 0
+		// Z: Set if result = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0x4E: {
+		code:        0x4E,
+		name:        "LSR", // Logical Shift Right
+		mode:        absolute,
+		description: "Each of the bits in A or M is shift one place to the right. The bit that was in bit 0 is shifted into the carry flag. Bit 7 is set to zero.",
+		cycle:       6,
+		// C: Set to contents of old bit 0
+		// Z: Set if result = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0x5E: {
+		code:        0x5E,
+		name:        "LSR", // Logical Shift Right
+		mode:        absoluteX,
+		description: "Each of the bits in A or M is shift one place to the right. The bit that was in bit 0 is shifted into the carry flag. Bit 7 is set to zero.",
+		cycle:       7,
+		// C: Set to contents of old bit 0
+		// Z: Set if result = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0x09: {
+		code:        0x09,
+		name:        "ORA", // Logical Inclusive OR
+		mode:        immediate,
+		description: "An inclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       2,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x05: {
+		code:        0x05,
+		name:        "ORA", // Logical Inclusive OR
+		mode:        zeroPage,
+		description: "An inclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       3,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x15: {
+		code:        0x15,
+		name:        "ORA", // Logical Inclusive OR
+		mode:        zeroPageX,
+		description: "An inclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x0D: {
+		code:        0x0D,
+		name:        "ORA", // Logical Inclusive OR
+		mode:        absolute,
+		description: "An inclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x1D: {
+		code:        0x1D,
+		name:        "ORA", // Logical Inclusive OR
+		mode:        absoluteX,
+		description: "An inclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x19: {
+		code:        0x19,
+		name:        "ORA", // Logical Inclusive OR
+		mode:        absoluteY,
+		description: "An inclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0x01: {
+		code:        0x01,
+		name:        "ORA", // Logical Inclusive OR
+		mode:        indirectX,
+		description: "An inclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       6,
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x11: {
+		code:        0x11,
+		name:        "ORA", // Logical Inclusive OR
+		mode:        indirectY,
+		description: "An inclusive OR is performed, bit by bit, on the accumulator contents using the contents of a byte of memory.",
+		cycle:       5,
+		pageCycle:   true, // +1 if page crossed
+		// Z: Set if A = 0
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x2A: {
+		code:        0x2A,
+		name:        "ROL", // Rotate Left
+		mode:        accumulator,
+		description: "Move each of the bits in either A or M one place to the left. Bit 0 is filled with the current value of the carry flag whilst the old bit 7 becomes the new carry flag value.",
+		cycle:       2,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:1
+	},
+	0x26: {
+		code:        0x26,
+		name:        "ROL", // Rotate Left
+		mode:        zeroPage,
+		description: "Move each of the bits in either A or M one place to the left. Bit 0 is filled with the current value of the carry flag whilst the old bit 7 becomes the new carry flag value.",
+		cycle:       5,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0x36: {
+		code:        0x36,
+		name:        "ROL", // Rotate Left
+		mode:        zeroPageX,
+		description: "Move each of the bits in either A or M one place to the left. Bit 0 is filled with the current value of the carry flag whilst the old bit 7 becomes the new carry flag value.",
+		cycle:       6,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0x2E: {
+		code:        0x2E,
+		name:        "ROL", // Rotate Left
+		mode:        absolute,
+		description: "Move each of the bits in either A or M one place to the left. Bit 0 is filled with the current value of the carry flag whilst the old bit 7 becomes the new carry flag value.",
+		cycle:       6,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0x3E: {
+		code:        0x3E,
+		name:        "ROL", // Rotate Left
+		mode:        absoluteX,
+		description: "Move each of the bits in either A or M one place to the left. Bit 0 is filled with the current value of the carry flag whilst the old bit 7 becomes the new carry flag value.",
+		cycle:       7,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0x6A: {
+		code:        0x6A,
+		name:        "ROR", // Rotate Right
+		mode:        accumulator,
+		description: "Move each of the bits in either A or M one place to the right. Bit 7 is filled with the current value of the carry flag whilst the old bit 0 becomes the new carry flag value.",
+		cycle:       2,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:1
+	},
+	0x66: {
+		code:        0x66,
+		name:        "ROR", // Rotate Right
+		mode:        zeroPage,
+		description: "Move each of the bits in either A or M one place to the right. Bit 7 is filled with the current value of the carry flag whilst the old bit 0 becomes the new carry flag value.",
+		cycle:       5,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0x76: {
+		code:        0x76,
+		name:        "ROR", // Rotate Right
+		mode:        zeroPageX,
+		description: "Move each of the bits in either A or M one place to the right. Bit 7 is filled with the current value of the carry flag whilst the old bit 0 becomes the new carry flag value.",
+		cycle:       6,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0x6E: {
+		code:        0x6E,
+		name:        "ROR", // Rotate Right
+		mode:        absolute,
+		description: "Move each of the bits in either A or M one place to the right. Bit 7 is filled with the current value of the carry flag whilst the old bit 0 becomes the new carry flag value.",
+		cycle:       6,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0x7E: {
+		code:        0x7E,
+		name:        "ROR", // Rotate Right
+		mode:        absoluteX,
+		description: "Move each of the bits in either A or M one place to the right. Bit 7 is filled with the current value of the carry flag whilst the old bit 0 becomes the new carry flag value.",
+		cycle:       7,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0x40: {
+		code:        0x40,
+		name:        "RTI", // Return from Interrupt
+		mode:        implied,
+		description: "The RTI instruction is used at the end of an interrupt processing routine. It pulls the processor flags from the stack followed by the program counter.",
+		cycle:       6,
+		// C: Set from stack
+		// Z: Set from stack
+		// I: Set from stack
+		// D: Set from stack
+		// B: Set from stack
+		// V: Set from stack
+		// N: Set from stack
+		// bytes:1
+	},
+	0xE9: {
+		code:        0xE9,
+		name:        "SBC", // Subtract with Carry
+		mode:        immediate,
+		description: "This instruction subtracts the contents of a memory location to the accumulator together with the not of the carry bit. If overflow occurs the carry bit is clear, this enables multiple byte subtraction to be performed.",
+		cycle:       2,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0xE5: {
+		code:        0xE5,
+		name:        "SBC", // Subtract with Carry
+		mode:        zeroPage,
+		description: "This instruction subtracts the contents of a memory location to the accumulator together with the not of the carry bit. If overflow occurs the carry bit is clear, this enables multiple byte subtraction to be performed.",
+		cycle:       3,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0xF5: {
+		code:        0xF5,
+		name:        "SBC", // Subtract with Carry
+		mode:        zeroPageX,
+		description: "This instruction subtracts the contents of a memory location to the accumulator together with the not of the carry bit. If overflow occurs the carry bit is clear, this enables multiple byte subtraction to be performed.",
+		cycle:       4,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0xED: {
+		code:        0xED,
+		name:        "SBC", // Subtract with Carry
+		mode:        absolute,
+		description: "This instruction subtracts the contents of a memory location to the accumulator together with the not of the carry bit. If overflow occurs the carry bit is clear, this enables multiple byte subtraction to be performed.",
+		cycle:       4,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0xFD: {
+		code:        0xFD,
+		name:        "SBC", // Subtract with Carry
+		mode:        absoluteX,
+		description: "This instruction subtracts the contents of a memory location to the accumulator together with the not of the carry bit. If overflow occurs the carry bit is clear, this enables multiple byte subtraction to be performed.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0xF9: {
+		code:        0xF9,
+		name:        "SBC", // Subtract with Carry
+		mode:        absoluteY,
+		description: "This instruction subtracts the contents of a memory location to the accumulator together with the not of the carry bit. If overflow occurs the carry bit is clear, this enables multiple byte subtraction to be performed.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:3
+	},
+	0xE1: {
+		code:        0xE1,
+		name:        "SBC", // Subtract with Carry
+		mode:        indirectX,
+		description: "This instruction subtracts the contents of a memory location to the accumulator together with the not of the carry bit. If overflow occurs the carry bit is clear, this enables multiple byte subtraction to be performed.",
+		cycle:       6,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0xF1: {
+		code:        0xF1,
+		name:        "SBC", // Subtract with Carry
+		mode:        indirectY,
+		description: "This instruction subtracts the contents of a memory location to the accumulator together with the not of the carry bit. If overflow occurs the carry bit is clear, this enables multiple byte subtraction to be performed.",
+		cycle:       5,
+		pageCycle:   true, // +1 if page crossed
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x95: {
+		code:        0x95,
+		name:        "STA", // Store Accumulator
+		mode:        zeroPageX,
+		description: "Stores the contents of the accumulator into memory.",
+		cycle:       4,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x9D: {
+		code:        0x9D,
+		name:        "STA", // Store Accumulator
+		mode:        absoluteX,
+		description: "Stores the contents of the accumulator into memory.",
+		cycle:       5,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0x99: {
+		code:        0x99,
+		name:        "STA", // Store Accumulator
+		mode:        absoluteY,
+		description: "Stores the contents of the accumulator into memory.",
+		cycle:       5,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0x81: {
+		code:        0x81,
+		name:        "STA", // Store Accumulator
+		mode:        indirectX,
+		description: "Stores the contents of the accumulator into memory.",
+		cycle:       6,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x91: {
+		code:        0x91,
+		name:        "STA", // Store Accumulator
+		mode:        indirectY,
+		description: "Stores the contents of the accumulator into memory.",
+		cycle:       6,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x96: {
+		code:        0x96,
+		name:        "STX", // Store X Register
+		mode:        zeroPageY,
+		description: "Stores the contents of the X register into memory.",
+		cycle:       4,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x8E: {
+		code:        0x8E,
+		name:        "STX", // Store X Register
+		mode:        absolute,
+		description: "Stores the contents of the X register into memory.",
+		cycle:       4,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0x84: {
+		code:        0x84,
+		name:        "STY", // Store Y Register
+		mode:        zeroPage,
+		description: "Stores the contents of the Y register into memory.",
+		cycle:       3,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x94: {
+		code:        0x94,
+		name:        "STY", // Store Y Register
+		mode:        zeroPageX,
+		description: "Stores the contents of the Y register into memory.",
+		cycle:       4,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x8C: {
+		code:        0x8C,
+		name:        "STY", // Store Y Register
+		mode:        absolute,
+		description: "Stores the contents of the Y register into memory.",
+		cycle:       4,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0xAA: {
+		code:        0xAA,
+		name:        "TAX", // Transfer Accumulator to X
+		mode:        implied,
+		description: "Copies the current contents of the accumulator into the X register and sets the zero and negative flags as appropriate.",
+		cycle:       2,
+		// Z: Set if X = 0
+		// N: Set if bit 7 of X is set
+		// bytes:1
+	},
+	0xA8: {
+		code:        0xA8,
+		name:        "TAY", // Transfer Accumulator to Y
+		mode:        implied,
+		description: "Copies the current contents of the accumulator into the Y register and sets the zero and negative flags as appropriate.",
+		cycle:       2,
+		// Z: Set if Y = 0
+		// N: Set if bit 7 of Y is set
+		// bytes:1
+	},
+	0xBA: {
+		code:        0xBA,
+		name:        "TSX", // Transfer Stack Pointer to X
+		mode:        implied,
+		description: "Copies the current contents of the stack register into the X register and sets the zero and negative flags as appropriate.",
+		cycle:       2,
+		// Z: Set if X = 0
+		// N: Set if bit 7 of X is set
+		// bytes:1
+	},
+	0x8A: {
+		code:        0x8A,
+		name:        "TXA", // Transfer X to Accumulator
+		mode:        implied,
+		description: "Copies the current contents of the X register into the accumulator and sets the zero and negative flags as appropriate.",
+		cycle:       2,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:1
+	},
+	0x98: {
+		code:        0x98,
+		name:        "TYA", // Transfer Y to Accumulator
+		mode:        implied,
+		description: "Copies the current contents of the Y register into the accumulator and sets the zero and negative flags as appropriate.",
+		cycle:       2,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:1
+	},
+
+	// Unofficial opcodes
+	// https://www.nesdev.org/wiki/CPU_unofficial_opcodes
+	0x07: {
+		code:        0x07,
+		name:        "SLO", // ASL + ORA
+		mode:        zeroPage,
+		description: "Shift left one bit in memory, then OR accumulator with memory.",
+		cycle:       5,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x17: {
+		code:        0x17,
+		name:        "SLO", // ASL + ORA
+		mode:        zeroPageX,
+		description: "Shift left one bit in memory, then OR accumulator with memory.",
+		cycle:       6,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x0F: {
+		code:        0x0F,
+		name:        "SLO", // ASL + ORA
+		mode:        absolute,
+		description: "Shift left one bit in memory, then OR accumulator with memory.",
+		cycle:       6,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x1F: {
+		code:        0x1F,
+		name:        "SLO", // ASL + ORA
+		mode:        absoluteX,
+		description: "Shift left one bit in memory, then OR accumulator with memory.",
+		cycle:       7,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x1B: {
+		code:        0x1B,
+		name:        "SLO", // ASL + ORA
+		mode:        absoluteY,
+		description: "Shift left one bit in memory, then OR accumulator with memory.",
+		cycle:       7,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x03: {
+		code:        0x03,
+		name:        "SLO", // ASL + ORA
+		mode:        indirectX,
+		description: "Shift left one bit in memory, then OR accumulator with memory.",
+		cycle:       8,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x13: {
+		code:        0x13,
+		name:        "SLO", // ASL + ORA
+		mode:        indirectY,
+		description: "Shift left one bit in memory, then OR accumulator with memory.",
+		cycle:       8,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x27: {
+		code:        0x27,
+		name:        "RLA", // ROL + AND
+		mode:        zeroPage,
+		description: "Rotate one bit left in memory, then AND accumulator with memory.",
+		cycle:       5,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x37: {
+		code:        0x37,
+		name:        "RLA", // ROL + AND
+		mode:        zeroPageX,
+		description: "Rotate one bit left in memory, then AND accumulator with memory.",
+		cycle:       6,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x2F: {
+		code:        0x2F,
+		name:        "RLA", // ROL + AND
+		mode:        absolute,
+		description: "Rotate one bit left in memory, then AND accumulator with memory.",
+		cycle:       6,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x3F: {
+		code:        0x3F,
+		name:        "RLA", // ROL + AND
+		mode:        absoluteX,
+		description: "Rotate one bit left in memory, then AND accumulator with memory.",
+		cycle:       7,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x3B: {
+		code:        0x3B,
+		name:        "RLA", // ROL + AND
+		mode:        absoluteY,
+		description: "Rotate one bit left in memory, then AND accumulator with memory.",
+		cycle:       7,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x23: {
+		code:        0x23,
+		name:        "RLA", // ROL + AND
+		mode:        indirectX,
+		description: "Rotate one bit left in memory, then AND accumulator with memory.",
+		cycle:       8,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x33: {
+		code:        0x33,
+		name:        "RLA", // ROL + AND
+		mode:        indirectY,
+		description: "Rotate one bit left in memory, then AND accumulator with memory.",
+		cycle:       8,
+		unofficial:  true,
+		// C: Set to contents of old bit 7
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x47: {
+		code:        0x47,
+		name:        "SRE", // LSR + EOR
+		mode:        zeroPage,
+		description: "Shift right one bit in memory, then EOR accumulator with memory.",
+		cycle:       5,
+		unofficial:  true,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x57: {
+		code:        0x57,
+		name:        "SRE", // LSR + EOR
+		mode:        zeroPageX,
+		description: "Shift right one bit in memory, then EOR accumulator with memory.",
+		cycle:       6,
+		unofficial:  true,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x4F: {
+		code:        0x4F,
+		name:        "SRE", // LSR + EOR
+		mode:        absolute,
+		description: "Shift right one bit in memory, then EOR accumulator with memory.",
+		cycle:       6,
+		unofficial:  true,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x5F: {
+		code:        0x5F,
+		name:        "SRE", // LSR + EOR
+		mode:        absoluteX,
+		description: "Shift right one bit in memory, then EOR accumulator with memory.",
+		cycle:       7,
+		unofficial:  true,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x5B: {
+		code:        0x5B,
+		name:        "SRE", // LSR + EOR
+		mode:        absoluteY,
+		description: "Shift right one bit in memory, then EOR accumulator with memory.",
+		cycle:       7,
+		unofficial:  true,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x43: {
+		code:        0x43,
+		name:        "SRE", // LSR + EOR
+		mode:        indirectX,
+		description: "Shift right one bit in memory, then EOR accumulator with memory.",
+		cycle:       8,
+		unofficial:  true,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x53: {
+		code:        0x53,
+		name:        "SRE", // LSR + EOR
+		mode:        indirectY,
+		description: "Shift right one bit in memory, then EOR accumulator with memory.",
+		cycle:       8,
+		unofficial:  true,
+		// C: Set to contents of old bit 0
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x67: {
+		code:        0x67,
+		name:        "RRA", // ROR + ADC
+		mode:        zeroPage,
+		description: "Rotate one bit right in memory, then add memory to accumulator (with carry).",
+		cycle:       5,
+		unofficial:  true,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x77: {
+		code:        0x77,
+		name:        "RRA", // ROR + ADC
+		mode:        zeroPageX,
+		description: "Rotate one bit right in memory, then add memory to accumulator (with carry).",
+		cycle:       6,
+		unofficial:  true,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x6F: {
+		code:        0x6F,
+		name:        "RRA", // ROR + ADC
+		mode:        absolute,
+		description: "Rotate one bit right in memory, then add memory to accumulator (with carry).",
+		cycle:       6,
+		unofficial:  true,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x7F: {
+		code:        0x7F,
+		name:        "RRA", // ROR + ADC
+		mode:        absoluteX,
+		description: "Rotate one bit right in memory, then add memory to accumulator (with carry).",
+		cycle:       7,
+		unofficial:  true,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x7B: {
+		code:        0x7B,
+		name:        "RRA", // ROR + ADC
+		mode:        absoluteY,
+		description: "Rotate one bit right in memory, then add memory to accumulator (with carry).",
+		cycle:       7,
+		unofficial:  true,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0x63: {
+		code:        0x63,
+		name:        "RRA", // ROR + ADC
+		mode:        indirectX,
+		description: "Rotate one bit right in memory, then add memory to accumulator (with carry).",
+		cycle:       8,
+		unofficial:  true,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x73: {
+		code:        0x73,
+		name:        "RRA", // ROR + ADC
+		mode:        indirectY,
+		description: "Rotate one bit right in memory, then add memory to accumulator (with carry).",
+		cycle:       8,
+		unofficial:  true,
+		// C: Set if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x87: {
+		code:        0x87,
+		name:        "SAX", // Store A AND X
+		mode:        zeroPage,
+		description: "AND X register with accumulator and store result in memory.",
+		cycle:       3,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x97: {
+		code:        0x97,
+		name:        "SAX", // Store A AND X
+		mode:        zeroPageY,
+		description: "AND X register with accumulator and store result in memory.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x8F: {
+		code:        0x8F,
+		name:        "SAX", // Store A AND X
+		mode:        absolute,
+		description: "AND X register with accumulator and store result in memory.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0x83: {
+		code:        0x83,
+		name:        "SAX", // Store A AND X
+		mode:        indirectX,
+		description: "AND X register with accumulator and store result in memory.",
+		cycle:       6,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0xA7: {
+		code:        0xA7,
+		name:        "LAX", // LDA + LDX
+		mode:        zeroPage,
+		description: "Load accumulator and X register with memory.",
+		cycle:       3,
+		unofficial:  true,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xB7: {
+		code:        0xB7,
+		name:        "LAX", // LDA + LDX
+		mode:        zeroPageY,
+		description: "Load accumulator and X register with memory.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xAF: {
+		code:        0xAF,
+		name:        "LAX", // LDA + LDX
+		mode:        absolute,
+		description: "Load accumulator and X register with memory.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0xBF: {
+		code:        0xBF,
+		name:        "LAX", // LDA + LDX
+		mode:        absoluteY,
+		description: "Load accumulator and X register with memory.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		unofficial:  true,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0xA3: {
+		code:        0xA3,
+		name:        "LAX", // LDA + LDX
+		mode:        indirectX,
+		description: "Load accumulator and X register with memory.",
+		cycle:       6,
+		unofficial:  true,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xB3: {
+		code:        0xB3,
+		name:        "LAX", // LDA + LDX
+		mode:        indirectY,
+		description: "Load accumulator and X register with memory.",
+		cycle:       5,
+		pageCycle:   true, // +1 if page crossed
+		unofficial:  true,
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xC7: {
+		code:        0xC7,
+		name:        "DCP", // DEC + CMP
+		mode:        zeroPage,
+		description: "Subtract 1 from memory (without borrow), then compare the result with the accumulator.",
+		cycle:       5,
+		unofficial:  true,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xD7: {
+		code:        0xD7,
+		name:        "DCP", // DEC + CMP
+		mode:        zeroPageX,
+		description: "Subtract 1 from memory (without borrow), then compare the result with the accumulator.",
+		cycle:       6,
+		unofficial:  true,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xCF: {
+		code:        0xCF,
+		name:        "DCP", // DEC + CMP
+		mode:        absolute,
+		description: "Subtract 1 from memory (without borrow), then compare the result with the accumulator.",
+		cycle:       6,
+		unofficial:  true,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xDF: {
+		code:        0xDF,
+		name:        "DCP", // DEC + CMP
+		mode:        absoluteX,
+		description: "Subtract 1 from memory (without borrow), then compare the result with the accumulator.",
+		cycle:       7,
+		unofficial:  true,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xDB: {
+		code:        0xDB,
+		name:        "DCP", // DEC + CMP
+		mode:        absoluteY,
+		description: "Subtract 1 from memory (without borrow), then compare the result with the accumulator.",
+		cycle:       7,
+		unofficial:  true,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:3
+	},
+	0xC3: {
+		code:        0xC3,
+		name:        "DCP", // DEC + CMP
+		mode:        indirectX,
+		description: "Subtract 1 from memory (without borrow), then compare the result with the accumulator.",
+		cycle:       8,
+		unofficial:  true,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xD3: {
+		code:        0xD3,
+		name:        "DCP", // DEC + CMP
+		mode:        indirectY,
+		description: "Subtract 1 from memory (without borrow), then compare the result with the accumulator.",
+		cycle:       8,
+		unofficial:  true,
+		// C: Set if A >= M
+		// Z: Set if A = M
+		// N: Set if bit 7 of the result is set
+		// bytes:2
+	},
+	0xE7: {
+		code:        0xE7,
+		name:        "ISB", // INC + SBC
+		mode:        zeroPage,
+		description: "Increase memory by one, then subtract memory from accumulator (with borrow).",
+		cycle:       5,
+		unofficial:  true,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xF7: {
+		code:        0xF7,
+		name:        "ISB", // INC + SBC
+		mode:        zeroPageX,
+		description: "Increase memory by one, then subtract memory from accumulator (with borrow).",
+		cycle:       6,
+		unofficial:  true,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xEF: {
+		code:        0xEF,
+		name:        "ISB", // INC + SBC
+		mode:        absolute,
+		description: "Increase memory by one, then subtract memory from accumulator (with borrow).",
+		cycle:       6,
+		unofficial:  true,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0xFF: {
+		code:        0xFF,
+		name:        "ISB", // INC + SBC
+		mode:        absoluteX,
+		description: "Increase memory by one, then subtract memory from accumulator (with borrow).",
+		cycle:       7,
+		unofficial:  true,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0xFB: {
+		code:        0xFB,
+		name:        "ISB", // INC + SBC
+		mode:        absoluteY,
+		description: "Increase memory by one, then subtract memory from accumulator (with borrow).",
+		cycle:       7,
+		unofficial:  true,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:3
+	},
+	0xE3: {
+		code:        0xE3,
+		name:        "ISB", // INC + SBC
+		mode:        indirectX,
+		description: "Increase memory by one, then subtract memory from accumulator (with borrow).",
+		cycle:       8,
+		unofficial:  true,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xF3: {
+		code:        0xF3,
+		name:        "ISB", // INC + SBC
+		mode:        indirectY,
+		description: "Increase memory by one, then subtract memory from accumulator (with borrow).",
+		cycle:       8,
+		unofficial:  true,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x0B: {
+		code:        0x0B,
+		name:        "ANC", // AND + set C
+		mode:        immediate,
+		description: "AND byte with accumulator. If result is negative then carry is set.",
+		cycle:       2,
+		unofficial:  true,
+		// C: Set to bit 7 of the result
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x2B: {
+		code:        0x2B,
+		name:        "ANC", // AND + set C
+		mode:        immediate,
+		description: "AND byte with accumulator. If result is negative then carry is set.",
+		cycle:       2,
+		unofficial:  true,
+		// C: Set to bit 7 of the result
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x4B: {
+		code:        0x4B,
+		name:        "ALR", // AND + LSR
+		mode:        immediate,
+		description: "AND byte with accumulator, then shift right one bit in accumulator.",
+		cycle:       2,
+		unofficial:  true,
+		// C: Set to contents of bit 0 of the AND result
+		// Z: Set if A = 0
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0x6B: {
+		code:        0x6B,
+		name:        "ARR", // AND + ROR
+		mode:        immediate,
+		description: "AND byte with accumulator, then rotate one bit right in accumulator.",
+		cycle:       2,
+		unofficial:  true,
+		// C: Set to bit 6 of the result
+		// Z: Set if A = 0
+		// V: Set to bit 6 xor bit 5 of the result
+		// N: Set if bit 7 of A is set
+		// bytes:2
+	},
+	0xCB: {
+		code:        0xCB,
+		name:        "AXS", // (A AND X) - M to X
+		mode:        immediate,
+		description: "AND X register with accumulator and store result in X register, then subtract byte from X register (without borrow).",
+		cycle:       2,
+		unofficial:  true,
+		// C: Set if (A AND X) >= M
+		// Z: Set if X = 0
+		// N: Set if bit 7 of X is set
+		// bytes:2
+	},
+	0xEB: {
+		code:        0xEB,
+		name:        "SBC", // Subtract with Carry
+		mode:        immediate,
+		description: "Same as the official SBC #imm ($E9).",
+		cycle:       2,
+		unofficial:  true,
+		// C: Clear if overflow in bit 7
+		// Z: Set if A = 0
+		// V: Set if sign bit is incorrect
+		// N: Set if bit 7 set
+		// bytes:2
+	},
+	0x1A: {
+		code:        0x1A,
+		name:        "NOP", // No Operation
+		mode:        implied,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:1
+	},
+	0x3A: {
+		code:        0x3A,
+		name:        "NOP", // No Operation
+		mode:        implied,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:1
+	},
+	0x5A: {
+		code:        0x5A,
+		name:        "NOP", // No Operation
+		mode:        implied,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:1
+	},
+	0x7A: {
+		code:        0x7A,
+		name:        "NOP", // No Operation
+		mode:        implied,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:1
+	},
+	0xDA: {
+		code:        0xDA,
+		name:        "NOP", // No Operation
+		mode:        implied,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:1
+	},
+	0xFA: {
+		code:        0xFA,
+		name:        "NOP", // No Operation
+		mode:        implied,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:1
+	},
+	0x80: {
+		code:        0x80,
+		name:        "NOP", // No Operation
+		mode:        immediate,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x82: {
+		code:        0x82,
+		name:        "NOP", // No Operation
+		mode:        immediate,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x89: {
+		code:        0x89,
+		name:        "NOP", // No Operation
+		mode:        immediate,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0xC2: {
+		code:        0xC2,
+		name:        "NOP", // No Operation
+		mode:        immediate,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0xE2: {
+		code:        0xE2,
+		name:        "NOP", // No Operation
+		mode:        immediate,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       2,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x04: {
+		code:        0x04,
+		name:        "NOP", // No Operation
+		mode:        zeroPage,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       3,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x44: {
+		code:        0x44,
+		name:        "NOP", // No Operation
+		mode:        zeroPage,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       3,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x64: {
+		code:        0x64,
+		name:        "NOP", // No Operation
+		mode:        zeroPage,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       3,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x14: {
+		code:        0x14,
+		name:        "NOP", // No Operation
+		mode:        zeroPageX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x34: {
+		code:        0x34,
+		name:        "NOP", // No Operation
+		mode:        zeroPageX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x54: {
+		code:        0x54,
+		name:        "NOP", // No Operation
+		mode:        zeroPageX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x74: {
+		code:        0x74,
+		name:        "NOP", // No Operation
+		mode:        zeroPageX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0xD4: {
+		code:        0xD4,
+		name:        "NOP", // No Operation
+		mode:        zeroPageX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0xF4: {
+		code:        0xF4,
+		name:        "NOP", // No Operation
+		mode:        zeroPageX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:2
+	},
+	0x0C: {
+		code:        0x0C,
+		name:        "NOP", // No Operation
+		mode:        absolute,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0x1C: {
+		code:        0x1C,
+		name:        "NOP", // No Operation
+		mode:        absoluteX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0x3C: {
+		code:        0x3C,
+		name:        "NOP", // No Operation
+		mode:        absoluteX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0x5C: {
+		code:        0x5C,
+		name:        "NOP", // No Operation
+		mode:        absoluteX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0x7C: {
+		code:        0x7C,
+		name:        "NOP", // No Operation
+		mode:        absoluteX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0xDC: {
+		code:        0xDC,
+		name:        "NOP", // No Operation
+		mode:        absoluteX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
+	0xFC: {
+		code:        0xFC,
+		name:        "NOP", // No Operation
+		mode:        absoluteX,
+		description: "No operation. The operand is read and discarded.",
+		cycle:       4,
+		pageCycle:   true, // +1 if page crossed
+		unofficial:  true,
+		// Z: not affected
+		// N: not affected
+		// bytes:3
+	},
 }
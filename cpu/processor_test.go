@@ -0,0 +1,192 @@
+package cpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// processorTestsDir has SingleStepTests/ProcessorTests JSON files for NES 2A03 (nes6502/v1/xx.json).
+// https://github.com/SingleStepTests/ProcessorTests
+// Put them into testdata to run TestCPU_ProcessorTests.
+const processorTestsDir = "testdata/nes6502/v1"
+
+// compareBusActivity enables comparison of the bus access of each cycle.
+// ダミーリード・ダミーライトを含め、1cycleに1回のアクセスをハードウェアの順序で行う
+const compareBusActivity = true
+
+type processorTestState struct {
+	PC  uint16    `json:"pc"`
+	S   byte      `json:"s"`
+	A   byte      `json:"a"`
+	X   byte      `json:"x"`
+	Y   byte      `json:"y"`
+	P   byte      `json:"p"`
+	RAM [][2]uint `json:"ram"`
+}
+
+type processorTestCase struct {
+	Name    string             `json:"name"`
+	Initial processorTestState `json:"initial"`
+	Final   processorTestState `json:"final"`
+	Cycles  []busCycle         `json:"cycles"`
+}
+
+// busCycle is a bus access in 1 cycle. e.g. [59082, 177, "read"]
+type busCycle struct {
+	Address uint16
+	Data    byte
+	Kind    string // read or write
+}
+
+func (b *busCycle) UnmarshalJSON(data []byte) error {
+	var v [3]json.RawMessage
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(v[0], &b.Address); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(v[1], &b.Data); err != nil {
+		return err
+	}
+	return json.Unmarshal(v[2], &b.Kind)
+}
+
+// recordingMemory is flat 64KB RAM which records bus accesses.
+type recordingMemory struct {
+	RAM
+	cycles []busCycle
+}
+
+func (m *recordingMemory) Read(address uint16) byte {
+	data := m.RAM.Read(address)
+	m.cycles = append(m.cycles, busCycle{Address: address, Data: data, Kind: "read"})
+	return data
+}
+
+func (m *recordingMemory) Write(address uint16, data byte) {
+	m.RAM.Write(address, data)
+	m.cycles = append(m.cycles, busCycle{Address: address, Data: data, Kind: "write"})
+}
+
+// TestCPU_ProcessorTests runs each test case for 1 instruction
+// and compares registers, RAM and the number of cycles.
+func TestCPU_ProcessorTests(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(processorTestsDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Skipf("%s not found", processorTestsDir)
+	}
+
+	for _, file := range files {
+		file := file
+		code, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(file), ".json"), 16, 8)
+		if err != nil {
+			continue
+		}
+		inst := opecodes[byte(code)]
+		if inst == nil {
+			continue
+		}
+		t.Run(fmt.Sprintf("code=%#02x:%s", code, inst.name), func(t *testing.T) {
+			t.Parallel()
+			cases, err := loadProcessorTests(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, tc := range cases {
+				if err := runProcessorTest(tc); err != nil {
+					t.Fatalf("%s: %v", tc.Name, err)
+				}
+			}
+		})
+	}
+}
+
+func loadProcessorTests(file string) ([]*processorTestCase, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var cases []*processorTestCase
+	if err := json.Unmarshal(b, &cases); err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+func runProcessorTest(tc *processorTestCase) error {
+	mem := &recordingMemory{}
+	for _, r := range tc.Initial.RAM {
+		mem.RAM[r[0]] = byte(r[1])
+	}
+
+	cpu := NewCPU(mem)
+	cpu.register = &Register{
+		A:  tc.Initial.A,
+		X:  tc.Initial.X,
+		Y:  tc.Initial.Y,
+		S:  tc.Initial.S,
+		PC: tc.Initial.PC,
+		P:  tc.Initial.P,
+	}
+	cycle := cpu.Run()
+
+	want := &Register{
+		A:  tc.Final.A,
+		X:  tc.Final.X,
+		Y:  tc.Final.Y,
+		S:  tc.Final.S,
+		PC: tc.Final.PC,
+		P:  tc.Final.P,
+	}
+	if diff := cmp.Diff(want, cpu.register); diff != "" {
+		return fmt.Errorf("register mismatch (-want +got):\n%s", diff)
+	}
+	for _, r := range tc.Final.RAM {
+		if want, got := byte(r[1]), mem.RAM[r[0]]; want != got {
+			return fmt.Errorf("RAM[%#04x]: want=%#02x, got=%#02x", r[0], want, got)
+		}
+	}
+	if want, got := len(tc.Cycles), cycle; want != got {
+		return fmt.Errorf("cycle: want=%v, got=%v", want, got)
+	}
+	if compareBusActivity {
+		if diff := cmp.Diff(tc.Cycles, mem.cycles); diff != "" {
+			return fmt.Errorf("bus activity mismatch (-want +got):\n%s", diff)
+		}
+	}
+	return nil
+}
+
+func TestProcessorTestCase_UnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	data := `[{"name": "a9 10 00", "initial": {"pc": 32768, "s": 253, "a": 0, "x": 0, "y": 0, "p": 36, "ram": [[32768, 169], [32769, 16]]},
+		"final": {"pc": 32770, "s": 253, "a": 16, "x": 0, "y": 0, "p": 36, "ram": [[32768, 169], [32769, 16]]},
+		"cycles": [[32768, 169, "read"], [32769, 16, "read"]]}]`
+	var cases []*processorTestCase
+	if err := json.Unmarshal([]byte(data), &cases); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(cases); want != got {
+		t.Fatalf("len: want=%v, got=%v", want, got)
+	}
+	if want, got := []busCycle{{0x8000, 0xA9, "read"}, {0x8001, 0x10, "read"}}, cases[0].Cycles; !cmp.Equal(want, got) {
+		t.Errorf("cycles mismatch (-want +got):\n%s", cmp.Diff(want, got))
+	}
+
+	// 読み込んだケースを実際に実行できること
+	if err := runProcessorTest(cases[0]); err != nil {
+		t.Error(err)
+	}
+}
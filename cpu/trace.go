@@ -0,0 +1,241 @@
+package cpu
+
+import (
+	"fmt"
+	"io"
+)
+
+// Tracer receives the CPU state before every executed instruction.
+type Tracer interface {
+	Trace(s *TraceState)
+}
+
+// PPUPositioner is implemented by Memory which can tell the current PPU position.
+// The position is written to trace lines.
+type PPUPositioner interface {
+	PPUPosition() (scanline, dot int)
+}
+
+// Banker is implemented by Memory which maps CPU addresses to PRG banks.
+type Banker interface {
+	// Bank returns the PRG bank mapped at address, or -1 if address is not PRG.
+	Bank(address uint16) int
+}
+
+// TraceState is a snapshot of CPU taken just before an instruction executes.
+type TraceState struct {
+	PC          uint16
+	Bank        int // PCのPRGバンク. 不明な場合は-1
+	Instruction Instruction
+
+	A, X, Y, P, S byte
+
+	Scanline, Dot int
+	Cycles        uint64 // 命令実行前の累計cycle数
+	Interrupts    uint64 // 命令実行前までに割り込みハンドラへ入った回数. CPU.Interruptsを参照
+}
+
+// WithTracer sets a tracer called before every instruction. Tracing is off by default.
+func WithTracer(t Tracer) Option {
+	return func(c *CPU) {
+		c.tracer = t
+	}
+}
+
+// SetTracer replaces the tracer. nil turns tracing off.
+func (c *CPU) SetTracer(t Tracer) {
+	c.tracer = t
+}
+
+// Tracer returns the tracer set by WithTracer or SetTracer, or nil.
+func (c *CPU) Tracer() Tracer {
+	return c.tracer
+}
+
+// MultiTracer returns a Tracer calling all tracers in order.
+func MultiTracer(tracers ...Tracer) Tracer {
+	return multiTracer(append([]Tracer(nil), tracers...))
+}
+
+type multiTracer []Tracer
+
+func (m multiTracer) Trace(s *TraceState) {
+	for _, t := range m {
+		t.Trace(s)
+	}
+}
+
+// traceState builds TraceState of the instruction at pc without side effects.
+func (c *CPU) traceState(pc uint16) *TraceState {
+	s := &TraceState{
+		PC:          pc,
+		Bank:        c.Bank(pc),
+		Instruction: c.Disassemble(pc),
+		A:           c.register.A,
+		X:           c.register.X,
+		Y:           c.register.Y,
+		P:           c.register.P,
+		S:           c.register.S,
+		Cycles:      c.cycles,
+		Interrupts:  c.interrupts,
+	}
+	if p, ok := c.bus.(PPUPositioner); ok {
+		s.Scanline, s.Dot = p.PPUPosition()
+	}
+	return s
+}
+
+// TraceFormat is a line format of TraceLogger.
+type TraceFormat int
+
+const (
+	// TraceNestest is the format of nestest.log.
+	//	C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7
+	TraceNestest TraceFormat = iota
+	// TraceMesen is similar to the default format of Mesen trace logger.
+	//	C000  JMP $C5F5                      A:00 X:00 Y:00 S:FD P:nvUbdIzc V:0   H:21  Cycle:7
+	TraceMesen
+)
+
+// TraceFilter reports whether s should be traced.
+type TraceFilter func(s *TraceState) bool
+
+// PCRange matches instructions whose address is in [from, to].
+func PCRange(from, to uint16) TraceFilter {
+	return func(s *TraceState) bool {
+		return from <= s.PC && s.PC <= to
+	}
+}
+
+// InBank matches instructions in one of banks.
+func InBank(banks ...int) TraceFilter {
+	return func(s *TraceState) bool {
+		for _, b := range banks {
+			if s.Bank == b {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// TraceOption configures TraceLogger.
+type TraceOption func(*TraceLogger)
+
+// WithTraceFormat sets the line format. The default is TraceNestest.
+func WithTraceFormat(f TraceFormat) TraceOption {
+	return func(l *TraceLogger) {
+		l.format = f
+	}
+}
+
+// WithTraceFilter adds a filter. A line is written only if all filters match.
+func WithTraceFilter(f TraceFilter) TraceOption {
+	return func(l *TraceLogger) {
+		l.filters = append(l.filters, f)
+	}
+}
+
+// WithTraceStart makes TraceLogger wait until start matches.
+// The matched instruction is the first line.
+func WithTraceStart(start TraceFilter) TraceOption {
+	return func(l *TraceLogger) {
+		l.start = start
+		l.active = false
+	}
+}
+
+// WithTraceStop makes TraceLogger stop after an instruction matching stop.
+// The matched instruction is the last line. Tracing resumes when start matches again.
+func WithTraceStop(stop TraceFilter) TraceOption {
+	return func(l *TraceLogger) {
+		l.stop = stop
+	}
+}
+
+// WithTraceLabels replaces operand addresses with labels, e.g. "JSR update_player".
+func WithTraceLabels(label func(address uint16) (string, bool)) TraceOption {
+	return func(l *TraceLogger) {
+		l.label = label
+	}
+}
+
+// TraceLogger is a Tracer writing a line per instruction to io.Writer.
+type TraceLogger struct {
+	w           io.Writer
+	format      TraceFormat
+	filters     []TraceFilter
+	start, stop TraceFilter
+	active      bool
+	label       func(address uint16) (string, bool)
+	err         error
+}
+
+func NewTraceLogger(w io.Writer, opts ...TraceOption) *TraceLogger {
+	l := &TraceLogger{
+		w:      w,
+		active: true,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Trace implements Tracer.
+func (l *TraceLogger) Trace(s *TraceState) {
+	if l.err != nil {
+		return
+	}
+	if !l.active {
+		if l.start == nil || !l.start(s) {
+			return
+		}
+		l.active = true
+	}
+	if l.stop != nil && l.stop(s) {
+		l.active = false
+	}
+	for _, f := range l.filters {
+		if !f(s) {
+			return
+		}
+	}
+	_, l.err = io.WriteString(l.w, l.line(s))
+}
+
+// Err returns the first write error. TraceLogger stops writing after an error.
+func (l *TraceLogger) Err() error {
+	return l.err
+}
+
+func (l *TraceLogger) line(s *TraceState) string {
+	inst := s.Instruction
+	if l.label != nil {
+		inst = inst.Symbolize(l.label)
+	}
+	switch l.format {
+	case TraceMesen:
+		return fmt.Sprintf("%04X  %-30s A:%02X X:%02X Y:%02X S:%02X P:%s V:%-3d H:%-3d Cycle:%d\n",
+			s.PC, inst, s.A, s.X, s.Y, s.S, flagString(s.P), s.Scanline, s.Dot, s.Cycles)
+	}
+	// 非公式命令はニーモニックの前に*を付ける
+	mark := " "
+	if inst.Unofficial {
+		mark = "*"
+	}
+	return fmt.Sprintf("%04X  %-8s %s%-32sA:%02X X:%02X Y:%02X P:%02X SP:%02X PPU:%3d,%3d CYC:%d\n",
+		s.PC, inst.FormatBytes(), mark, inst, s.A, s.X, s.Y, s.P, s.S, s.Scanline, s.Dot, s.Cycles)
+}
+
+// flagString formats P as "NVUBDIZC". Set flags are upper case and clear flags are lower case.
+func flagString(p byte) string {
+	const names = "nvubdizc"
+	b := []byte(names)
+	for i := range b {
+		if p&(0x80>>i) != 0 {
+			b[i] -= 'a' - 'A'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,213 @@
+package cpu
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTraceLogger_format(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name    string
+		format  TraceFormat
+		address uint16
+		program []byte
+		init    func(cpu *CPU)
+		want    string
+	}{
+		{
+			name:    "nestest:JMP",
+			format:  TraceNestest,
+			address: 0xC000,
+			program: []byte{0x4C, 0xF5, 0xC5},
+			want:    "C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0,  0 CYC:7\n",
+		},
+		{
+			name:    "nestest:unofficial",
+			format:  TraceNestest,
+			address: 0xC6BD,
+			program: []byte{0x04, 0xA9},
+			want:    "C6BD  04 A9    *NOP $A9 = 00                    A:00 X:00 Y:00 P:24 SP:FD PPU:  0,  0 CYC:7\n",
+		},
+		{
+			name:    "nestest:implied",
+			format:  TraceNestest,
+			address: 0xC000,
+			program: []byte{0xE8},
+			init: func(cpu *CPU) {
+				cpu.register.X = 0x7F
+			},
+			want: "C000  E8        INX                             A:00 X:7F Y:00 P:24 SP:FD PPU:  0,  0 CYC:7\n",
+		},
+		{
+			name:    "nestest:relative",
+			format:  TraceNestest,
+			address: 0x800B,
+			program: []byte{0xD0, 0xF5},
+			want:    "800B  D0 F5     BNE $8002                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0,  0 CYC:7\n",
+		},
+		{
+			name:    "mesen:LDA",
+			format:  TraceMesen,
+			address: 0x8000,
+			program: []byte{0xA9, 0x10},
+			init: func(cpu *CPU) {
+				cpu.register.P = 0b1010_0101
+			},
+			want: "8000  LDA #$10                       A:00 X:00 Y:00 S:FD P:NvUbdIzC V:0   H:0   Cycle:7\n",
+		},
+		{
+			name:    "mesen:indirectY",
+			format:  TraceMesen,
+			address: 0x8000,
+			program: []byte{0xB1, 0x33},
+			want:    "8000  LDA ($33),Y = 0000 @ 0000 = 00 A:00 X:00 Y:00 S:FD P:nvUbdIzc V:0   H:0   Cycle:7\n",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			mem := &RAM{}
+			mem.Load(tt.address, tt.program)
+			cpu := NewCPU(mem, WithTracer(NewTraceLogger(&buf, WithTraceFormat(tt.format))))
+			cpu.PowerOn()
+			cpu.register.PC = tt.address
+			if tt.init != nil {
+				tt.init(cpu)
+			}
+			cpu.Run()
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("trace line mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestTraceLogger_nestest checks that a trace line can be read as nestest.log.
+func TestTraceLogger_nestest(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	mem := &RAM{}
+	mem.Load(0xC000, []byte{0x4C, 0xF5, 0xC5})
+	cpu := NewCPU(mem, WithTracer(NewTraceLogger(&buf)))
+	cpu.PowerOn()
+	cpu.register.PC = 0xC000
+	want := cpu.nestestState(3)
+	cpu.Run()
+
+	got, err := parseNestestLine(strings.TrimSuffix(buf.String(), "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("nestest state mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTraceLogger_filter(t *testing.T) {
+	t.Parallel()
+	pcIs := func(pc uint16) TraceFilter {
+		return func(s *TraceState) bool {
+			return s.PC == pc
+		}
+	}
+	for _, tt := range []struct {
+		name string
+		opts []TraceOption
+		want []string
+	}{
+		{
+			name: "no filter",
+			want: []string{"8000", "8001", "8002", "8003", "8004", "8005"},
+		},
+		{
+			name: "PCRange",
+			opts: []TraceOption{WithTraceFilter(PCRange(0x8001, 0x8002))},
+			want: []string{"8001", "8002"},
+		},
+		{
+			name: "InBank",
+			opts: []TraceOption{WithTraceFilter(InBank(0))},
+			want: nil, // RAMはバンクを持たない
+		},
+		{
+			name: "start/stop",
+			opts: []TraceOption{WithTraceStart(pcIs(0x8001)), WithTraceStop(pcIs(0x8003))},
+			want: []string{"8001", "8002", "8003"},
+		},
+		{
+			name: "stop only",
+			opts: []TraceOption{WithTraceStop(pcIs(0x8001))},
+			want: []string{"8000", "8001"},
+		},
+		{
+			name: "start and filter",
+			opts: []TraceOption{WithTraceStart(pcIs(0x8002)), WithTraceFilter(PCRange(0x8000, 0x8003))},
+			want: []string{"8002", "8003"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			mem := &RAM{}
+			mem.Load(0x8000, []byte{0xEA, 0xEA, 0xEA, 0xEA, 0xEA, 0xEA})
+			cpu := NewCPU(mem, WithTracer(NewTraceLogger(&buf, tt.opts...)))
+			cpu.register.PC = 0x8000
+			for i := 0; i < 6; i++ {
+				cpu.Run()
+			}
+			var got []string
+			for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+				if line != "" {
+					got = append(got, line[:4])
+				}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("traced PC mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTraceLogger_labels(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	mem := &RAM{}
+	mem.Load(0xC000, []byte{0x4C, 0xF5, 0xC5})
+	label := func(address uint16) (string, bool) {
+		return "main_loop", address == 0xC5F5
+	}
+	cpu := NewCPU(mem, WithTracer(NewTraceLogger(&buf, WithTraceLabels(label))))
+	cpu.PowerOn()
+	cpu.register.PC = 0xC000
+	cpu.Run()
+	want := "C000  4C F5 C5  JMP main_loop                   A:00 X:00 Y:00 P:24 SP:FD PPU:  0,  0 CYC:7\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("trace line mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMultiTracer(t *testing.T) {
+	t.Parallel()
+	var a, b bytes.Buffer
+	mem := &RAM{}
+	mem.Load(0x8000, []byte{0xEA, 0xEA})
+	cpu := NewCPU(mem, WithTracer(MultiTracer(
+		NewTraceLogger(&a),
+		NewTraceLogger(&b, WithTraceFilter(PCRange(0x8001, 0x8001))),
+	)))
+	cpu.register.PC = 0x8000
+	cpu.Run()
+	cpu.Run()
+	if want, got := 2, strings.Count(a.String(), "\n"); want != got {
+		t.Errorf("first tracer lines: want=%d, got=%d", want, got)
+	}
+	if want, got := "8001", b.String()[:4]; want != got {
+		t.Errorf("second tracer: want=%s, got=%s", want, got)
+	}
+}
@@ -0,0 +1,585 @@
+// Package dap implements a Debug Adapter Protocol server to debug ROMs in editors such as VS Code.
+//
+//	$ ./bin/gones dap
+//
+// The server speaks DAP on stdin and stdout. Source breakpoints and stack frames use
+// the debug info written by `ld65 --dbgfile`.
+package dap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yusukemisa/gones/bus"
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/debugger"
+	"github.com/yusukemisa/gones/expr"
+	"github.com/yusukemisa/gones/ppu"
+	"github.com/yusukemisa/gones/rom"
+	"github.com/yusukemisa/gones/symbols"
+)
+
+const threadID = 1
+
+// variablesReferenceの値
+const (
+	registersRef = iota + 1
+	flagsRef
+	zeroPageRef
+	stackRef
+	ppuRef
+)
+
+// Server is a DAP session.
+type Server struct {
+	w   io.Writer
+	seq int
+
+	d       *debugger.Debugger
+	symbols *symbols.Table
+	dbgDir  string // 相対パスのソースファイルの基準
+
+	stopOnEntry bool
+	breakpoints map[string][]int // ソースファイルごとのデバッガのブレークポイントID
+	dapIDs      map[int]int      // デバッガのID -> DAPのブレークポイントID
+	nextID      int
+
+	running bool
+	stopped chan debugger.Stop
+}
+
+// Serve serves a session on r and w until the client disconnects.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &Server{
+		w:           w,
+		symbols:     symbols.New(),
+		breakpoints: map[string][]int{},
+		dapIDs:      map[int]int{},
+		nextID:      1,
+		stopped:     make(chan debugger.Stop),
+	}
+
+	requests := make(chan *request)
+	errc := make(chan error, 1)
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			req, err := readRequest(br)
+			if err != nil {
+				errc <- err
+				close(requests)
+				return
+			}
+			requests <- req
+		}
+	}()
+
+	for {
+		select {
+		case req, ok := <-requests:
+			if !ok {
+				s.interrupt()
+				if err := <-errc; err != io.EOF {
+					return err
+				}
+				return nil
+			}
+			quit, err := s.handle(req)
+			if err != nil {
+				return err
+			}
+			if quit {
+				return nil
+			}
+		case stop := <-s.stopped:
+			s.running = false
+			if err := s.sendStopped(stop, ""); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// interrupt stops running CPU and waits for it.
+func (s *Server) interrupt() {
+	if s.running {
+		s.d.Interrupt()
+		<-s.stopped
+		s.running = false
+	}
+}
+
+func (s *Server) send(v interface{}) error {
+	s.seq++
+	switch m := v.(type) {
+	case *response:
+		m.Seq, m.Type = s.seq, "response"
+	case *event:
+		m.Seq, m.Type = s.seq, "event"
+	}
+	return writeMessage(s.w, v)
+}
+
+func (s *Server) respond(req *request, body interface{}) error {
+	return s.send(&response{RequestSeq: req.Seq, Success: true, Command: req.Command, Body: body})
+}
+
+func (s *Server) respondError(req *request, err error) error {
+	return s.send(&response{RequestSeq: req.Seq, Command: req.Command, Message: err.Error()})
+}
+
+func (s *Server) sendEvent(name string, body interface{}) error {
+	return s.send(&event{Event: name, Body: body})
+}
+
+// errRunning is the error for requests which need the CPU stopped.
+var errRunning = errors.New("the program is running")
+
+// handle handles a request. It reports true when the session ends.
+func (s *Server) handle(req *request) (bool, error) {
+	switch req.Command {
+	case "initialize":
+		return false, s.respond(req, map[string]bool{
+			"supportsConfigurationDoneRequest":  true,
+			"supportsConditionalBreakpoints":    true,
+			"supportsHitConditionalBreakpoints": true,
+			"supportsEvaluateForHovers":         true,
+			"supportsReadMemoryRequest":         true,
+			"supportsSteppingGranularity":       true,
+		})
+	case "disconnect", "terminate":
+		s.interrupt()
+		return true, s.respond(req, nil)
+	case "threads":
+		return false, s.respond(req, map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": threadID, "name": "CPU"}},
+		})
+	case "pause":
+		if s.running {
+			s.d.Interrupt()
+		}
+		return false, s.respond(req, nil)
+	}
+
+	if s.running {
+		return false, s.respondError(req, errRunning)
+	}
+	if s.d == nil && req.Command != "launch" {
+		return false, s.respondError(req, errors.New("not launched"))
+	}
+
+	var body interface{}
+	var err error
+	switch req.Command {
+	case "launch":
+		err = s.launch(req)
+		if err == nil {
+			// 起動後にブレークポイントの設定を受け付ける
+			if err := s.respond(req, nil); err != nil {
+				return false, err
+			}
+			return false, s.sendEvent("initialized", nil)
+		}
+	case "setBreakpoints":
+		body, err = s.setBreakpoints(req)
+	case "setExceptionBreakpoints":
+		body = map[string]interface{}{}
+	case "configurationDone":
+		if err := s.respond(req, nil); err != nil {
+			return false, err
+		}
+		if s.stopOnEntry {
+			return false, s.sendStopped(debugger.Stop{Reason: debugger.ReasonStep, PC: s.d.CPU().Register().PC}, "entry")
+		}
+		s.start(s.d.Continue)
+		return false, nil
+	case "stackTrace":
+		body = map[string]interface{}{"stackFrames": s.stackTrace()}
+	case "scopes":
+		body = map[string]interface{}{"scopes": s.scopes()}
+	case "variables":
+		var args struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		if err = json.Unmarshal(req.Arguments, &args); err == nil {
+			body = map[string]interface{}{"variables": s.variables(args.VariablesReference)}
+		}
+	case "evaluate":
+		body, err = s.evaluate(req)
+	case "readMemory":
+		body, err = s.readMemory(req)
+	case "continue":
+		s.start(s.d.Continue)
+		body = map[string]bool{"allThreadsContinued": true}
+	case "next", "stepIn", "stepOut":
+		var args stepArguments
+		if len(req.Arguments) > 0 {
+			if err := json.Unmarshal(req.Arguments, &args); err != nil {
+				return false, s.respondError(req, err)
+			}
+		}
+		s.start(s.stepFunc(req.Command, args.Granularity == "instruction"))
+	default:
+		err = fmt.Errorf("unsupported command: %s", req.Command)
+	}
+	if err != nil {
+		return false, s.respondError(req, err)
+	}
+	return false, s.respond(req, body)
+}
+
+// start runs f in another goroutine. The stop is received in Serve.
+func (s *Server) start(f func() debugger.Stop) {
+	s.running = true
+	go func() {
+		s.stopped <- f()
+	}()
+}
+
+func (s *Server) launch(req *request) error {
+	var args LaunchArguments
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return err
+	}
+	if s.d != nil {
+		return errors.New("already launched")
+	}
+	f, err := os.Open(args.Program)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := rom.NewRom(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args.Program, err)
+	}
+	dbgFile := args.DebugFile
+	if dbgFile == "" {
+		if path := strings.TrimSuffix(args.Program, filepath.Ext(args.Program)) + ".dbg"; fileExists(path) {
+			dbgFile = path
+		}
+	}
+	if dbgFile != "" {
+		// .nlや.mlbではソース行は使えないがスタックフレームにラベルが付く
+		if err := s.symbols.Load(dbgFile, len(r.PRG)); err != nil {
+			return err
+		}
+		s.dbgDir = filepath.Dir(dbgFile)
+	}
+
+	p, err := ppu.NewPPU(r.CHR, args.Headless)
+	if err != nil {
+		return err
+	}
+	b := bus.NewBus(r, p)
+	c := cpu.NewCPU(b)
+	c.PowerOn()
+	opts := []debugger.Option{debugger.WithIgnoredWrites(b.IgnoredWrite)}
+	if !args.Headless {
+		opts = append(opts, debugger.WithFrameHook(func() {
+			p.Canvas.Renderer.Present()
+			p.Canvas.Renderer.Clear()
+		}))
+	}
+	s.d = debugger.New(c, p, opts...)
+	s.stopOnEntry = args.StopOnEntry
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// setBreakpoints replaces breakpoints of a source file. A line may have code in several banks.
+func (s *Server) setBreakpoints(req *request) (interface{}, error) {
+	var args setBreakpointsArguments
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return nil, err
+	}
+	path := args.Source.Path
+	for _, id := range s.breakpoints[path] {
+		s.d.Delete(id)
+		delete(s.dapIDs, id)
+	}
+	delete(s.breakpoints, path)
+
+	bps := make([]breakpoint, 0, len(args.Breakpoints))
+	for _, sbp := range args.Breakpoints {
+		bp := breakpoint{Line: sbp.Line}
+		cond, err := compileCondition(sbp.Condition, sbp.HitCondition)
+		locs := s.symbols.Locations(path, sbp.Line)
+		switch {
+		case err != nil:
+			bp.Message = err.Error()
+		case len(locs) == 0:
+			bp.Message = "no code at this line"
+		default:
+			bp.ID, bp.Verified = s.nextID, true
+			s.nextID++
+			for _, loc := range locs {
+				id := s.d.AddBreakpoint(debugger.Breakpoint{Address: loc.Address, Bank: loc.Bank, Condition: cond})
+				s.breakpoints[path] = append(s.breakpoints[path], id)
+				s.dapIDs[id] = bp.ID
+			}
+		}
+		bps = append(bps, bp)
+	}
+	return map[string]interface{}{"breakpoints": bps}, nil
+}
+
+// compileCondition combines a condition and a hit condition such as "5" or ">= 5".
+func compileCondition(cond, hit string) (*expr.Expr, error) {
+	hit = strings.TrimSpace(hit)
+	if hit != "" {
+		if _, err := strconv.Atoi(hit); err == nil {
+			hit = "==" + hit
+		}
+		hit = "hits " + hit
+	}
+	switch {
+	case cond != "" && hit != "":
+		return expr.Compile("(" + cond + ") && " + hit)
+	case cond != "":
+		return expr.Compile(cond)
+	case hit != "":
+		return expr.Compile(hit)
+	}
+	return nil, nil
+}
+
+// stepFunc returns a function for next, stepIn and stepOut.
+// Line steps run until the source line changes. Without debug info for PC, it steps an instruction.
+func (s *Server) stepFunc(command string, instruction bool) func() debugger.Stop {
+	d := s.d
+	switch {
+	case command == "stepOut":
+		return d.StepOut
+	case instruction && command == "next":
+		return d.StepOver
+	case instruction:
+		return d.Step
+	}
+	start, ok := s.line(d.CPU().Register().PC)
+	if !ok {
+		if command == "next" {
+			return d.StepOver
+		}
+		return d.Step
+	}
+	depth := len(d.CallStack())
+	return func() debugger.Stop {
+		return d.RunUntil(func(cpu.Register) bool {
+			// nextではサブルーチンと割り込みの中で止まらない
+			if command == "next" && len(d.CallStack()) > depth {
+				return false
+			}
+			line, ok := s.line(d.CPU().Register().PC)
+			return !ok || line != start
+		})
+	}
+}
+
+func (s *Server) line(pc uint16) (symbols.Line, bool) {
+	return s.symbols.Line(symbols.Location{Bank: s.d.CPU().Bank(pc), Address: pc})
+}
+
+func (s *Server) sendStopped(stop debugger.Stop, reason string) error {
+	body := map[string]interface{}{"threadId": threadID, "allThreadsStopped": true}
+	if reason == "" {
+		switch stop.Reason {
+		case debugger.ReasonBreakpoint:
+			reason = "breakpoint"
+			if id, ok := s.dapIDs[stop.ID]; ok {
+				body["hitBreakpointIds"] = []int{id}
+			}
+		case debugger.ReasonWatchpoint:
+			reason = "data breakpoint"
+		case debugger.ReasonInterrupted:
+			reason = "pause"
+		case debugger.ReasonError:
+			reason = "exception"
+			body["text"] = stop.Err.Error()
+		default:
+			reason = "step"
+		}
+	}
+	body["reason"] = reason
+	return s.sendEvent("stopped", body)
+}
+
+// stackTrace returns frames innermost first. The frame ID is the depth.
+func (s *Server) stackTrace() []stackFrame {
+	calls := s.d.CallStack()
+	pc := s.d.CPU().Register().PC
+	frames := make([]stackFrame, 0, len(calls)+1)
+	for i := len(calls); i >= 0; i-- {
+		f := stackFrame{ID: len(frames), Name: fmt.Sprintf("$%04X", pc), InstructionPointerReference: fmt.Sprintf("0x%04X", pc)}
+		if i > 0 {
+			f.Name = s.label(calls[i-1].Entry)
+			if calls[i-1].Interrupt {
+				f.Name += " <interrupt>"
+			}
+		} else if name, ok := s.symbols.Label(s.location(pc)); ok {
+			f.Name = name
+		}
+		if line, ok := s.line(pc); ok {
+			path := line.File
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(s.dbgDir, path)
+			}
+			f.Source = &source{Name: filepath.Base(path), Path: path}
+			f.Line, f.Column = line.Line, 1
+		}
+		frames = append(frames, f)
+		if i > 0 {
+			pc = calls[i-1].Caller
+		}
+	}
+	return frames
+}
+
+func (s *Server) location(address uint16) symbols.Location {
+	return symbols.Location{Bank: s.d.CPU().Bank(address), Address: address}
+}
+
+// label returns the label at address or "$XXXX".
+func (s *Server) label(address uint16) string {
+	if name, ok := s.symbols.Label(s.location(address)); ok {
+		return name
+	}
+	return fmt.Sprintf("$%04X", address)
+}
+
+func (s *Server) scopes() []scope {
+	scopes := []scope{
+		{Name: "Registers", VariablesReference: registersRef},
+		{Name: "Zero Page", VariablesReference: zeroPageRef},
+		{Name: "Stack", VariablesReference: stackRef},
+	}
+	if s.d.PPU() != nil {
+		scopes = append(scopes, scope{Name: "PPU", VariablesReference: ppuRef})
+	}
+	return scopes
+}
+
+func (s *Server) variables(ref int) []variable {
+	reg := s.d.CPU().Register()
+	hex8 := func(name string, v byte) variable {
+		return variable{Name: name, Value: fmt.Sprintf("$%02X", v)}
+	}
+	switch ref {
+	case registersRef:
+		p := hex8("P", reg.P)
+		p.VariablesReference = flagsRef
+		return []variable{
+			hex8("A", reg.A), hex8("X", reg.X), hex8("Y", reg.Y), p, hex8("SP", reg.S),
+			{Name: "PC", Value: fmt.Sprintf("$%04X", reg.PC), MemoryReference: fmt.Sprintf("0x%04X", reg.PC)},
+			{Name: "cycles", Value: strconv.FormatUint(s.d.CPU().Cycles(), 10)},
+		}
+	case flagsRef:
+		var vars []variable
+		for i, name := range []string{"C", "Z", "I", "D", "B", "U", "V", "N"} {
+			vars = append(vars, variable{Name: name, Value: strconv.Itoa(int(reg.P >> i & 1))})
+		}
+		return vars
+	case zeroPageRef:
+		return s.memoryRows(0x0000)
+	case stackRef:
+		return s.memoryRows(0x0100)
+	case ppuRef:
+		st := s.d.PPU().State()
+		return []variable{
+			hex8("CTRL", st.CTRL), hex8("MASK", st.MASK), hex8("STATUS", st.STATUS),
+			{Name: "ADDR", Value: fmt.Sprintf("$%04X", st.Address)},
+			{Name: "scanline", Value: strconv.Itoa(st.Scanline)},
+			{Name: "dot", Value: strconv.Itoa(st.Dot)},
+			{Name: "frame", Value: strconv.FormatUint(st.Frame, 10)},
+		}
+	}
+	return []variable{}
+}
+
+// memoryRows returns a page as 16 rows of 16 bytes.
+func (s *Server) memoryRows(page uint16) []variable {
+	vars := make([]variable, 0, 16)
+	for row := page; row < page+0x100; row += 0x10 {
+		var b strings.Builder
+		for i := uint16(0); i < 0x10; i++ {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%02X", s.d.CPU().Peek(row+i))
+		}
+		vars = append(vars, variable{Name: fmt.Sprintf("$%04X", row), Value: b.String(), MemoryReference: fmt.Sprintf("0x%04X", row)})
+	}
+	return vars
+}
+
+// evaluate evaluates an expression of the expr package, e.g. "[$0300] + X".
+func (s *Server) evaluate(req *request) (interface{}, error) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return nil, err
+	}
+	e, err := expr.Compile(args.Expression)
+	if err != nil {
+		return nil, err
+	}
+	v, err := e.Eval(&expr.MachineEnv{CPU: s.d.CPU(), PPU: s.d.PPU()})
+	if err != nil {
+		return nil, err
+	}
+	result := strconv.Itoa(v)
+	if v >= 0 {
+		result = fmt.Sprintf("%d ($%X)", v, v)
+	}
+	return map[string]interface{}{"result": result, "variablesReference": 0}, nil
+}
+
+// readMemory reads CPU memory without side effects.
+func (s *Server) readMemory(req *request) (interface{}, error) {
+	var args struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Count           int    `json:"count"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return nil, err
+	}
+	if args.Count < 0 {
+		return nil, fmt.Errorf("invalid count: %d", args.Count)
+	}
+	base, err := strconv.ParseInt(args.MemoryReference, 0, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid memory reference: %s", args.MemoryReference)
+	}
+	start := int(base) + args.Offset
+	if start < 0 || 0x10000 <= start {
+		return map[string]interface{}{"address": fmt.Sprintf("0x%04X", start&0xFFFF), "unreadableBytes": args.Count}, nil
+	}
+	count := args.Count
+	if start+count > 0x10000 {
+		count = 0x10000 - start
+	}
+	data := make([]byte, count)
+	for i := range data {
+		data[i] = s.d.CPU().Peek(uint16(start + i))
+	}
+	return map[string]interface{}{
+		"address":         fmt.Sprintf("0x%04X", start),
+		"data":            base64.StdEncoding.EncodeToString(data),
+		"unreadableBytes": args.Count - count,
+	}, nil
+}
@@ -0,0 +1,287 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/yusukemisa/gones/asm"
+	"github.com/yusukemisa/gones/cpu"
+)
+
+// mainS is main.s assembled to 16KB PRG-ROM. Comments are line numbers.
+const mainS = `
+		.org $C000
+reset:	LDX #$00	; 3
+loop:	JSR sub		; 4
+		INX			; 5
+		JMP loop	; 6
+sub:	LDA #$01	; 7
+		RTS			; 8
+		.org $FFFA
+		.word reset, reset, reset
+`
+
+// mainDbg is the debug info of mainS like ld65 writes.
+const mainDbg = `version	major=2,minor=0
+file	id=0,name="src/main.s",size=200,mtime=0x6530E2C1,mod=0
+line	id=0,file=0,line=3,span=0
+line	id=1,file=0,line=4,span=1
+line	id=2,file=0,line=5,span=2
+line	id=3,file=0,line=6,span=3
+line	id=4,file=0,line=7,span=4
+line	id=5,file=0,line=8,span=5
+seg	id=0,name="CODE",start=0x00C000,size=0x000C,addrsize=absolute,type=ro,oname="main.nes",ooffs=16
+span	id=0,seg=0,start=0,size=2
+span	id=1,seg=0,start=2,size=3
+span	id=2,seg=0,start=5,size=1
+span	id=3,seg=0,start=6,size=3
+span	id=4,seg=0,start=9,size=2
+span	id=5,seg=0,start=11,size=1
+sym	id=0,name="reset",addrsize=absolute,scope=0,def=0,val=0xC000,seg=0,type=lab
+sym	id=1,name="loop",addrsize=absolute,scope=0,def=1,val=0xC002,seg=0,type=lab
+sym	id=2,name="sub",addrsize=absolute,scope=0,def=4,val=0xC009,seg=0,type=lab
+`
+
+type client struct {
+	t   *testing.T
+	w   io.Writer
+	r   *bufio.Reader
+	seq int
+}
+
+// startServer writes main.nes and main.dbg to a temporary directory and starts a session.
+func startServer(t *testing.T) (*client, string) {
+	t.Helper()
+	var set []asm.Opcode
+	for _, op := range cpu.Opcodes() {
+		set = append(set, asm.Opcode(op))
+	}
+	p, err := asm.New(set).Assemble(mainS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := p.INES(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.nes"), image, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.dbg"), []byte(mainDbg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(sr, sw)
+		sw.Close()
+	}()
+	t.Cleanup(func() {
+		cw.Close()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Serve: %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Error("Serve did not return")
+		}
+	})
+	return &client{t: t, w: cw, r: bufio.NewReader(cr)}, dir
+}
+
+func (c *client) send(command string, args interface{}) {
+	c.t.Helper()
+	c.seq++
+	if err := writeMessage(c.w, map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// message reads a message as a generic JSON object.
+func (c *client) message() map[string]interface{} {
+	c.t.Helper()
+	var length int
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		if line == "\r\n" {
+			break
+		}
+		var v string
+		fmt.Sscanf(line, "Content-Length: %s", &v)
+		length, _ = strconv.Atoi(v)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		c.t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(body, &m); err != nil {
+		c.t.Fatal(err)
+	}
+	return m
+}
+
+// request sends a request and returns the body of the successful response.
+func (c *client) request(command string, args interface{}) map[string]interface{} {
+	c.t.Helper()
+	c.send(command, args)
+	m := c.message()
+	if m["type"] != "response" || m["command"] != command || m["request_seq"] != float64(c.seq) {
+		c.t.Fatalf("unexpected message for %s: %v", command, m)
+	}
+	if m["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, m["message"])
+	}
+	body, _ := m["body"].(map[string]interface{})
+	return body
+}
+
+// requestError sends a request expected to fail and returns the error message.
+func (c *client) requestError(command string, args interface{}) string {
+	c.t.Helper()
+	c.send(command, args)
+	m := c.message()
+	if m["type"] != "response" || m["command"] != command || m["request_seq"] != float64(c.seq) {
+		c.t.Fatalf("unexpected message for %s: %v", command, m)
+	}
+	if m["success"] != false {
+		c.t.Fatalf("%s must fail: %v", command, m)
+	}
+	message, _ := m["message"].(string)
+	return message
+}
+
+// event reads an event and returns its body.
+func (c *client) event(name string) map[string]interface{} {
+	c.t.Helper()
+	m := c.message()
+	if m["type"] != "event" || m["event"] != name {
+		c.t.Fatalf("%s event expected: %v", name, m)
+	}
+	body, _ := m["body"].(map[string]interface{})
+	return body
+}
+
+// stackTrace returns "name file:line" of stack frames.
+func (c *client) stackTrace() []string {
+	c.t.Helper()
+	var frames []string
+	for _, f := range c.request("stackTrace", map[string]int{"threadId": threadID})["stackFrames"].([]interface{}) {
+		f := f.(map[string]interface{})
+		s := f["name"].(string)
+		if src, ok := f["source"].(map[string]interface{}); ok {
+			s += fmt.Sprintf(" %s:%v", src["name"], f["line"])
+		}
+		frames = append(frames, s)
+	}
+	return frames
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+	c, dir := startServer(t)
+
+	caps := c.request("initialize", map[string]string{"adapterID": "gones"})
+	if caps["supportsConfigurationDoneRequest"] != true {
+		t.Errorf("unexpected capabilities: %v", caps)
+	}
+	c.request("launch", LaunchArguments{Program: filepath.Join(dir, "main.nes"), StopOnEntry: true, Headless: true})
+	c.event("initialized")
+
+	bps := c.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": filepath.Join(dir, "src", "main.s")},
+		"breakpoints": []map[string]interface{}{{"line": 7, "hitCondition": "2"}, {"line": 1}, {"line": 5, "condition": "A =="}},
+	})["breakpoints"].([]interface{})
+	var verified []interface{}
+	for _, bp := range bps {
+		verified = append(verified, bp.(map[string]interface{})["verified"])
+	}
+	if diff := cmp.Diff([]interface{}{true, false, false}, verified); diff != "" {
+		t.Errorf("verified mismatch (-want +got):\n%s", diff)
+	}
+	c.request("setExceptionBreakpoints", map[string]interface{}{"filters": []string{}})
+	c.request("configurationDone", nil)
+	if want, got := "entry", c.event("stopped")["reason"]; want != got {
+		t.Errorf("reason: want=%v, got=%v", want, got)
+	}
+	if diff := cmp.Diff([]string{"reset main.s:3"}, c.stackTrace()); diff != "" {
+		t.Errorf("stack mismatch (-want +got):\n%s", diff)
+	}
+
+	// hitConditionにより2回目の到達で止まる
+	c.request("continue", map[string]int{"threadId": threadID})
+	stopped := c.event("stopped")
+	if diff := cmp.Diff(map[string]interface{}{
+		"reason": "breakpoint", "threadId": float64(threadID), "allThreadsStopped": true, "hitBreakpointIds": []interface{}{bps[0].(map[string]interface{})["id"]},
+	}, stopped); diff != "" {
+		t.Errorf("stopped mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"sub main.s:7", "loop main.s:4"}, c.stackTrace()); diff != "" {
+		t.Errorf("stack mismatch (-want +got):\n%s", diff)
+	}
+	if want, got := "1 ($1)", c.request("evaluate", map[string]string{"expression": "X"})["result"]; want != got {
+		t.Errorf("evaluate: want=%v, got=%v", want, got)
+	}
+
+	for _, tt := range []struct {
+		command string
+		want    []string
+	}{
+		{"next", []string{"sub main.s:8", "loop main.s:4"}},
+		{"stepOut", []string{"$C005 main.s:5"}},
+		{"next", []string{"$C006 main.s:6"}},
+		{"next", []string{"loop main.s:4"}},
+		{"stepIn", []string{"sub main.s:7", "loop main.s:4"}},
+	} {
+		c.request(tt.command, map[string]int{"threadId": threadID})
+		if want, got := "step", c.event("stopped")["reason"]; want != got {
+			t.Errorf("%s: reason: want=%v, got=%v", tt.command, want, got)
+		}
+		if diff := cmp.Diff(tt.want, c.stackTrace()); diff != "" {
+			t.Errorf("%s: location mismatch (-want +got):\n%s", tt.command, diff)
+		}
+	}
+
+	scopes := c.request("scopes", map[string]int{"frameId": 0})["scopes"].([]interface{})
+	if want, got := 4, len(scopes); want != got {
+		t.Errorf("scopes: want=%v, got=%v", want, got)
+	}
+	regs := c.request("variables", map[string]int{"variablesReference": registersRef})["variables"].([]interface{})
+	var got []string
+	for _, v := range regs[:5] {
+		v := v.(map[string]interface{})
+		got = append(got, fmt.Sprintf("%s=%s", v["name"], v["value"]))
+	}
+	if diff := cmp.Diff([]string{"A=$01", "X=$02", "Y=$00", "P=$24", "SP=$FB"}, got); diff != "" {
+		t.Errorf("registers mismatch (-want +got):\n%s", diff)
+	}
+	mem := c.request("readMemory", map[string]interface{}{"memoryReference": "0xC000", "offset": 2, "count": 3})
+	if want, got := "IAnA", mem["data"]; want != got { // 20 09 C0
+		t.Errorf("readMemory: want=%v, got=%v", want, got)
+	}
+	if want, got := "invalid count: -1", c.requestError("readMemory", map[string]interface{}{"memoryReference": "0xC000", "count": -1}); want != got {
+		t.Errorf("readMemory with negative count: want=%q, got=%q", want, got)
+	}
+
+	c.request("continue", map[string]int{"threadId": threadID})
+	c.request("pause", map[string]int{"threadId": threadID})
+	if want, got := "pause", c.event("stopped")["reason"]; want != got {
+		t.Errorf("reason: want=%v, got=%v", want, got)
+	}
+	c.request("disconnect", nil)
+}
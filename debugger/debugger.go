@@ -0,0 +1,471 @@
+// Package debugger pauses and steps the emulator with breakpoints and watchpoints.
+package debugger
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/expr"
+	"github.com/yusukemisa/gones/ppu"
+	"github.com/yusukemisa/gones/symbols"
+)
+
+// Space is an address space watched by Watchpoint.
+type Space int
+
+const (
+	CPUSpace Space = iota
+	PPUSpace
+)
+
+func (s Space) String() string {
+	if s == PPUSpace {
+		return "ppu"
+	}
+	return "cpu"
+}
+
+// AccessKind is a kind of memory access. Read and Write can be combined.
+type AccessKind int
+
+const (
+	Read AccessKind = 1 << iota
+	Write
+	ReadWrite = Read | Write
+)
+
+func (k AccessKind) String() string {
+	switch k {
+	case Read:
+		return "r"
+	case Write:
+		return "w"
+	}
+	return "rw"
+}
+
+// Breakpoint stops execution before the instruction at Address.
+type Breakpoint struct {
+	Address uint16
+	// Bank is the PRG bank of Address. -1 matches any bank.
+	Bank int
+	// Condition stops only if it is true. nil means always.
+	// `hits` is the number of times the breakpoint was reached.
+	Condition *expr.Expr
+}
+
+// Watchpoint stops execution after an instruction accessing [From, To] in Space.
+type Watchpoint struct {
+	Space    Space
+	From, To uint16
+	Kind     AccessKind
+	// Condition stops only if it is true. nil means always.
+	// `hits`, `address` and `value` of the access are available.
+	Condition *expr.Expr
+}
+
+// Access is a memory access which hit a watchpoint.
+type Access struct {
+	Space   Space
+	Address uint16
+	Data    byte
+	Write   bool
+}
+
+// Reason is why execution stopped.
+type Reason int
+
+const (
+	// ReasonStep means the requested step finished.
+	ReasonStep Reason = iota
+	ReasonBreakpoint
+	ReasonWatchpoint
+	// ReasonInterrupted means Interrupt was called.
+	ReasonInterrupted
+	// ReasonError means CPU stopped with an error, a condition failed or a write was ignored. See Stop.Err.
+	ReasonError
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonBreakpoint:
+		return "breakpoint"
+	case ReasonWatchpoint:
+		return "watchpoint"
+	case ReasonInterrupted:
+		return "interrupted"
+	case ReasonError:
+		return "error"
+	}
+	return "step"
+}
+
+// Stop describes where and why execution stopped.
+type Stop struct {
+	Reason Reason
+	PC     uint16
+	ID     int     // ヒットしたブレークポイントまたはウォッチポイントのID
+	Access *Access // ウォッチポイントにヒットしたアクセス
+	Err    error
+}
+
+// Frame is a subroutine or an interrupt handler on the call stack.
+type Frame struct {
+	// Caller is the address of JSR, or the address where the interrupt occurred.
+	Caller uint16
+	// Entry is the address of the subroutine or the interrupt handler.
+	Entry uint16
+	// SP is the stack pointer before the call.
+	SP        byte
+	Interrupt bool
+}
+
+// Option configures Debugger.
+type Option func(*Debugger)
+
+// WithFrameHook sets a function called when PPU finishes a frame, e.g. to present the screen.
+func WithFrameHook(f func()) Option {
+	return func(d *Debugger) {
+		d.onFrame = f
+	}
+}
+
+// WithSymbols sets labels used to show and parse addresses in REPL.
+func WithSymbols(t *symbols.Table) Option {
+	return func(d *Debugger) {
+		d.symbols = t
+	}
+}
+
+// WithIgnoredWrites sets a function returning a write ignored by memory since the last call,
+// e.g. (*bus.Bus).IgnoredWrite. The debugger stops with ReasonError when it returns an error.
+func WithIgnoredWrites(f func() error) Option {
+	return func(d *Debugger) {
+		d.ignoredWrite = f
+	}
+}
+
+// Debugger drives CPU and PPU one instruction at a time.
+type Debugger struct {
+	cpu *cpu.CPU
+	ppu *ppu.PPU
+
+	nextID      int
+	breakpoints map[int]Breakpoint
+	watchpoints map[int]Watchpoint
+	hits        map[int]int // IDごとの到達回数
+	frames      []Frame     // デバッガで実行中に観測したコールスタック
+
+	// 実行中の命令でヒットしたウォッチポイント
+	hitID  int
+	hit    *Access
+	hitErr error
+	paused atomic.Bool
+
+	onFrame      func()
+	symbols      *symbols.Table
+	ignoredWrite func() error
+}
+
+// New creates Debugger. ppu may be nil to debug CPU only.
+// Debugger installs memory hooks to c and p.
+func New(c *cpu.CPU, p *ppu.PPU, opts ...Option) *Debugger {
+	d := &Debugger{
+		cpu:         c,
+		ppu:         p,
+		nextID:      1,
+		breakpoints: map[int]Breakpoint{},
+		watchpoints: map[int]Watchpoint{},
+		hits:        map[int]int{},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	c.SetMemoryHook(func(address uint16, data byte, write bool) {
+		d.access(CPUSpace, address, data, write)
+	})
+	if p != nil {
+		p.SetMemoryHook(func(address uint16, data byte, write bool) {
+			d.access(PPUSpace, address, data, write)
+		})
+	}
+	return d
+}
+
+// CPU returns the debugged CPU.
+func (d *Debugger) CPU() *cpu.CPU {
+	return d.cpu
+}
+
+// PPU returns the debugged PPU. It is nil when debugging CPU only.
+func (d *Debugger) PPU() *ppu.PPU {
+	return d.ppu
+}
+
+// AddBreakpoint adds bp and returns its ID.
+func (d *Debugger) AddBreakpoint(bp Breakpoint) int {
+	id := d.nextID
+	d.nextID++
+	d.breakpoints[id] = bp
+	return id
+}
+
+// AddWatchpoint adds wp and returns its ID.
+func (d *Debugger) AddWatchpoint(wp Watchpoint) int {
+	id := d.nextID
+	d.nextID++
+	d.watchpoints[id] = wp
+	return id
+}
+
+// Delete removes the breakpoint or watchpoint with id.
+func (d *Debugger) Delete(id int) error {
+	if _, ok := d.breakpoints[id]; ok {
+		delete(d.breakpoints, id)
+		delete(d.hits, id)
+		return nil
+	}
+	if _, ok := d.watchpoints[id]; ok {
+		delete(d.watchpoints, id)
+		delete(d.hits, id)
+		return nil
+	}
+	return fmt.Errorf("no breakpoint or watchpoint %d", id)
+}
+
+// Breakpoints returns breakpoints by ID.
+func (d *Debugger) Breakpoints() map[int]Breakpoint {
+	return d.breakpoints
+}
+
+// Watchpoints returns watchpoints by ID.
+func (d *Debugger) Watchpoints() map[int]Watchpoint {
+	return d.watchpoints
+}
+
+// IDs returns IDs of breakpoints and watchpoints in ascending order.
+func (d *Debugger) IDs() []int {
+	ids := make([]int, 0, len(d.breakpoints)+len(d.watchpoints))
+	for id := range d.breakpoints {
+		ids = append(ids, id)
+	}
+	for id := range d.watchpoints {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Interrupt stops running execution at the next instruction boundary.
+// It can be called from another goroutine, e.g. a signal handler.
+// If nothing is running, the next run stops after its first instruction.
+// これにより実行開始直前に呼ばれた場合も取りこぼさない
+func (d *Debugger) Interrupt() {
+	d.paused.Store(true)
+}
+
+// Step executes one instruction, or an interrupt sequence if one is pending.
+func (d *Debugger) Step() Stop {
+	return d.run(func(cpu.Register) bool {
+		return true
+	})
+}
+
+// StepOver executes one instruction. If it is JSR, it runs until the subroutine returns.
+func (d *Debugger) StepOver() Stop {
+	reg := d.cpu.Register()
+	if d.cpu.Peek(reg.PC) != 0x20 { // JSR
+		return d.Step()
+	}
+	ret, sp := reg.PC+3, reg.S
+	return d.run(func(cpu.Register) bool {
+		return reg.PC == ret && reg.S == sp
+	})
+}
+
+// StepOut runs until the current subroutine returns by RTS or RTI.
+func (d *Debugger) StepOut() Stop {
+	reg := d.cpu.Register()
+	sp := reg.S
+	return d.run(func(before cpu.Register) bool {
+		// 呼び出し元のスタックフレームに戻ったら終了
+		switch d.cpu.Peek(before.PC) {
+		case 0x60, 0x40: // RTS, RTI
+			return reg.S > sp
+		}
+		return false
+	})
+}
+
+// RunToScanline runs until PPU enters scanline.
+func (d *Debugger) RunToScanline(scanline int) (Stop, error) {
+	if d.ppu == nil {
+		return Stop{}, fmt.Errorf("no PPU")
+	}
+	if scanline < 0 || 261 < scanline {
+		return Stop{}, fmt.Errorf("scanline out of range: %d", scanline)
+	}
+	prev, _ := d.ppu.Position()
+	return d.run(func(cpu.Register) bool {
+		line, _ := d.ppu.Position()
+		entered := line == scanline && prev != scanline
+		prev = line
+		return entered
+	}), nil
+}
+
+// RunUntil runs until done returns true, a breakpoint, a watchpoint, an error or Interrupt.
+// done is called after each instruction with registers before the instruction.
+func (d *Debugger) RunUntil(done func(before cpu.Register) bool) Stop {
+	return d.run(done)
+}
+
+// CallStack returns subroutine and interrupt calls observed by the debugger, outermost first.
+// Calls made before the debugger started are not included.
+func (d *Debugger) CallStack() []Frame {
+	return append([]Frame(nil), d.frames...)
+}
+
+// Continue runs until a breakpoint, a watchpoint, an error or Interrupt.
+func (d *Debugger) Continue() Stop {
+	return d.run(func(cpu.Register) bool {
+		return false
+	})
+}
+
+// run executes instructions until done returns true. done receives registers before the instruction.
+// 最初の命令はブレークポイントを無視する. ブレークポイントで止まった位置から再開できるようにするため
+func (d *Debugger) run(done func(before cpu.Register) bool) Stop {
+	defer d.paused.Store(false)
+	reg := d.cpu.Register()
+	for first := true; ; first = false {
+		if !first {
+			if id, err := d.breakpointAt(reg.PC); err != nil {
+				return Stop{Reason: ReasonError, PC: reg.PC, ID: id, Err: err}
+			} else if id != 0 {
+				return Stop{Reason: ReasonBreakpoint, PC: reg.PC, ID: id}
+			}
+			if d.paused.Load() {
+				return Stop{Reason: ReasonInterrupted, PC: reg.PC}
+			}
+		}
+
+		before := *reg
+		code := d.cpu.Peek(reg.PC)
+		interrupts := d.cpu.Interrupts()
+		d.hitID, d.hit, d.hitErr = 0, nil, nil
+		d.tick()
+		d.trackCall(before, code, d.cpu.Interrupts() != interrupts)
+		if err := d.cpu.Err(); err != nil {
+			return Stop{Reason: ReasonError, PC: reg.PC, Err: err}
+		}
+		// CPUは止まらないが、ROMへの書き込みなどはプログラムの誤りなので止めて知らせる
+		if d.ignoredWrite != nil {
+			if err := d.ignoredWrite(); err != nil {
+				return Stop{Reason: ReasonError, PC: reg.PC, Err: err}
+			}
+		}
+		if d.hitErr != nil {
+			return Stop{Reason: ReasonError, PC: reg.PC, ID: d.hitID, Err: d.hitErr}
+		}
+		if d.hit != nil {
+			return Stop{Reason: ReasonWatchpoint, PC: reg.PC, ID: d.hitID, Access: d.hit}
+		}
+		if done(before) {
+			return Stop{Reason: ReasonStep, PC: reg.PC}
+		}
+	}
+}
+
+// tick runs CPU for an instruction and PPU for the same time like main loop.
+func (d *Debugger) tick() {
+	cycle := d.cpu.Run()
+	if d.ppu == nil {
+		return
+	}
+	if screen := d.ppu.Run(cycle * 3); screen != nil && d.onFrame != nil {
+		d.onFrame()
+	}
+	if d.ppu.PollNMI() {
+		d.cpu.NMI()
+	}
+}
+
+// trackCall updates the call stack after an instruction or an interrupt sequence.
+// code is the opcode at PC before it, and interrupted reports whether an interrupt handler was entered.
+func (d *Debugger) trackCall(before cpu.Register, code byte, interrupted bool) {
+	reg := d.cpu.Register()
+	// 呼び出し前のスタックポインタまで戻ったフレームを取り除く. RTS, RTIの他TXSで捨てられた場合も含む
+	n := len(d.frames)
+	for n > 0 && d.frames[n-1].SP <= reg.S {
+		n--
+	}
+	d.frames = d.frames[:n]
+
+	switch {
+	case interrupted: // NMI, IRQ, BRK
+		d.frames = append(d.frames, Frame{Caller: before.PC, Entry: reg.PC, SP: before.S, Interrupt: true})
+	case code == 0x20 && reg.S == before.S-2: // JSR
+		d.frames = append(d.frames, Frame{Caller: before.PC, Entry: reg.PC, SP: before.S})
+	}
+}
+
+// breakpointAt returns the smallest ID of breakpoints at pc whose condition is true.
+// 条件の評価に失敗した場合はエラーとして停止させる
+func (d *Debugger) breakpointAt(pc uint16) (int, error) {
+	found := 0
+	var err error
+	for id, bp := range d.breakpoints {
+		if bp.Address != pc || (bp.Bank >= 0 && bp.Bank != d.cpu.Bank(pc)) {
+			continue
+		}
+		d.hits[id]++
+		ok, condErr := d.condition(bp.Condition, map[string]int{"hits": d.hits[id]})
+		if (ok || condErr != nil) && (found == 0 || id < found) {
+			found, err = id, condErr
+		}
+	}
+	return found, err
+}
+
+// access records the first access hitting a watchpoint.
+func (d *Debugger) access(space Space, address uint16, data byte, write bool) {
+	if d.hit != nil || len(d.watchpoints) == 0 {
+		return
+	}
+	kind := Read
+	if write {
+		kind = Write
+	}
+	for id, wp := range d.watchpoints {
+		if wp.Space != space || wp.Kind&kind == 0 || address < wp.From || wp.To < address {
+			continue
+		}
+		d.hits[id]++
+		ok, err := d.condition(wp.Condition, map[string]int{
+			"hits":    d.hits[id],
+			"address": int(address),
+			"value":   int(data),
+		})
+		if (ok || err != nil) && (d.hitID == 0 || id < d.hitID) {
+			d.hitID, d.hitErr = id, err
+		}
+	}
+	if d.hitID != 0 {
+		d.hit = &Access{Space: space, Address: address, Data: data, Write: write}
+	}
+}
+
+// condition evaluates cond with extra variables. nil is always true.
+func (d *Debugger) condition(cond *expr.Expr, extra map[string]int) (bool, error) {
+	if cond == nil {
+		return true, nil
+	}
+	ok, err := cond.True(&expr.MachineEnv{CPU: d.cpu, PPU: d.ppu, Extra: extra})
+	if err != nil {
+		return false, fmt.Errorf("condition %q: %w", cond, err)
+	}
+	return ok, nil
+}
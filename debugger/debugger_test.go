@@ -0,0 +1,448 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/yusukemisa/gones/asm"
+	"github.com/yusukemisa/gones/bus"
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/expr"
+	"github.com/yusukemisa/gones/ppu"
+	"github.com/yusukemisa/gones/rom"
+	"github.com/yusukemisa/gones/symbols"
+)
+
+// newDebugger assembles src into 32KB PRG-ROM and powers on CPU and PPU.
+// src must set the reset vector.
+func newDebugger(t *testing.T, src string, opts ...Option) *Debugger {
+	t.Helper()
+	var set []asm.Opcode
+	for _, op := range cpu.Opcodes() {
+		set = append(set, asm.Opcode(op))
+	}
+	p, err := asm.New(set).Assemble(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prg := make([]byte, 0x8000)
+	copy(prg[p.Origin-0x8000:], p.Bytes)
+
+	ppu, err := ppu.NewPPU(make([]byte, 0x2000), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cpu.NewCPU(bus.NewBus(&rom.Rom{PRG: prg}, ppu))
+	c.PowerOn()
+	return New(c, ppu, opts...)
+}
+
+const program = `
+PPUADDR = $2006
+PPUDATA = $2007
+		.org $8000
+reset:	LDX #$00
+loop:	JSR sub
+		INX
+		STX $0200
+		JMP loop
+sub:	LDA $0300
+		JSR leaf
+		RTS
+leaf:	RTS
+		.org $C000
+bank1:	LDA #$20
+		STA PPUADDR
+		LDA #$00
+		STA PPUADDR
+		STA PPUDATA
+		JMP bank1
+		.org $FFFC
+		.word reset
+`
+
+func TestDebugger_breakpoint(t *testing.T) {
+	t.Parallel()
+	d := newDebugger(t, program)
+	id := d.AddBreakpoint(Breakpoint{Address: 0x8005, Bank: -1}) // INX
+
+	for i := 1; i <= 2; i++ {
+		stop := d.Continue()
+		if diff := cmp.Diff(Stop{Reason: ReasonBreakpoint, PC: 0x8005, ID: id}, stop); diff != "" {
+			t.Fatalf("stop mismatch (-want +got):\n%s", diff)
+		}
+		if want, got := byte(i-1), d.CPU().Register().X; want != got {
+			t.Errorf("X: want=%v, got=%v", want, got)
+		}
+	}
+
+	if err := d.Delete(id); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Delete(id); err == nil {
+		t.Error("deleting twice must fail")
+	}
+}
+
+func TestDebugger_breakpoint_condition(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name  string
+		cond  string
+		wantX byte
+	}{
+		{name: "register", cond: "X == 3", wantX: 3},
+		{name: "hits", cond: "hits == 5", wantX: 4},
+		{name: "memory", cond: "[$0200] >= 2 && z", wantX: 2},
+		{name: "ppu", cond: "scanline >= 1", wantX: 3},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := newDebugger(t, program)
+			id := d.AddBreakpoint(Breakpoint{Address: 0x8005, Bank: -1, Condition: expr.MustCompile(tt.cond)})
+			stop := d.Continue()
+			if diff := cmp.Diff(Stop{Reason: ReasonBreakpoint, PC: 0x8005, ID: id}, stop); diff != "" {
+				t.Fatalf("stop mismatch (-want +got):\n%s", diff)
+			}
+			if want, got := tt.wantX, d.CPU().Register().X; want != got {
+				t.Errorf("X: want=%v, got=%v", want, got)
+			}
+		})
+	}
+}
+
+func TestDebugger_condition_error(t *testing.T) {
+	t.Parallel()
+	d := newDebugger(t, program)
+	// valueはウォッチポイントでしか使えない
+	id := d.AddBreakpoint(Breakpoint{Address: 0x8005, Bank: -1, Condition: expr.MustCompile("value == 1")})
+	stop := d.Continue()
+	if want, got := ReasonError, stop.Reason; want != got {
+		t.Fatalf("reason: want=%v, got=%v", want, got)
+	}
+	if want, got := id, stop.ID; want != got {
+		t.Errorf("ID: want=%v, got=%v", want, got)
+	}
+	if stop.Err == nil || !strings.Contains(stop.Err.Error(), "value") {
+		t.Errorf("unexpected error: %v", stop.Err)
+	}
+}
+
+func TestDebugger_ignoredWrite(t *testing.T) {
+	t.Parallel()
+	var set []asm.Opcode
+	for _, op := range cpu.Opcodes() {
+		set = append(set, asm.Opcode(op))
+	}
+	p, err := asm.New(set).Assemble(`
+		.org $8000
+reset:	LDA #$01
+		STA $8000
+		JMP reset
+		.org $FFFC
+		.word reset
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prg := make([]byte, 0x8000)
+	copy(prg[p.Origin-0x8000:], p.Bytes)
+	b := bus.NewBus(&rom.Rom{PRG: prg}, nil)
+	c := cpu.NewCPU(b)
+	c.PowerOn()
+	d := New(c, nil, WithIgnoredWrites(b.IgnoredWrite))
+
+	// CPUは止まらないが、デバッガはROMへの書き込みで止まる
+	stop := d.Continue()
+	if want, got := ReasonError, stop.Reason; want != got {
+		t.Fatalf("reason: want=%v, got=%v", want, got)
+	}
+	if diff := cmp.Diff(&bus.ErrPRGWrite{Address: 0x8000, Data: 0x01}, stop.Err); diff != "" {
+		t.Errorf("error mismatch (-want +got):\n%s", diff)
+	}
+	if want, got := uint16(0x8005), stop.PC; want != got {
+		t.Errorf("PC: want=%#04x, got=%#04x", want, got)
+	}
+	if err := c.Err(); err != nil {
+		t.Errorf("CPU must keep running: %v", err)
+	}
+}
+
+func TestDebugger_breakpoint_bank(t *testing.T) {
+	t.Parallel()
+	d := newDebugger(t, program)
+	d.CPU().Register().PC = 0xC000
+	// 0xC000はPRGのバンク1
+	d.AddBreakpoint(Breakpoint{Address: 0xC002, Bank: 0})
+	id := d.AddBreakpoint(Breakpoint{Address: 0xC005, Bank: 1})
+
+	stop := d.Continue()
+	if diff := cmp.Diff(Stop{Reason: ReasonBreakpoint, PC: 0xC005, ID: id}, stop); diff != "" {
+		t.Errorf("stop mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDebugger_watchpoint(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name string
+		pc   uint16
+		wp   Watchpoint
+		want Stop
+	}{
+		{
+			name: "cpu write",
+			pc:   0x8000,
+			wp:   Watchpoint{Space: CPUSpace, From: 0x0200, To: 0x0200, Kind: Write},
+			want: Stop{Reason: ReasonWatchpoint, PC: 0x8009, ID: 1, Access: &Access{CPUSpace, 0x0200, 0x01, true}},
+		},
+		{
+			name: "cpu read in range",
+			pc:   0x8000,
+			wp:   Watchpoint{Space: CPUSpace, From: 0x02FF, To: 0x0300, Kind: Read},
+			want: Stop{Reason: ReasonWatchpoint, PC: 0x800F, ID: 1, Access: &Access{CPUSpace, 0x0300, 0x00, false}},
+		},
+		{
+			name: "ppu write",
+			pc:   0xC000,
+			wp:   Watchpoint{Space: PPUSpace, From: 0x2000, To: 0x23FF, Kind: ReadWrite},
+			want: Stop{Reason: ReasonWatchpoint, PC: 0xC00D, ID: 1, Access: &Access{PPUSpace, 0x2000, 0x00, true}},
+		},
+		{
+			name: "condition on value",
+			pc:   0x8000,
+			wp: Watchpoint{Space: CPUSpace, From: 0x0200, To: 0x0200, Kind: Write,
+				Condition: expr.MustCompile("value == 3 && address == $0200")},
+			want: Stop{Reason: ReasonWatchpoint, PC: 0x8009, ID: 1, Access: &Access{CPUSpace, 0x0200, 0x03, true}},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := newDebugger(t, program)
+			d.CPU().Register().PC = tt.pc
+			d.AddWatchpoint(tt.wp)
+			if diff := cmp.Diff(tt.want, d.Continue()); diff != "" {
+				t.Errorf("stop mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDebugger_step(t *testing.T) {
+	t.Parallel()
+	d := newDebugger(t, program)
+	reg := d.CPU().Register()
+
+	var got []uint16
+	d.Step() // LDX
+	got = append(got, reg.PC)
+	d.Step() // JSR sub
+	got = append(got, reg.PC)
+	d.StepOver() // LDA
+	got = append(got, reg.PC)
+	d.StepOver() // JSR leaf
+	got = append(got, reg.PC)
+	d.StepOut() // subからloopへ戻る
+	got = append(got, reg.PC)
+	d.StepOver() // INX
+	got = append(got, reg.PC)
+
+	want := []uint16{0x8002, 0x800C, 0x800F, 0x8012, 0x8005, 0x8006}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("PC mismatch (-want +got):\n%s", diff)
+	}
+	if want, got := byte(0xFD), reg.S; want != got {
+		t.Errorf("S: want=%#02x, got=%#02x", want, got)
+	}
+}
+
+func TestDebugger_StepOver_breakpoint(t *testing.T) {
+	t.Parallel()
+	d := newDebugger(t, program)
+	d.Step()                                                     // LDX
+	id := d.AddBreakpoint(Breakpoint{Address: 0x8013, Bank: -1}) // leaf
+
+	stop := d.StepOver()
+	if diff := cmp.Diff(Stop{Reason: ReasonBreakpoint, PC: 0x8013, ID: id}, stop); diff != "" {
+		t.Errorf("stop mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDebugger_CallStack(t *testing.T) {
+	t.Parallel()
+	d := newDebugger(t, program)
+	d.AddBreakpoint(Breakpoint{Address: 0x8013, Bank: -1}) // leaf
+
+	d.Continue()
+	want := []Frame{
+		{Caller: 0x8002, Entry: 0x800C, SP: 0xFD},
+		{Caller: 0x800F, Entry: 0x8013, SP: 0xFB},
+	}
+	if diff := cmp.Diff(want, d.CallStack()); diff != "" {
+		t.Errorf("call stack mismatch (-want +got):\n%s", diff)
+	}
+	var out bytes.Buffer
+	if _, err := d.Exec(&out, "bt"); err != nil {
+		t.Fatal(err)
+	}
+	wantOut := `#0  $8013 in $8013
+#1  $800F in $800C
+#2  $8002 in ?
+`
+	if diff := cmp.Diff(wantOut, out.String()); diff != "" {
+		t.Errorf("backtrace mismatch (-want +got):\n%s", diff)
+	}
+
+	// RTSで戻るとフレームが取り除かれる
+	d.StepOut()
+	if diff := cmp.Diff(want[:1], d.CallStack()); diff != "" {
+		t.Errorf("call stack mismatch (-want +got):\n%s", diff)
+	}
+	stop := d.RunUntil(func(before cpu.Register) bool {
+		return before.PC == 0x8012 // subのRTS
+	})
+	if want, got := uint16(0x8005), stop.PC; want != got {
+		t.Errorf("PC: want=%#04x, got=%#04x", want, got)
+	}
+	if got := d.CallStack(); len(got) != 0 {
+		t.Errorf("call stack must be empty: %v", got)
+	}
+
+	// NMIは割り込みフレームになる
+	d.CPU().NMI()
+	d.Step()
+	if got := d.CallStack(); len(got) != 1 || !got[0].Interrupt || got[0].Caller != 0x8005 {
+		t.Errorf("unexpected call stack: %v", got)
+	}
+}
+
+func TestDebugger_RunToScanline(t *testing.T) {
+	t.Parallel()
+	d := newDebugger(t, program)
+	for _, line := range []int{20, 241, 0} {
+		if _, err := d.RunToScanline(line); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := d.PPU().Position()
+		if line != got {
+			t.Errorf("scanline: want=%v, got=%v", line, got)
+		}
+	}
+	if _, err := d.RunToScanline(262); err == nil {
+		t.Error("scanline 262 must be rejected")
+	}
+}
+
+func TestDebugger_Interrupt(t *testing.T) {
+	t.Parallel()
+	var d *Debugger
+	frames := 0
+	d = newDebugger(t, program, WithFrameHook(func() {
+		frames++
+		d.Interrupt()
+	}))
+	stop := d.Continue()
+	if want, got := ReasonInterrupted, stop.Reason; want != got {
+		t.Errorf("reason: want=%v, got=%v", want, got)
+	}
+	if want, got := 1, frames; want != got {
+		t.Errorf("frames: want=%v, got=%v", want, got)
+	}
+}
+
+func TestDebugger_REPL(t *testing.T) {
+	t.Parallel()
+	d := newDebugger(t, program)
+	in := strings.Join([]string{
+		"break 8005",
+		"watch w 0200",
+		"break 8002 if X == 1 && hits > 1",
+		"info",
+		"delete 3",
+		"c",
+		"c",
+		"",
+		"regs",
+		"x 0200 4",
+		"ppu",
+		"l 8000 2",
+		"delete 9",
+		"foo",
+		"q",
+		"step", // quitの後は読まない
+	}, "\n")
+	var out bytes.Buffer
+	if err := d.REPL(strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `> 8000  A2 00     LDX #$00
+(gones) breakpoint 1 at $8005
+(gones) watchpoint 2 at cpu w $0200
+(gones) breakpoint 3 at $8002 if X == 1 && hits > 1
+(gones) 1: break $8005
+2: watch cpu w $0200
+3: break $8002 if X == 1 && hits > 1
+(gones) (gones) breakpoint 1
+> 8005  E8        INX
+(gones) watchpoint 2: cpu write $0200 = 01
+> 8009  4C 02 80  JMP $8002
+(gones) breakpoint 1
+> 8005  E8        INX
+(gones) PC:8005 A:00 X:01 Y:00 P:26(nvUbdIZc) SP:FD CYC:74
+(gones) 0200  01 00 00 00
+(gones) CTRL:00 MASK:00 STATUS:00 SCROLL:00 ADDR:0000 scanline:0 dot:201
+(gones)   8000  A2 00     LDX #$00
+  8002  20 0C 80  JSR $800C
+(gones) error: no breakpoint or watchpoint 9
+(gones) error: unknown command "foo". type help
+(gones) `
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("REPL output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDebugger_REPL_symbols(t *testing.T) {
+	t.Parallel()
+	table := symbols.New()
+	table.AddLabel("reset", symbols.Location{Bank: 0, Address: 0x8000})
+	table.AddLabel("sub", symbols.Location{Bank: 0, Address: 0x800C})
+	table.AddLabel("leaf", symbols.Location{Bank: 0, Address: 0x8013})
+	table.AddLabel("counter", symbols.Location{Bank: -1, Address: 0x0300})
+	d := newDebugger(t, program, WithSymbols(table))
+	in := strings.Join([]string{
+		"break leaf",
+		"c",
+		"bt",
+		"x counter 2",
+		"l sub 2",
+		"break nothing",
+	}, "\n")
+	var out bytes.Buffer
+	if err := d.REPL(strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `reset:
+> 8000  A2 00     LDX #$00
+(gones) breakpoint 1 at $8013 bank 0
+(gones) breakpoint 1
+leaf:
+> 8013  60        RTS
+(gones) #0  $8013 in leaf
+#1  $800F in sub
+#2  $8002 in ?
+(gones) 0300  00 00
+(gones) sub:
+  800C  AD 00 03  LDA counter = 00
+  800F  20 13 80  JSR leaf
+(gones) error: invalid address: nothing
+(gones) `
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("REPL output mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,408 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/expr"
+	"github.com/yusukemisa/gones/symbols"
+)
+
+const prompt = "(gones) "
+
+const help = `commands:
+  s, step [N]                 execute N instructions (default 1)
+  n, next                     step over JSR
+  finish                      run until the current subroutine returns
+  c, continue                 run until a breakpoint or watchpoint (Ctrl-C to pause)
+  scanline N                  run until PPU enters scanline N
+  b, break ADDR [BANK] [if COND]
+                              set a breakpoint
+  w, watch [cpu|ppu] [r|w|rw] ADDR[-END] [if COND]
+                              set a watchpoint (default: cpu, w)
+  bt, backtrace               show the call stack
+  d, delete ID                delete a breakpoint or watchpoint
+  i, info                     list breakpoints and watchpoints
+  r, regs                     show CPU registers
+  x, mem ADDR [LEN]           dump CPU memory
+  ppu                         show PPU registers
+  ppumem ADDR [LEN]           dump PPU memory
+  l, disasm [ADDR] [N]        disassemble N instructions (default: PC, 10)
+  q, quit                     quit
+An empty line repeats the last command. Addresses are hexadecimal or labels of symbol files.
+COND is an expression such as "A == #$40 && [$0300] > 3 && scanline < 20" or "hits == 10".
+`
+
+// REPL reads commands from r and writes results to w until quit or EOF.
+func (d *Debugger) REPL(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	var last string
+	d.printLocation(w)
+	for {
+		fmt.Fprint(w, prompt)
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			line = last
+		}
+		if line == "" {
+			continue
+		}
+		last = line
+		quit, err := d.Exec(w, line)
+		if err != nil {
+			fmt.Fprintf(w, "error: %v\n", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// Exec executes a REPL command. It reports true when the command is quit.
+func (d *Debugger) Exec(w io.Writer, line string) (quit bool, err error) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return false, nil
+	}
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "h", "help":
+		fmt.Fprint(w, help)
+	case "s", "step":
+		n := 1
+		if len(args) > 0 {
+			if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+				return false, fmt.Errorf("invalid count: %s", args[0])
+			}
+		}
+		stop := d.Step()
+		for i := 1; i < n && stop.Reason == ReasonStep; i++ {
+			stop = d.Step()
+		}
+		d.printStop(w, stop)
+	case "n", "next":
+		d.printStop(w, d.StepOver())
+	case "finish":
+		d.printStop(w, d.StepOut())
+	case "c", "continue":
+		d.printStop(w, d.Continue())
+	case "scanline":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: scanline N")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return false, fmt.Errorf("invalid scanline: %s", args[0])
+		}
+		stop, err := d.RunToScanline(n)
+		if err != nil {
+			return false, err
+		}
+		d.printStop(w, stop)
+	case "b", "break":
+		return false, d.execBreak(w, args)
+	case "w", "watch":
+		return false, d.execWatch(w, args)
+	case "d", "delete":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: delete ID")
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return false, fmt.Errorf("invalid ID: %s", args[0])
+		}
+		return false, d.Delete(id)
+	case "bt", "backtrace":
+		d.printBacktrace(w)
+	case "i", "info":
+		d.printPoints(w)
+	case "r", "regs":
+		fmt.Fprintf(w, "%v CYC:%d\n", *d.cpu.Register(), d.cpu.Cycles())
+	case "x", "mem":
+		return false, d.execDump(w, args, d.cpu.Peek)
+	case "ppu":
+		if d.ppu == nil {
+			return false, fmt.Errorf("no PPU")
+		}
+		s := d.ppu.State()
+		fmt.Fprintf(w, "CTRL:%02X MASK:%02X STATUS:%02X SCROLL:%02X ADDR:%04X scanline:%d dot:%d\n",
+			s.CTRL, s.MASK, s.STATUS, s.SCROLL, s.Address, s.Scanline, s.Dot)
+	case "ppumem":
+		if d.ppu == nil {
+			return false, fmt.Errorf("no PPU")
+		}
+		return false, d.execDump(w, args, d.ppu.Peek)
+	case "l", "disasm":
+		return false, d.execDisasm(w, args)
+	case "q", "quit":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %q. type help", cmd)
+	}
+	return false, nil
+}
+
+func (d *Debugger) execBreak(w io.Writer, args []string) error {
+	args, cond, err := splitCondition(args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || 2 < len(args) {
+		return fmt.Errorf("usage: break ADDR [BANK] [if COND]")
+	}
+	loc, err := d.parseLocation(args[0])
+	if err != nil {
+		return err
+	}
+	// ラベルのバンクが分かっていればそのバンクだけで止まる
+	bp := Breakpoint{Address: loc.Address, Bank: loc.Bank, Condition: cond}
+	if len(args) == 2 {
+		if bp.Bank, err = strconv.Atoi(args[1]); err != nil || bp.Bank < 0 {
+			return fmt.Errorf("invalid bank: %s", args[1])
+		}
+	}
+	fmt.Fprintf(w, "breakpoint %d at %s\n", d.AddBreakpoint(bp), formatBreakpoint(bp))
+	return nil
+}
+
+func (d *Debugger) execWatch(w io.Writer, args []string) error {
+	args, cond, err := splitCondition(args)
+	if err != nil {
+		return err
+	}
+	wp := Watchpoint{Space: CPUSpace, Kind: Write, Condition: cond}
+	var hasAddress bool
+	for _, arg := range args {
+		switch arg {
+		case "cpu":
+			wp.Space = CPUSpace
+		case "ppu":
+			wp.Space = PPUSpace
+		case "r":
+			wp.Kind = Read
+		case "w":
+			wp.Kind = Write
+		case "rw":
+			wp.Kind = ReadWrite
+		default:
+			from, to, found := strings.Cut(arg, "-")
+			var err error
+			if wp.From, err = d.parseAddress(from); err != nil {
+				return err
+			}
+			wp.To = wp.From
+			if found {
+				if wp.To, err = d.parseAddress(to); err != nil {
+					return err
+				}
+			}
+			if wp.To < wp.From {
+				return fmt.Errorf("invalid range: %s", arg)
+			}
+			hasAddress = true
+		}
+	}
+	if !hasAddress {
+		return fmt.Errorf("usage: watch [cpu|ppu] [r|w|rw] ADDR[-END] [if COND]")
+	}
+	fmt.Fprintf(w, "watchpoint %d at %s\n", d.AddWatchpoint(wp), formatWatchpoint(wp))
+	return nil
+}
+
+func (d *Debugger) execDump(w io.Writer, args []string, peek func(uint16) byte) error {
+	if len(args) < 1 || 2 < len(args) {
+		return fmt.Errorf("usage: mem ADDR [LEN]")
+	}
+	addr, err := d.parseAddress(args[0])
+	if err != nil {
+		return err
+	}
+	n := 0x40
+	if len(args) == 2 {
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+			return fmt.Errorf("invalid length: %s", args[1])
+		}
+	}
+	for i := 0; i < n; i += 0x10 {
+		fmt.Fprintf(w, "%04X ", addr+uint16(i))
+		for j := i; j < i+0x10 && j < n; j++ {
+			fmt.Fprintf(w, " %02X", peek(addr+uint16(j)))
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func (d *Debugger) execDisasm(w io.Writer, args []string) error {
+	addr, n := d.cpu.Register().PC, 10
+	var err error
+	if len(args) > 0 {
+		if addr, err = d.parseAddress(args[0]); err != nil {
+			return err
+		}
+	}
+	if len(args) > 1 {
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+			return fmt.Errorf("invalid count: %s", args[1])
+		}
+	}
+	for i := 0; i < n; i++ {
+		inst := d.cpu.Disassemble(addr)
+		d.printInstruction(w, inst, addr == d.cpu.Register().PC)
+		addr += uint16(len(inst.Bytes))
+	}
+	return nil
+}
+
+func (d *Debugger) printStop(w io.Writer, stop Stop) {
+	switch stop.Reason {
+	case ReasonBreakpoint:
+		fmt.Fprintf(w, "breakpoint %d\n", stop.ID)
+	case ReasonWatchpoint:
+		a := stop.Access
+		kind := "read"
+		if a.Write {
+			kind = "write"
+		}
+		fmt.Fprintf(w, "watchpoint %d: %s %s $%04X = %02X\n", stop.ID, a.Space, kind, a.Address, a.Data)
+	case ReasonInterrupted:
+		fmt.Fprintln(w, "interrupted")
+	case ReasonError:
+		fmt.Fprintf(w, "stopped: %v\n", stop.Err)
+	}
+	d.printLocation(w)
+}
+
+// printLocation prints the next instruction.
+func (d *Debugger) printLocation(w io.Writer) {
+	pc := d.cpu.Register().PC
+	d.printInstruction(w, d.cpu.Disassemble(pc), true)
+}
+
+func (d *Debugger) printPoints(w io.Writer) {
+	for _, id := range d.IDs() {
+		if bp, ok := d.breakpoints[id]; ok {
+			fmt.Fprintf(w, "%d: break %s\n", id, formatBreakpoint(bp))
+			continue
+		}
+		fmt.Fprintf(w, "%d: watch %s\n", id, formatWatchpoint(d.watchpoints[id]))
+	}
+}
+
+// printBacktrace prints the call stack innermost first, e.g. "#1  $800F in $800C" or "#1  $800F in sub".
+func (d *Debugger) printBacktrace(w io.Writer) {
+	pc := d.cpu.Register().PC
+	for i := len(d.frames); i >= 0; i-- {
+		entry, suffix := "?", ""
+		if i > 0 {
+			f := d.frames[i-1]
+			entry = d.formatAddress(f.Entry)
+			if f.Interrupt {
+				suffix = " <interrupt>"
+			}
+		}
+		fmt.Fprintf(w, "#%d  $%04X in %s%s\n", len(d.frames)-i, pc, entry, suffix)
+		if i > 0 {
+			pc = d.frames[i-1].Caller
+		}
+	}
+}
+
+// printInstruction prints an instruction. With symbols, the label of the address is printed before it
+// and the operand address is replaced by its label.
+func (d *Debugger) printInstruction(w io.Writer, inst cpu.Instruction, current bool) {
+	mark := " "
+	if current {
+		mark = ">"
+	}
+	if d.symbols != nil {
+		if name, ok := d.label(inst.Address); ok {
+			fmt.Fprintf(w, "%s:\n", name)
+		}
+		inst = inst.Symbolize(d.label)
+	}
+	fmt.Fprintf(w, "%s %04X  %-8s  %s\n", mark, inst.Address, inst.FormatBytes(), inst)
+}
+
+// label returns the label at address in the current bank.
+func (d *Debugger) label(address uint16) (string, bool) {
+	if d.symbols == nil {
+		return "", false
+	}
+	return d.symbols.Label(symbols.Location{Bank: d.cpu.Bank(address), Address: address})
+}
+
+// formatAddress returns the label at address or "$XXXX".
+func (d *Debugger) formatAddress(address uint16) string {
+	if name, ok := d.label(address); ok {
+		return name
+	}
+	return fmt.Sprintf("$%04X", address)
+}
+
+func formatBreakpoint(bp Breakpoint) string {
+	s := fmt.Sprintf("$%04X", bp.Address)
+	if bp.Bank >= 0 {
+		s += fmt.Sprintf(" bank %d", bp.Bank)
+	}
+	return s + formatCondition(bp.Condition)
+}
+
+func formatWatchpoint(wp Watchpoint) string {
+	s := fmt.Sprintf("%s %s $%04X", wp.Space, wp.Kind, wp.From)
+	if wp.From != wp.To {
+		s += fmt.Sprintf("-$%04X", wp.To)
+	}
+	return s + formatCondition(wp.Condition)
+}
+
+func formatCondition(cond *expr.Expr) string {
+	if cond == nil {
+		return ""
+	}
+	return " if " + cond.String()
+}
+
+// splitCondition splits "ARGS... if COND" into arguments and the compiled condition.
+func splitCondition(args []string) ([]string, *expr.Expr, error) {
+	for i, arg := range args {
+		if arg != "if" {
+			continue
+		}
+		cond, err := expr.Compile(strings.Join(args[i+1:], " "))
+		if err != nil {
+			return nil, nil, err
+		}
+		return args[:i], cond, nil
+	}
+	return args, nil, nil
+}
+
+// parseAddress parses a label or hexadecimal address such as "C000", "$C000" and "0xC000".
+func (d *Debugger) parseAddress(s string) (uint16, error) {
+	loc, err := d.parseLocation(s)
+	return loc.Address, err
+}
+
+// parseLocation is parseAddress returning the bank of the label. The bank of a number is -1.
+// "$"や"0x"の付かない名前はラベルを優先する. "add"や"beef"のような16進数と紛らわしいラベルのため
+func (d *Debugger) parseLocation(s string) (symbols.Location, error) {
+	if d.symbols != nil {
+		if loc, ok := d.symbols.Address(s); ok {
+			return loc, nil
+		}
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "$"), "0x")
+	v, err := strconv.ParseUint(hex, 16, 16)
+	if err != nil {
+		return symbols.Location{}, fmt.Errorf("invalid address: %s", s)
+	}
+	return symbols.Location{Bank: -1, Address: uint16(v)}, nil
+}
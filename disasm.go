@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yusukemisa/gones/bus"
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/rom"
+)
+
+// vectors are entry points of a ROM.
+var vectors = []struct {
+	name    string
+	address uint16
+}{
+	{"nmi", 0xFFFA},
+	{"reset", 0xFFFC},
+	{"irq", 0xFFFE},
+}
+
+// disasm writes the code reachable from the interrupt vectors of the ROM at path.
+// symbolFiles are comma separated symbol files to show labels.
+func disasm(w io.Writer, path, symbolFiles string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// 逆アセンブルはPRG-ROMだけを読むのでPPUは不要
+	r, err := rom.NewRom(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	mem := bus.NewBus(r, nil)
+	syms, err := loadSymbols(symbolFiles, len(r.PRG))
+	if err != nil {
+		return err
+	}
+	label := func(uint16) (string, bool) { return "", false }
+	if syms != nil {
+		label = syms.LabelFunc(mem.Bank)
+	}
+
+	labels := map[uint16][]string{}
+	var entries []uint16
+	for _, v := range vectors {
+		addr := uint16(mem.Peek(v.address)) | uint16(mem.Peek(v.address+1))<<8
+		fmt.Fprintf(w, "; %-5s $%04X\n", v.name, addr)
+		labels[addr] = append(labels[addr], v.name)
+		entries = append(entries, addr)
+	}
+
+	var next uint16
+	for i, inst := range cpu.DisassembleCode(mem, 0x8000, 0xFFFF, entries...) {
+		// 連続していない命令の間は空行で区切る
+		if i == 0 || inst.Address != next {
+			fmt.Fprintln(w)
+		}
+		for _, name := range labels[inst.Address] {
+			fmt.Fprintf(w, "%s:\n", name)
+		}
+		// ベクタ名と同じラベルは二重に出さない
+		if name, ok := label(inst.Address); ok && !contains(labels[inst.Address], name) {
+			fmt.Fprintf(w, "%s:\n", name)
+		}
+		fmt.Fprintf(w, "%04X  %-8s  %s\n", inst.Address, inst.FormatBytes(), inst.Symbolize(label))
+		next = inst.Address + uint16(len(inst.Bytes))
+	}
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
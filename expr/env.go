@@ -0,0 +1,113 @@
+package expr
+
+import (
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/ppu"
+	"github.com/yusukemisa/gones/util"
+)
+
+// flags maps flag names to bits of P.
+var flags = map[string]byte{"c": 0, "z": 1, "i": 2, "d": 3, "v": 6, "n": 7}
+
+// MachineEnv is Env reading the current state of CPU and PPU.
+type MachineEnv struct {
+	CPU *cpu.CPU
+	PPU *ppu.PPU // nilの場合scanline, dot, frameは使えない
+	// Extra provides context dependent variables such as hits, address and value.
+	Extra map[string]int
+}
+
+// Var implements Env.
+func (e *MachineEnv) Var(name string) (int, bool) {
+	if v, ok := e.Extra[name]; ok {
+		return v, true
+	}
+	reg := e.CPU.Register()
+	switch name {
+	case "cycles":
+		return int(e.CPU.Cycles()), true
+	case "bank":
+		return e.CPU.Bank(reg.PC), true
+	case "scanline", "dot", "frame":
+		return ppuVar(e.PPU, name)
+	}
+	return registerVar(*reg, name)
+}
+
+// Peek implements Env.
+func (e *MachineEnv) Peek(address uint16) byte {
+	return e.CPU.Peek(address)
+}
+
+// TraceFilter returns a trace filter which evaluates e with the state before each instruction.
+// Memory is read from c and frame from p. An evaluation error is treated as false.
+func TraceFilter(e *Expr, c *cpu.CPU, p *ppu.PPU) cpu.TraceFilter {
+	return func(s *cpu.TraceState) bool {
+		ok, err := e.True(&traceEnv{state: s, cpu: c, ppu: p})
+		return err == nil && ok
+	}
+}
+
+type traceEnv struct {
+	state *cpu.TraceState
+	cpu   *cpu.CPU
+	ppu   *ppu.PPU
+}
+
+func (e *traceEnv) Var(name string) (int, bool) {
+	s := e.state
+	switch name {
+	case "cycles":
+		return int(s.Cycles), true
+	case "bank":
+		return s.Bank, true
+	case "scanline":
+		return s.Scanline, true
+	case "dot":
+		return s.Dot, true
+	case "frame":
+		return ppuVar(e.ppu, name)
+	}
+	return registerVar(cpu.Register{A: s.A, X: s.X, Y: s.Y, S: s.S, P: s.P, PC: s.PC}, name)
+}
+
+func (e *traceEnv) Peek(address uint16) byte {
+	return e.cpu.Peek(address)
+}
+
+func registerVar(reg cpu.Register, name string) (int, bool) {
+	switch name {
+	case "a":
+		return int(reg.A), true
+	case "x":
+		return int(reg.X), true
+	case "y":
+		return int(reg.Y), true
+	case "s":
+		return int(reg.S), true
+	case "p":
+		return int(reg.P), true
+	case "pc":
+		return int(reg.PC), true
+	}
+	if bit, ok := flags[name]; ok {
+		return boolInt(util.TestBit(reg.P, bit)), true
+	}
+	return 0, false
+}
+
+func ppuVar(p *ppu.PPU, name string) (int, bool) {
+	if p == nil {
+		return 0, false
+	}
+	s := p.State()
+	switch name {
+	case "scanline":
+		return s.Scanline, true
+	case "dot":
+		return s.Dot, true
+	case "frame":
+		return int(s.Frame), true
+	}
+	return 0, false
+}
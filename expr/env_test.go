@@ -0,0 +1,39 @@
+package expr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/yusukemisa/gones/cpu"
+)
+
+func TestTraceFilter(t *testing.T) {
+	t.Parallel()
+	mem := &cpu.RAM{}
+	mem.Load(0x8000, []byte{
+		0xA2, 0x00, // LDX #$00
+		0xE8,             // INX
+		0x8E, 0x00, 0x03, // STX $0300
+		0x4C, 0x02, 0x80, // JMP $8002
+	})
+	c := cpu.NewCPU(mem)
+	c.Register().PC = 0x8000
+
+	var buf bytes.Buffer
+	e := MustCompile("PC == $8002 && [$0300] >= 2 && !z && cycles < 100")
+	c.SetTracer(cpu.NewTraceLogger(&buf, cpu.WithTraceFilter(TraceFilter(e, c, nil))))
+	for i := 0; i < 40; i++ {
+		c.Run()
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		got = append(got, line[:4]+" "+line[53:57])
+	}
+	want := []string{"8002 X:02", "8002 X:03", "8002 X:04", "8002 X:05", "8002 X:06", "8002 X:07", "8002 X:08", "8002 X:09", "8002 X:0A"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("trace mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,392 @@
+// Package expr is an expression language for conditional breakpoints, watchpoints and trace filters.
+//
+//	A == #$40 && [$0300] > 3 && scanline < 20
+//
+// Values are integers and a condition is true if it is not 0.
+//   - numbers: $40, #$40, 0x40, %0100_0000 and 64. `#` is allowed for readability
+//   - registers: A, X, Y, S (or SP), P and PC
+//   - flags: C, Z, I, D, V and N are 1 if set
+//   - PPU and timing: scanline, dot, frame and cycles
+//   - bank: PRG bank of PC
+//   - hits: how many times the breakpoint was reached including this time
+//   - address, value: the accessed address and data in watchpoints
+//   - memory: [expr] reads a byte without side effects
+//   - operators (by precedence): || && | ^ & (== !=) (< <= > >=) (<< >>) (+ -) (* / %) and unary ! - ~
+//
+// Names are case insensitive.
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Env provides variables and memory to expressions.
+type Env interface {
+	// Var returns the value of a variable. ok is false if it is not available in the context.
+	Var(name string) (v int, ok bool)
+	// Peek reads memory without side effects.
+	Peek(address uint16) byte
+}
+
+// vars are the known variable names.
+var vars = map[string]bool{
+	"a": true, "x": true, "y": true, "s": true, "p": true, "pc": true,
+	"c": true, "z": true, "i": true, "d": true, "v": true, "n": true,
+	"scanline": true, "dot": true, "frame": true, "cycles": true, "bank": true,
+	"hits": true, "address": true, "value": true,
+}
+
+// Expr is a compiled expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses src.
+func Compile(src string) (*Expr, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	root, err := p.parse(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// MustCompile is like Compile but panics if src cannot be parsed.
+func MustCompile(src string) *Expr {
+	e, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression.
+func (e *Expr) Eval(env Env) (int, error) {
+	return e.root.eval(env)
+}
+
+// True evaluates the expression as a condition.
+func (e *Expr) True(env Env) (bool, error) {
+	v, err := e.Eval(env)
+	return v != 0, err
+}
+
+type node interface {
+	eval(env Env) (int, error)
+}
+
+type number int
+
+func (n number) eval(Env) (int, error) {
+	return int(n), nil
+}
+
+type variable string
+
+func (v variable) eval(env Env) (int, error) {
+	value, ok := env.Var(string(v))
+	if !ok {
+		return 0, fmt.Errorf("%s is not available here", string(v))
+	}
+	return value, nil
+}
+
+type memory struct {
+	address node
+}
+
+func (m memory) eval(env Env) (int, error) {
+	addr, err := m.address.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	return int(env.Peek(uint16(addr))), nil
+}
+
+type unary struct {
+	op string
+	x  node
+}
+
+func (u unary) eval(env Env) (int, error) {
+	x, err := u.x.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch u.op {
+	case "-":
+		return -x, nil
+	case "~":
+		return ^x, nil
+	}
+	return boolInt(x == 0), nil
+}
+
+type binary struct {
+	op   string
+	x, y node
+}
+
+func (b binary) eval(env Env) (int, error) {
+	x, err := b.x.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	// && と || は短絡評価する
+	switch {
+	case b.op == "&&" && x == 0:
+		return 0, nil
+	case b.op == "||" && x != 0:
+		return 1, nil
+	}
+	y, err := b.y.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case "||", "&&":
+		return boolInt(y != 0), nil
+	case "|":
+		return x | y, nil
+	case "^":
+		return x ^ y, nil
+	case "&":
+		return x & y, nil
+	case "==":
+		return boolInt(x == y), nil
+	case "!=":
+		return boolInt(x != y), nil
+	case "<":
+		return boolInt(x < y), nil
+	case "<=":
+		return boolInt(x <= y), nil
+	case ">":
+		return boolInt(x > y), nil
+	case ">=":
+		return boolInt(x >= y), nil
+	case "<<":
+		return x << uint(y&63), nil
+	case ">>":
+		return x >> uint(y&63), nil
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	}
+	if y == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	if b.op == "/" {
+		return x / y, nil
+	}
+	return x % y, nil
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// precedence of binary operators. Larger binds tighter.
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, "<=": 7, ">": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value int
+	pos   int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at %d: %s", fmt.Sprintf(format, args...), p.tok.pos+1, p.src)
+}
+
+// parse parses binary operators whose precedence is larger than min.
+func (p *parser) parse(min int) (node, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && precedence[p.tok.text] > min {
+		op := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		y, err := p.parse(precedence[op])
+		if err != nil {
+			return nil, err
+		}
+		x = binary{op: op, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokNumber:
+		return number(tok.value), p.next()
+	case tok.kind == tokIdent:
+		name := strings.ToLower(tok.text)
+		if name == "sp" {
+			name = "s"
+		}
+		if !vars[name] {
+			return nil, p.errorf("unknown name %q", tok.text)
+		}
+		return variable(name), p.next()
+	case tok.text == "!" || tok.text == "-" || tok.text == "~":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op: tok.text, x: x}, nil
+	case tok.text == "(" || tok.text == "[":
+		closing := ")"
+		if tok.text == "[" {
+			closing = "]"
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parse(0)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.text != closing {
+			return nil, p.errorf("missing %q", closing)
+		}
+		if tok.text == "[" {
+			x = memory{address: x}
+		}
+		return x, p.next()
+	case tok.kind == tokEOF:
+		return nil, p.errorf("unexpected end")
+	}
+	return nil, p.errorf("unexpected %q", tok.text)
+}
+
+// next reads the next token.
+func (p *parser) next() error {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return nil
+	}
+
+	// 値の直後の%は剰余、それ以外は2進数
+	afterValue := p.tok.kind == tokNumber || p.tok.kind == tokIdent || p.tok.text == ")" || p.tok.text == "]"
+	c := p.src[p.pos]
+	switch {
+	case c == '#' || c == '$' || c == '%' && !afterValue || isDigit(c, 10):
+		return p.readNumber()
+	case isIdentStart(c):
+		for p.pos < len(p.src) && (isIdentStart(p.src[p.pos]) || isDigit(p.src[p.pos], 10)) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.src[start:p.pos], pos: start}
+		return nil
+	}
+	for _, op := range []string{"||", "&&", "==", "!=", "<=", ">=", "<<", ">>"} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			p.pos += 2
+			p.tok = token{kind: tokOp, text: op, pos: start}
+			return nil
+		}
+	}
+	if strings.IndexByte("|^&<>+-*/%!~()[]", c) < 0 {
+		return fmt.Errorf("unexpected character %q at %d: %s", c, start+1, p.src)
+	}
+	p.pos++
+	p.tok = token{kind: tokOp, text: string(c), pos: start}
+	return nil
+}
+
+func (p *parser) readNumber() error {
+	start := p.pos
+	if p.src[p.pos] == '#' {
+		p.pos++
+	}
+	base := 10
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "$"):
+		base, p.pos = 16, p.pos+1
+	case strings.HasPrefix(p.src[p.pos:], "0x"), strings.HasPrefix(p.src[p.pos:], "0X"):
+		base, p.pos = 16, p.pos+2
+	case strings.HasPrefix(p.src[p.pos:], "%"):
+		base, p.pos = 2, p.pos+1
+	}
+	digits := p.pos
+	for p.pos < len(p.src) && (isDigit(p.src[p.pos], base) || p.src[p.pos] == '_') {
+		p.pos++
+	}
+	v, err := strconv.ParseInt(strings.ReplaceAll(p.src[digits:p.pos], "_", ""), base, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q at %d: %s", p.src[start:p.pos], start+1, p.src)
+	}
+	p.tok = token{kind: tokNumber, text: p.src[start:p.pos], value: int(v), pos: start}
+	return nil
+}
+
+func isDigit(c byte, base int) bool {
+	switch base {
+	case 2:
+		return c == '0' || c == '1'
+	case 16:
+		return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+	}
+	return '0' <= c && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,110 @@
+package expr
+
+import (
+	"testing"
+)
+
+// mapEnv is Env for tests. Memory is the low byte of address.
+type mapEnv map[string]int
+
+func (e mapEnv) Var(name string) (int, bool) {
+	v, ok := e[name]
+	return v, ok
+}
+
+func (e mapEnv) Peek(address uint16) byte {
+	return byte(address)
+}
+
+func TestExpr_Eval(t *testing.T) {
+	t.Parallel()
+	env := mapEnv{"a": 0x40, "x": 3, "pc": 0xC000, "scanline": 10, "hits": 5, "c": 1, "z": 0}
+	for _, tt := range []struct {
+		src  string
+		want int
+	}{
+		{"$40", 0x40},
+		{"#$40", 0x40},
+		{"0x40", 0x40},
+		{"%0100_0000", 0x40},
+		{"64", 64},
+		{"A", 0x40},
+		{"a == #$40", 1},
+		{"PC == $C000 && X != 3", 0},
+		{"[$0310]", 0x10},
+		{"[$0300 + X] > 2", 1},
+		{"A == #$40 && [$0303] > 3 && scanline < 20", 0},
+		{"A == #$40 && [$0304] > 3 && scanline < 20", 1},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"hits % 2", 1},
+		{"hits %10", 5},
+		{"7 / 2", 3},
+		{"1 << 4 | 1", 17},
+		{"$F0 & $3C ^ $FF", 0xCF},
+		{"!Z && C", 1},
+		{"-1 < 0", 1},
+		{"~0", -1},
+		{"0 || hits >= 5", 1},
+		{"1 <= 1 && 2 >= 3", 0},
+		// 短絡評価なので未定義の変数を評価しない
+		{"0 && frame", 0},
+		{"1 || frame", 1},
+	} {
+		tt := tt
+		t.Run(tt.src, func(t *testing.T) {
+			t.Parallel()
+			e, err := Compile(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := e.Eval(env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != got {
+				t.Errorf("want=%v, got=%v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompile_error(t *testing.T) {
+	t.Parallel()
+	for _, src := range []string{
+		"",
+		"A ==",
+		"foo == 1",
+		"(A == 1",
+		"[$0300",
+		"$GG",
+		"A = 1",
+		"1 2",
+	} {
+		src := src
+		t.Run(src, func(t *testing.T) {
+			t.Parallel()
+			if _, err := Compile(src); err == nil {
+				t.Errorf("Compile(%q) must fail", src)
+			}
+		})
+	}
+}
+
+func TestExpr_Eval_error(t *testing.T) {
+	t.Parallel()
+	for _, src := range []string{
+		"frame > 1", // envにない変数
+		"1 / 0",
+		"1 % (A - A)",
+	} {
+		src := src
+		t.Run(src, func(t *testing.T) {
+			t.Parallel()
+			if _, err := MustCompile(src).Eval(mapEnv{"a": 1}); err == nil {
+				t.Errorf("Eval(%q) must fail", src)
+			}
+		})
+	}
+}
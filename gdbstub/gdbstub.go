@@ -0,0 +1,472 @@
+// Package gdbstub serves the GDB remote serial protocol (RSP) for the 6502 CPU.
+//
+//	$ ./bin/gones -gdb localhost:2345
+//	(gdb) target remote localhost:2345
+//
+// Registers are a, x, y, p, sp (8 bits) and pc (16 bits, little endian) in this order.
+// The target description is served by qXfer:features:read:target.xml.
+package gdbstub
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yusukemisa/gones/debugger"
+)
+
+// packetSize is the maximum packet size reported to GDB.
+const packetSize = 0x1000
+
+const targetXML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.gnu.gdb.m6502.core">
+    <reg name="a" bitsize="8" type="uint8" regnum="0"/>
+    <reg name="x" bitsize="8" type="uint8"/>
+    <reg name="y" bitsize="8" type="uint8"/>
+    <reg name="p" bitsize="8" type="uint8"/>
+    <reg name="sp" bitsize="8" type="uint8"/>
+    <reg name="pc" bitsize="16" type="code_ptr"/>
+  </feature>
+</target>
+`
+
+// シグナル番号
+const (
+	sigINT  = 2
+	sigILL  = 4
+	sigTRAP = 5
+)
+
+// Server serves RSP sessions for a Debugger.
+type Server struct {
+	d *debugger.Debugger
+	// Z/zパケットの引数 "type,addr,kind" ごとのブレークポイント、ウォッチポイントのID
+	points map[string]int
+}
+
+// NewServer creates Server. Breakpoints and watchpoints set by GDB are added to d.
+func NewServer(d *debugger.Debugger) *Server {
+	return &Server{d: d, points: map[string]int{}}
+}
+
+// ListenAndServe listens on the TCP address and serves GDB connections one at a time.
+func ListenAndServe(address string, d *debugger.Debugger) error {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return NewServer(d).Serve(l)
+}
+
+// Serve accepts connections on l and serves them one at a time until l is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		err = s.ServeConn(conn)
+		conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// errKill is returned by handle when GDB kills the target.
+var errKill = errors.New("killed")
+
+// ServeConn serves a session until GDB detaches or the connection is closed.
+func (s *Server) ServeConn(conn io.ReadWriter) error {
+	c := &session{w: conn}
+	// 実行中だけCtrl-Cと切断でCPUを止める
+	interrupt := func() {
+		if c.running.Load() {
+			s.d.Interrupt()
+		}
+	}
+	packets := make(chan string)
+	done := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		err := c.readPackets(bufio.NewReader(conn), packets, done, interrupt)
+		// 切断されたら実行中のCPUを止める
+		interrupt()
+		errc <- err
+		close(packets)
+	}()
+	defer close(done)
+	defer s.clearPoints()
+
+	for packet := range packets {
+		reply, err := s.handle(c, packet)
+		if err == errKill {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.send(reply); err != nil {
+			return err
+		}
+		if packet == "D" {
+			return nil
+		}
+	}
+	if err := <-errc; err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// session is the connection state.
+type session struct {
+	mu      sync.Mutex
+	w       io.Writer
+	noAck   atomic.Bool
+	running atomic.Bool
+}
+
+func (c *session) write(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.w.Write(b)
+	return err
+}
+
+// send sends a packet "$data#checksum".
+func (c *session) send(data string) error {
+	return c.write([]byte(fmt.Sprintf("$%s#%02x", data, checksum(data))))
+}
+
+// readPackets sends received packets to ch until r fails or done is closed.
+// 0x03 (Ctrl-C) calls interrupt immediately because the main loop may be running the CPU.
+func (c *session) readPackets(r *bufio.Reader, ch chan<- string, done <-chan struct{}, interrupt func()) error {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case 0x03:
+			interrupt()
+			continue
+		case '$':
+		default:
+			// ACK(+/-)やゴミは読み飛ばす
+			continue
+		}
+		data, err := r.ReadString('#')
+		if err != nil {
+			return err
+		}
+		data = data[:len(data)-1]
+		var sum [2]byte
+		if _, err := io.ReadFull(r, sum[:]); err != nil {
+			return err
+		}
+		if !c.noAck.Load() {
+			ack := []byte{'+'}
+			if v, err := strconv.ParseUint(string(sum[:]), 16, 8); err != nil || byte(v) != checksum(data) {
+				ack[0] = '-'
+			}
+			if err := c.write(ack); err != nil {
+				return err
+			}
+			if ack[0] == '-' {
+				continue
+			}
+		}
+		select {
+		case ch <- data:
+		case <-done:
+			return nil
+		}
+	}
+}
+
+func checksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+// handle executes a packet and returns the reply. An empty reply means unsupported.
+func (s *Server) handle(c *session, packet string) (string, error) {
+	if packet == "" {
+		return "", nil
+	}
+	cmd, args := packet[0], packet[1:]
+	switch cmd {
+	case '?':
+		return fmt.Sprintf("S%02x", sigTRAP), nil
+	case 'g':
+		return hex.EncodeToString(s.registers()), nil
+	case 'G':
+		b, err := hex.DecodeString(args)
+		if err != nil || len(b) != 7 {
+			return "E01", nil
+		}
+		s.setRegisters(b)
+		return "OK", nil
+	case 'p':
+		n, err := strconv.ParseUint(args, 16, 8)
+		if err != nil || n > 5 {
+			return "E01", nil
+		}
+		regs := s.registers()
+		if n == 5 {
+			return hex.EncodeToString(regs[5:7]), nil
+		}
+		return hex.EncodeToString(regs[n : n+1]), nil
+	case 'P':
+		return s.writeRegister(args), nil
+	case 'm':
+		return s.readMemory(args), nil
+	case 'M':
+		return s.writeMemory(args), nil
+	case 's', 'c':
+		if args != "" {
+			pc, err := strconv.ParseUint(args, 16, 16)
+			if err != nil {
+				return "E01", nil
+			}
+			s.d.CPU().Register().PC = uint16(pc)
+		}
+		run := s.d.Continue
+		if cmd == 's' {
+			run = s.d.Step
+		}
+		c.running.Store(true)
+		stop := run()
+		c.running.Store(false)
+		return s.stopReply(stop), nil
+	case 'Z', 'z':
+		return s.point(cmd == 'Z', args), nil
+	case 'H', 'T':
+		// スレッドは1つだけ
+		return "OK", nil
+	case 'D':
+		return "OK", nil
+	case 'k':
+		return "", errKill
+	case 'q', 'Q':
+		return s.query(c, packet), nil
+	}
+	return "", nil
+}
+
+func (s *Server) query(c *session, packet string) string {
+	name, args, _ := strings.Cut(packet, ":")
+	switch name {
+	case "qSupported":
+		return fmt.Sprintf("PacketSize=%x;qXfer:features:read+;swbreak+;hwbreak+;QStartNoAckMode+", packetSize)
+	case "QStartNoAckMode":
+		// OKにはまだACKが返ってくる
+		c.noAck.Store(true)
+		return "OK"
+	case "qAttached":
+		return "1"
+	case "qC":
+		return "QC1"
+	case "qfThreadInfo":
+		return "m1"
+	case "qsThreadInfo":
+		return "l"
+	case "qSymbol":
+		return "OK"
+	case "qXfer":
+		// qXfer:features:read:target.xml:offset,length
+		const prefix = "features:read:target.xml:"
+		if !strings.HasPrefix(args, prefix) {
+			return "E00"
+		}
+		offset, length, err := parseRange(strings.TrimPrefix(args, prefix))
+		if err != nil {
+			return "E01"
+		}
+		if offset >= len(targetXML) {
+			return "l"
+		}
+		end := offset + length
+		if end >= len(targetXML) {
+			return "l" + targetXML[offset:]
+		}
+		return "m" + targetXML[offset:end]
+	}
+	return ""
+}
+
+// registers returns a, x, y, p, sp and pc in the order of the target description.
+func (s *Server) registers() []byte {
+	reg := s.d.CPU().Register()
+	return []byte{reg.A, reg.X, reg.Y, reg.P, reg.S, byte(reg.PC), byte(reg.PC >> 8)}
+}
+
+func (s *Server) setRegisters(b []byte) {
+	reg := s.d.CPU().Register()
+	reg.A, reg.X, reg.Y, reg.P, reg.S = b[0], b[1], b[2], b[3], b[4]
+	reg.PC = uint16(b[5]) | uint16(b[6])<<8
+}
+
+// writeRegister handles "P n=value".
+func (s *Server) writeRegister(args string) string {
+	n, value, ok := strings.Cut(args, "=")
+	i, err := strconv.ParseUint(n, 16, 8)
+	if !ok || err != nil || i > 5 {
+		return "E01"
+	}
+	b, err := hex.DecodeString(value)
+	if err != nil || (i < 5 && len(b) != 1) || (i == 5 && len(b) != 2) {
+		return "E01"
+	}
+	regs := s.registers()
+	copy(regs[i:], b)
+	s.setRegisters(regs)
+	return "OK"
+}
+
+// readMemory handles "m addr,length". Memory is read without side effects.
+func (s *Server) readMemory(args string) string {
+	addr, length, err := parseRange(args)
+	if err != nil || length > packetSize/2 {
+		return "E01"
+	}
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = s.d.CPU().Peek(uint16(addr + i))
+	}
+	return hex.EncodeToString(b)
+}
+
+// writeMemory handles "M addr,length:data". ROM mapped by a mapper is read only.
+func (s *Server) writeMemory(args string) string {
+	r, data, ok := strings.Cut(args, ":")
+	addr, length, err := parseRange(r)
+	if !ok || err != nil {
+		return "E01"
+	}
+	b, err := hex.DecodeString(data)
+	if err != nil || len(b) != length {
+		return "E01"
+	}
+	c := s.d.CPU()
+	for i := range b {
+		if c.Bank(uint16(addr+i)) >= 0 {
+			return "E0e" // EFAULT
+		}
+	}
+	for i, v := range b {
+		c.Poke(uint16(addr+i), v)
+	}
+	return "OK"
+}
+
+// point handles "Z type,addr,kind" and "z type,addr,kind".
+// 0, 1 are breakpoints and 2, 3, 4 are write, read and access watchpoints of kind bytes.
+func (s *Server) point(insert bool, args string) string {
+	parts := strings.Split(args, ",")
+	if len(parts) != 3 {
+		return "E01"
+	}
+	addr, length, err := parseRange(parts[1] + "," + parts[2])
+	if err != nil || addr > 0xFFFF {
+		return "E01"
+	}
+	if !insert {
+		id, ok := s.points[args]
+		if !ok {
+			return "OK"
+		}
+		delete(s.points, args)
+		if err := s.d.Delete(id); err != nil {
+			return "E01"
+		}
+		return "OK"
+	}
+	if _, ok := s.points[args]; ok {
+		return "OK"
+	}
+
+	wp := debugger.Watchpoint{Space: debugger.CPUSpace, From: uint16(addr), To: uint16(addr + length - 1)}
+	switch parts[0] {
+	case "0", "1":
+		s.points[args] = s.d.AddBreakpoint(debugger.Breakpoint{Address: uint16(addr), Bank: -1})
+		return "OK"
+	case "2":
+		wp.Kind = debugger.Write
+	case "3":
+		wp.Kind = debugger.Read
+	case "4":
+		wp.Kind = debugger.ReadWrite
+	default:
+		return ""
+	}
+	if length < 1 || addr+length-1 > 0xFFFF {
+		return "E01"
+	}
+	s.points[args] = s.d.AddWatchpoint(wp)
+	return "OK"
+}
+
+// clearPoints removes breakpoints and watchpoints set by the session.
+func (s *Server) clearPoints() {
+	for key, id := range s.points {
+		s.d.Delete(id)
+		delete(s.points, key)
+	}
+}
+
+// stopReply formats a stop reply packet.
+func (s *Server) stopReply(stop debugger.Stop) string {
+	switch stop.Reason {
+	case debugger.ReasonBreakpoint:
+		return fmt.Sprintf("T%02xswbreak:;", sigTRAP)
+	case debugger.ReasonWatchpoint:
+		kind := "awatch"
+		switch s.d.Watchpoints()[stop.ID].Kind {
+		case debugger.Write:
+			kind = "watch"
+		case debugger.Read:
+			kind = "rwatch"
+		}
+		return fmt.Sprintf("T%02x%s:%x;", sigTRAP, kind, stop.Access.Address)
+	case debugger.ReasonInterrupted:
+		return fmt.Sprintf("S%02x", sigINT)
+	case debugger.ReasonError:
+		return fmt.Sprintf("S%02x", sigILL)
+	}
+	return fmt.Sprintf("S%02x", sigTRAP)
+}
+
+// parseRange parses "addr,length" in hexadecimal.
+func parseRange(s string) (addr, length int, err error) {
+	a, l, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range: %s", s)
+	}
+	av, err := strconv.ParseUint(a, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	lv, err := strconv.ParseUint(l, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(av), int(lv), nil
+}
@@ -0,0 +1,186 @@
+package gdbstub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/yusukemisa/gones/asm"
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/debugger"
+)
+
+const program = `
+		.org $8000
+reset:	LDX #$00
+loop:	INX
+		STX $0200
+		JMP loop
+`
+
+// client is a minimal RSP client.
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// startServer serves program on a localhost port and connects to it.
+func startServer(t *testing.T) *client {
+	t.Helper()
+	var set []asm.Opcode
+	for _, op := range cpu.Opcodes() {
+		set = append(set, asm.Opcode(op))
+	}
+	p, err := asm.New(set).Assemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := &cpu.RAM{}
+	mem.Load(p.Origin, p.Bytes)
+	c := cpu.NewCPU(mem)
+	c.Register().PC = p.Origin
+	d := debugger.New(c, nil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- NewServer(d).Serve(l)
+	}()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		l.Close()
+		if err := <-served; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// request sends a packet and returns the reply.
+func (c *client) request(data string) string {
+	c.t.Helper()
+	c.send(data)
+	return c.reply()
+}
+
+func (c *client) send(data string) {
+	c.t.Helper()
+	if _, err := fmt.Fprintf(c.conn, "$%s#%02x", data, checksum(data)); err != nil {
+		c.t.Fatal(err)
+	}
+	if ack, err := c.r.ReadByte(); err != nil || ack != '+' {
+		c.t.Fatalf("ack: %q, %v", ack, err)
+	}
+}
+
+func (c *client) reply() string {
+	c.t.Helper()
+	if _, err := c.r.ReadString('$'); err != nil {
+		c.t.Fatal(err)
+	}
+	data, err := c.r.ReadString('#')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	data = data[:len(data)-1]
+	var sum [2]byte
+	if _, err := io.ReadFull(c.r, sum[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	if want := fmt.Sprintf("%02x", checksum(data)); want != string(sum[:]) {
+		c.t.Errorf("checksum: want=%s, got=%s", want, sum)
+	}
+	if _, err := c.conn.Write([]byte{'+'}); err != nil {
+		c.t.Fatal(err)
+	}
+	return data
+}
+
+func TestServer_session(t *testing.T) {
+	t.Parallel()
+	c := startServer(t)
+	for _, tt := range []struct {
+		request string
+		want    string
+	}{
+		{"qSupported:multiprocess+;swbreak+", "PacketSize=1000;qXfer:features:read+;swbreak+;hwbreak+;QStartNoAckMode+"},
+		{"vMustReplyEmpty", ""},
+		{"Hg0", "OK"},
+		{"?", "S05"},
+		{"qXfer:features:read:target.xml:0,15", "m<?xml version=\"1.0\"?>"},
+		{"qXfer:features:read:target.xml:1000,10", "l"},
+		// a x y p sp pc
+		{"g", "0000002000" + "0080"},
+		{"p5", "0080"},
+		{"s", "S05"},
+		{"s", "S05"},
+		{"g", "0001002000" + "0380"},
+		{"P0=40", "OK"},
+		{"p0", "40"},
+		{"G112233445506" + "80", "OK"},
+		{"g", "11223344550680"},
+		// 書き込み先の$0200を監視する
+		{"M0300,3:c0ffee", "OK"},
+		{"m0300,4", "c0ffee00"},
+		{"m8000,2", "a200"},
+		{"Z2,200,1", "OK"},
+		{"c", "T05watch:200;"},
+		{"m0200,1", "23"},
+		{"z2,200,1", "OK"},
+		{"Z0,8002,1", "OK"},
+		{"c8000", "T05swbreak:;"},
+		{"p5", "0280"},
+		{"z0,8002,1", "OK"},
+		{"z0,8002,1", "OK"},
+		{"Z5,8002,1", ""},
+		{"m0300", "E01"},
+		{"D", "OK"},
+	} {
+		if diff := cmp.Diff(tt.want, c.request(tt.request)); diff != "" {
+			t.Errorf("%s: reply mismatch (-want +got):\n%s", tt.request, diff)
+		}
+	}
+}
+
+func TestServer_interrupt(t *testing.T) {
+	t.Parallel()
+	c := startServer(t)
+	if want, got := "OK", c.request("QStartNoAckMode"); want != got {
+		t.Fatalf("QStartNoAckMode: want=%v, got=%v", want, got)
+	}
+
+	// NoAckモードではACKを送受信しない
+	if _, err := fmt.Fprintf(c.conn, "$c#%02x", checksum("c")); err != nil {
+		t.Fatal(err)
+	}
+	// 停止中のCtrl-Cは無視されるので止まるまで送り続ける
+	stopped := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stopped:
+				return
+			case <-time.After(10 * time.Millisecond):
+				c.conn.Write([]byte{0x03})
+			}
+		}
+	}()
+	reply := c.reply()
+	close(stopped)
+	if want, got := "S02", reply; want != got {
+		t.Errorf("stop reply: want=%v, got=%v", want, got)
+	}
+}
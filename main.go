@@ -1,41 +1,326 @@
 package main
 
 import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/yusukemisa/gones/bus"
+	"github.com/yusukemisa/gones/cdl"
 	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/dap"
+	"github.com/yusukemisa/gones/debugger"
+	"github.com/yusukemisa/gones/expr"
+	"github.com/yusukemisa/gones/gdbstub"
 	"github.com/yusukemisa/gones/joypad"
 	"github.com/yusukemisa/gones/ppu"
+	"github.com/yusukemisa/gones/profile"
 	"github.com/yusukemisa/gones/rom"
+	"github.com/yusukemisa/gones/symbols"
 )
 
 func main() {
-	f, err := os.Open("sample1.nes")
+	tracePath := flag.String("trace", "", "write CPU trace in nestest.log format to the file")
+	traceIf := flag.String("trace-if", "", "write only instructions where the expression is true, e.g. \"scanline < 20 && A == $40\"")
+	debug := flag.Bool("debug", false, "start with the debugger REPL")
+	symbolFiles := flag.String("symbols", "", "comma separated symbol files (.dbg, .nl, .mlb) used by disasm, trace and debugger")
+	gdbAddr := flag.String("gdb", "", "serve the GDB remote protocol on the address, e.g. localhost:2345")
+	profilePath := flag.String("profile", "", "write CPU profile on exit. pprof format if the file ends with .pb.gz, otherwise a text report")
+	cdlPath := flag.String("cdl", "", "log PRG and CHR bytes used as code or data to the FCEUX .cdl file. An existing file is merged")
+	profileIdle := flag.String("profile-idle", "", "exclude the address range from cycles per frame, e.g. \"C010-C015\" or \"wait_nmi\"")
+	flag.Parse()
+
+	// gones disasm rom.nes
+	if flag.Arg(0) == "disasm" {
+		if flag.NArg() != 2 {
+			log.Fatal("usage: gones disasm rom.nes")
+		}
+		w := bufio.NewWriter(os.Stdout)
+		defer w.Flush()
+		if err := disasm(w, flag.Arg(1), *symbolFiles); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// gones dap: 標準入出力でDebug Adapter Protocolを話す
+	if flag.Arg(0) == "dap" {
+		if err := dap.Serve(os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// gones [flags] rom.nes
+	if flag.NArg() != 1 {
+		log.Fatal("usage: gones [flags] rom.nes")
+	}
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var tracers []cpu.Tracer
+	rom, err := rom.NewRom(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ppu, err := ppu.NewPPU(rom.CHR, false)
 	if err != nil {
 		log.Fatal(err)
 	}
+	bus := bus.NewBus(rom, ppu)
+	cpu := cpu.NewCPU(bus)
+	syms, err := loadSymbols(*symbolFiles, len(rom.PRG))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *tracePath != "" {
+		tf, err := os.Create(*tracePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer tf.Close()
+		w := bufio.NewWriter(tf)
+		defer w.Flush()
+		tracer, err := newTracer(w, *traceIf, cpu, ppu, syms)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tracers = append(tracers, tracer)
+	}
+	if *profilePath != "" {
+		prof, err := newProfiler(*profileIdle, syms)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tracers = append(tracers, prof)
+		defer func() {
+			if err := writeProfile(*profilePath, prof); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+	if len(tracers) > 0 {
+		cpu.SetTracer(multiTracer(tracers))
+	}
+
+	// CDLはデバッガのフックの後ろにつなぐので先にデバッガを作る
+	var d *debugger.Debugger
+	if *debug || *gdbAddr != "" {
+		d = newDebugger(cpu, ppu, bus, syms)
+	}
+	if *cdlPath != "" {
+		logger, err := newCDL(*cdlPath, len(rom.PRG), len(rom.CHR))
+		if err != nil {
+			log.Fatal(err)
+		}
+		logger.Attach(cpu, ppu)
+		defer func() {
+			if err := saveCDL(*cdlPath, logger); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+
+	if *debug {
+		runDebugger(d)
+		return
+	}
+	if *gdbAddr != "" {
+		log.Printf("waiting for GDB on %s", *gdbAddr)
+		if err := gdbstub.ListenAndServe(*gdbAddr, d); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	run(cpu, ppu, bus, &joypad.Joypad{})
+}
+
+// loadSymbols loads comma separated symbol files. It returns nil if paths is empty.
+func loadSymbols(paths string, prgSize int) (*symbols.Table, error) {
+	if paths == "" {
+		return nil, nil
+	}
+	t := symbols.New()
+	for _, path := range strings.Split(paths, ",") {
+		if err := t.Load(path, prgSize); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// newTracer creates a nestest format tracer. If cond is not empty, only instructions where it is true are written.
+// Operand addresses are shown as labels if syms is not nil.
+func newTracer(w io.Writer, cond string, c *cpu.CPU, p *ppu.PPU, syms *symbols.Table) (cpu.Tracer, error) {
+	var opts []cpu.TraceOption
+	if syms != nil {
+		opts = append(opts, cpu.WithTraceLabels(syms.LabelFunc(c.Bank)))
+	}
+	if cond != "" {
+		e, err := expr.Compile(cond)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, cpu.WithTraceFilter(expr.TraceFilter(e, c, p)))
+	}
+	return cpu.NewTraceLogger(w, opts...), nil
+}
+
+// multiTracer combines tracers. localの変数cpuがパッケージを隠すのでここでまとめる
+func multiTracer(tracers []cpu.Tracer) cpu.Tracer {
+	if len(tracers) == 1 {
+		return tracers[0]
+	}
+	return cpu.MultiTracer(tracers...)
+}
+
+// newProfiler creates a profiler. idle is an address range like "C010-C015" where each end may be a label.
+func newProfiler(idle string, syms *symbols.Table) (*profile.Profiler, error) {
+	var opts []profile.Option
+	if syms != nil {
+		opts = append(opts, profile.WithSymbols(syms))
+	}
+	if idle != "" {
+		from, to, _ := strings.Cut(idle, "-")
+		if to == "" {
+			to = from
+		}
+		f, err := parseAddress(from, syms)
+		if err != nil {
+			return nil, err
+		}
+		t, err := parseAddress(to, syms)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, profile.WithIdle(f, t))
+	}
+	return profile.New(opts...), nil
+}
+
+// parseAddress parses a label or hexadecimal address such as "C000" and "$C000".
+func parseAddress(s string, syms *symbols.Table) (uint16, error) {
+	if syms != nil {
+		if loc, ok := syms.Address(s); ok {
+			return loc.Address, nil
+		}
+	}
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "$"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address: %s", s)
+	}
+	return uint16(v), nil
+}
+
+// writeProfile writes the profile to path. Files ending with .pb.gz are written in pprof format.
+func writeProfile(path string, prof *profile.Profiler) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(path, ".pb.gz") {
+		err = prof.WriteProfile(f)
+	} else {
+		err = prof.WriteReport(f, 20)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// newCDL creates a code/data logger merging the .cdl file at path if it exists.
+func newCDL(path string, prgSize, chrSize int) (*cdl.Logger, error) {
+	l := cdl.New(prgSize, chrSize)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := l.Load(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+func saveCDL(path string, l *cdl.Logger) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := l.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
 
-	rom := rom.NewRom(f)
-	ppu := ppu.NewPPU(rom.CHR, false)
-	cpu := cpu.NewCPU(bus.NewBus(rom, ppu))
+// runDebugger runs the debugger REPL on the terminal. Ctrl-C pauses the execution.
+func runDebugger(d *debugger.Debugger) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		for range sig {
+			d.Interrupt()
+		}
+	}()
 
-	run(cpu, ppu, &joypad.Joypad{})
+	if err := d.REPL(os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
 }
 
-func run(cpu *cpu.CPU, ppu *ppu.PPU, joyPad *joypad.Joypad) {
-	cpu.Reset()
+// newDebugger powers on the CPU and creates a debugger which presents the screen every frame.
+func newDebugger(cpu *cpu.CPU, ppu *ppu.PPU, bus *bus.Bus, syms *symbols.Table) *debugger.Debugger {
+	cpu.PowerOn()
+	opts := []debugger.Option{debugger.WithFrameHook(func() {
+		ppu.Canvas.Renderer.Present()
+		ppu.Canvas.Renderer.Clear()
+	}), debugger.WithIgnoredWrites(bus.IgnoredWrite)}
+	if syms != nil {
+		opts = append(opts, debugger.WithSymbols(syms))
+	}
+	return debugger.New(cpu, ppu, opts...)
+}
+
+func run(cpu *cpu.CPU, ppu *ppu.PPU, bus *bus.Bus, joyPad *joypad.Joypad) {
+	cpu.PowerOn()
 	for {
 		cycle := cpu.Run()
-		if screen := ppu.Run(cycle * 3); screen != nil {
+		// KILや未実装の命令ではCPUが止まる
+		if err := cpu.Err(); err != nil {
+			log.Print(err)
+			return
+		}
+		// PRG-ROMへの書き込みは無視して実行を続ける
+		if err := bus.IgnoredWrite(); err != nil {
+			log.Print(err)
+		}
+		screen := ppu.Run(cycle * 3)
+		if ppu.PollNMI() {
+			cpu.NMI()
+		}
+		if screen != nil {
 			ppu.Canvas.Renderer.Present()
 			ppu.Canvas.Renderer.Clear()
 			time.Sleep(100 * time.Microsecond)
 		}
 		if quit := joyPad.PollEvent(); quit {
-			println("Quit")
 			return
 		}
 	}
@@ -0,0 +1,63 @@
+package ppu
+
+// MemoryHook is called on every PPU memory access through PPUDATA($2007).
+type MemoryHook func(address uint16, data byte, write bool)
+
+// SetMemoryHook sets a hook for debuggers. nil removes the hook.
+func (p *PPU) SetMemoryHook(h MemoryHook) {
+	p.memoryHook = h
+}
+
+// MemoryHook returns the hook set by SetMemoryHook, or nil.
+func (p *PPU) MemoryHook() MemoryHook {
+	return p.memoryHook
+}
+
+// RenderHook is called when PPU fetches a 16 byte tile from the pattern table to render the background.
+type RenderHook func(address uint16)
+
+// SetRenderHook sets a hook for code/data loggers. nil removes the hook.
+// The hook is called also in headless mode.
+func (p *PPU) SetRenderHook(h RenderHook) {
+	p.renderHook = h
+}
+
+// Peek reads PPU memory without side effects.
+func (p *PPU) Peek(address uint16) byte {
+	if int(address) >= len(p.memory) {
+		return 0
+	}
+	return p.memory[address]
+}
+
+// OAM returns a copy of Object Attribute Memory.
+func (p *PPU) OAM() [0x100]byte {
+	return p.oam
+}
+
+// State is a snapshot of PPU registers for debuggers.
+type State struct {
+	CTRL, MASK, STATUS, SCROLL byte
+	Address                    uint16 // PPUADDR
+	Scanline, Dot              int
+	Frame                      uint64
+}
+
+// State returns the current registers and position.
+func (p *PPU) State() State {
+	return State{
+		CTRL:     p.register.CTRL,
+		MASK:     p.register.MASK,
+		STATUS:   p.register.STATUS,
+		SCROLL:   p.register.SCROLL,
+		Address:  p.address.get(),
+		Scanline: p.line,
+		Dot:      p.cycle,
+		Frame:    p.frame,
+	}
+}
+
+// Frame returns the number of frames rendered since power on.
+func (p *PPU) Frame() uint64 {
+	return p.frame
+}
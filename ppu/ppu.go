@@ -10,12 +10,18 @@ import (
 
 const (
 	windowWidth, windowHeight = 256, 240
+
+	// vblankScanLine is the scanline where vblank starts. NMI is raised at dot 1 of this line.
+	vblankScanLine = 241
+	// preRenderScanLine is the last scanline of a frame. vblank ends at dot 1 of this line.
+	preRenderScanLine = 261
 )
 
 type register struct {
 	// Control
 	CTRL   byte // 0x2000 割り込み
 	MASK   byte // 0x2001 背景イネーブル
+	STATUS byte // 0x2002 PPUステータス
 	SCROLL byte // 0x2005 背景スクロール
 
 	// Object Attribute Memory - スプライトに対応する空間
@@ -44,13 +50,15 @@ type Tile struct {
 	c    color.RGBA
 }
 
-func NewPPU(CHRROM []byte, debug bool) *PPU {
+// NewPPU creates PPU with CHR-ROM. If debug is true, it runs without the window.
+// An error is returned if the window cannot be created.
+func NewPPU(CHRROM []byte, debug bool) (*PPU, error) {
 	if debug {
 		return &PPU{
 			address:  &AddressRegister{},
 			memory:   append(CHRROM, make([]byte, 0x2000)...),
 			register: &register{},
-		}
+		}, nil
 	}
 	// Spriteの初期化
 	sprites := make(map[int][]byte)
@@ -78,7 +86,9 @@ func NewPPU(CHRROM []byte, debug bool) *PPU {
 	}
 
 	can := &canvas.SDL2Canvas{}
-	can.Setup("gones", windowWidth, windowHeight)
+	if err := can.Setup("gones", windowWidth, windowHeight); err != nil {
+		return nil, err
+	}
 
 	//printSprite(sprites[0x48])
 
@@ -88,7 +98,7 @@ func NewPPU(CHRROM []byte, debug bool) *PPU {
 		sprites:  sprites,
 		register: &register{},
 		Canvas:   can,
-	}
+	}, nil
 }
 
 func printSprite(sprite []byte) {
@@ -138,6 +148,9 @@ func (ar *AddressRegister) increment() {
 type PPU struct {
 	cycle int
 	line  int
+	frame uint64 // 電源投入から描画したフレーム数
+	// CPUへのNMI要求. PollNMIで取り出されるまで保持する
+	nmi bool
 	// CPUに読ませるのはこちらの内部バッファ.
 	// 直接PPUメモリやROMから読んだ内容にアクセスさせない
 	internalDataBuf byte
@@ -160,10 +173,16 @@ type PPU struct {
 	// 0x3F00～0x3F0F	0x0010	バックグラウンドパレット
 	// 0x3F10～0x3F1F	0x0010	スプライトパレット
 	// 0x3F20～0x3FFF	0x0040	0x3F00~0x3F1Fのミラー
-	memory  []byte
+	memory []byte
+	// Object Attribute Memory. 4byte x 64スプライト(Y, タイル番号, 属性, X)
+	oam     [0x100]byte
 	sprites map[int][]byte
 	tiles   []*Tile
 	Canvas  *canvas.SDL2Canvas
+
+	// デバッガー用のメモリアクセスの通知先
+	memoryHook MemoryHook
+	renderHook RenderHook
 }
 
 func (p *PPU) Read() byte {
@@ -172,13 +191,61 @@ func (p *PPU) Read() byte {
 
 	result := p.internalDataBuf
 	p.internalDataBuf = p.memory[addr]
+	if p.memoryHook != nil {
+		p.memoryHook(addr, p.internalDataBuf, false)
+	}
 	return result
 }
 
+// PowerOn initializes registers to the power-up state.
+// https://www.nesdev.org/wiki/PPU_power_up_state
+func (p *PPU) PowerOn() {
+	p.Reset()
+	p.register.STATUS = 0
+	p.address.set(0)
+	p.cycle, p.line, p.frame = 0, 0, 0
+}
+
+// Reset clears registers affected by the reset button.
+// PPUSTATUS and PPUADDR are not changed.
+func (p *PPU) Reset() {
+	p.register.CTRL = 0
+	p.register.MASK = 0
+	p.register.SCROLL = 0
+	p.address.lowShouldBeWrite = false
+	p.internalDataBuf = 0
+	p.nmi = false
+}
+
+// ReadStatus returns PPUSTATUS($2002).
+// 読み込むとvblankフラグとPPUADDRの書き込み順序がリセットされる
+func (p *PPU) ReadStatus() byte {
+	result := p.register.STATUS
+	p.register.STATUS = util.ClearBit(p.register.STATUS, 7)
+	p.address.lowShouldBeWrite = false
+	return result
+}
+
+// PeekStatus returns PPUSTATUS($2002) without clearing vblank flag.
+func (p *PPU) PeekStatus() byte {
+	return p.register.STATUS
+}
+
 func (p *PPU) WriteControl(data byte) {
+	// vblank中にNMIを有効にした場合もNMIが発生する
+	if !util.TestBit(p.register.CTRL, 7) && util.TestBit(data, 7) && util.TestBit(p.register.STATUS, 7) {
+		p.nmi = true
+	}
 	p.register.CTRL = data
 }
 
+// PollNMI reports whether PPU has raised NMI since the last call.
+func (p *PPU) PollNMI() bool {
+	nmi := p.nmi
+	p.nmi = false
+	return nmi
+}
+
 func (p *PPU) WriteMask(data byte) {
 	p.register.MASK = data
 }
@@ -191,14 +258,62 @@ func (p *PPU) WriteAddress(data byte) {
 	p.address.update(data)
 }
 
+// WriteOAMAddress writes OAMADDR($2003).
+func (p *PPU) WriteOAMAddress(data byte) {
+	p.register.OAMAddress = data
+}
+
+// WriteOAMData writes OAMDATA($2004) and increments OAMADDR.
+// OAM DMA($4014)もここを256回呼んで転送する
+func (p *PPU) WriteOAMData(data byte) {
+	p.oam[p.register.OAMAddress] = data
+	p.register.OAMAddress++
+}
+
+// ReadOAMData returns OAMDATA($2004). 書き込みと違いOAMADDRはインクリメントされない
+func (p *PPU) ReadOAMData() byte {
+	return p.oam[p.register.OAMAddress]
+}
+
 func (p *PPU) WriteData(data byte) {
 	addr := p.address.get()
 	p.memory[addr] = data
 	p.address.increment()
+	if p.memoryHook != nil {
+		p.memoryHook(addr, data, true)
+	}
+}
+
+// Position returns the current scanline and dot.
+func (p *PPU) Position() (scanline, dot int) {
+	return p.line, p.cycle
 }
 
 func (p *PPU) Run(cycle int) *Screen {
-	p.cycle += cycle
+	var screen *Screen
+	for i := 0; i < cycle; i++ {
+		if s := p.tick(); s != nil {
+			screen = s
+		}
+	}
+	return screen
+}
+
+// tick advances PPU by 1 dot.
+func (p *PPU) tick() *Screen {
+	p.cycle++
+	if p.cycle == 1 {
+		switch p.line {
+		case vblankScanLine:
+			// vblank開始. PPUCTRLのbit7が立っていればNMIを発生させる
+			p.register.STATUS = util.SetBit(p.register.STATUS, 7)
+			if util.TestBit(p.register.CTRL, 7) {
+				p.nmi = true
+			}
+		case preRenderScanLine:
+			p.register.STATUS = util.ClearBit(p.register.STATUS, 7)
+		}
+	}
 	if p.cycle >= 341 {
 		p.cycle -= 341
 		p.line++
@@ -209,6 +324,7 @@ func (p *PPU) Run(cycle int) *Screen {
 		}
 		if p.line == 262 {
 			p.line = 0
+			p.frame++
 			return &Screen{}
 		}
 	}
@@ -219,8 +335,21 @@ func (p *PPU) Run(cycle int) *Screen {
 func (p *PPU) buildBackGround(line int) {
 	// line=120; 0x1C0~0x1E0 14 * 32 = 448(1C0)
 	index := (line / 8) - 1
+	// PPUCTRLのbit4が立っていれば背景は$1000のパターンテーブルを使う
+	var patternTable uint16
+	if util.TestBit(p.register.CTRL, 4) {
+		patternTable = 0x1000
+	}
 	for i := 0; i < 0x20; i++ {
-		p.buildTile(0x20*index + i)
+		tileAddress := 0x20*index + i
+		if p.renderHook != nil && 0x2000+tileAddress < len(p.memory) {
+			// spritesはCHRの先頭から16byteごとに作っている
+			p.renderHook(patternTable + uint16(p.memory[0x2000+tileAddress])*0x10)
+		}
+		// debugモードでは描画しない
+		if p.Canvas != nil {
+			p.buildTile(tileAddress)
+		}
 	}
 }
 
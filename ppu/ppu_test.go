@@ -0,0 +1,150 @@
+package ppu
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPPU_NMI(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name     string
+		ctrl     byte
+		wantNMI  bool
+		wantFlag bool
+	}{
+		{
+			name:     "NMI enabled",
+			ctrl:     0b1000_0000,
+			wantNMI:  true,
+			wantFlag: true,
+		},
+		{
+			name:     "NMI disabled",
+			ctrl:     0b0000_0000,
+			wantNMI:  false,
+			wantFlag: true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPPU([]byte{}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.WriteControl(tt.ctrl)
+
+			// vblank直前(scanline 241, dot 0)まで進める
+			p.Run(vblankScanLine * 341)
+			if p.PollNMI() {
+				t.Fatal("NMI raised before vblank")
+			}
+
+			p.Run(1)
+			if want, got := tt.wantNMI, p.PollNMI(); want != got {
+				t.Errorf("NMI: want=%v, got=%v", want, got)
+			}
+			if p.PollNMI() {
+				t.Error("NMI should be cleared after PollNMI")
+			}
+			if want, got := tt.wantFlag, p.ReadStatus()&0x80 != 0; want != got {
+				t.Errorf("vblank flag: want=%v, got=%v", want, got)
+			}
+			if p.ReadStatus()&0x80 != 0 {
+				t.Error("vblank flag should be cleared after reading PPUSTATUS")
+			}
+		})
+	}
+}
+
+func TestPPU_WriteControl_NMI(t *testing.T) {
+	t.Parallel()
+
+	p, err := NewPPU([]byte{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Run(vblankScanLine*341 + 1)
+	if p.PollNMI() {
+		t.Fatal("NMI raised while disabled")
+	}
+
+	// vblank中にNMIを有効にするとNMIが発生する
+	p.WriteControl(0b1000_0000)
+	if !p.PollNMI() {
+		t.Error("NMI should be raised when enabled during vblank")
+	}
+}
+
+func TestPPU_Reset(t *testing.T) {
+	t.Parallel()
+
+	p, err := NewPPU([]byte{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.WriteControl(0b1000_0000)
+	p.WriteAddress(0x21)
+	p.Run(vblankScanLine*341 + 1)
+
+	// リセットではPPUSTATUSは変わらない
+	p.Reset()
+	if want, got := byte(0), p.register.CTRL; want != got {
+		t.Errorf("CTRL: want=%#02x, got=%#02x", want, got)
+	}
+	if p.address.lowShouldBeWrite {
+		t.Error("address latch should be cleared")
+	}
+	if p.register.STATUS&0x80 == 0 {
+		t.Error("vblank flag should be kept")
+	}
+
+	p.PowerOn()
+	if want, got := byte(0), p.register.STATUS; want != got {
+		t.Errorf("STATUS: want=%#02x, got=%#02x", want, got)
+	}
+}
+
+func TestPPU_SetRenderHook(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name string
+		ctrl byte
+		want map[uint16]bool
+	}{
+		{
+			name: "pattern table 0",
+			ctrl: 0b0000_0000,
+			want: map[uint16]bool{0x0050: true},
+		},
+		{
+			name: "pattern table 1",
+			ctrl: 0b0001_0000,
+			want: map[uint16]bool{0x1050: true},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := NewPPU(make([]byte, 0x2000), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// ネームテーブルをすべてタイル5にする
+			for i := 0x2000; i < 0x23C0; i++ {
+				p.memory[i] = 0x05
+			}
+			p.WriteControl(tt.ctrl)
+			got := map[uint16]bool{}
+			p.SetRenderHook(func(address uint16) {
+				got[address] = true
+			})
+
+			p.Run(341 * 262)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("rendered tiles mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/yusukemisa/gones/symbols"
+)
+
+// WriteProfile writes the profile in the gzipped protocol buffer format of pprof.
+// Sample values are instructions and cycles. Functions are subroutines, and lines are
+// source lines if the symbols have them.
+//
+//	$ go tool pprof -top -sample_index=cycles cpu.pb.gz
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	b := &profileBuilder{
+		p:         p,
+		strings:   map[string]uint64{"": 0},
+		table:     []string{""},
+		functions: map[string]uint64{},
+		locations: map[locationKey]uint64{},
+	}
+	var pb protobuf
+	for _, t := range []string{"instructions", "cycles"} {
+		pb.message(1, func(vt *protobuf) { // sample_type
+			vt.uint64(1, b.string(t))
+			vt.uint64(2, b.string("count"))
+		})
+	}
+	p.walk(func(n *node) {
+		for loc, c := range n.pcs {
+			stack := []uint64{b.location(n, loc)}
+			for m := n; m.parent != nil; m = m.parent {
+				stack = append(stack, b.location(m.parent, m.caller))
+			}
+			pb.message(2, func(s *protobuf) { // sample
+				s.packed(1, stack)
+				s.packed(2, []uint64{c.Instructions, c.Cycles})
+			})
+		}
+	})
+	pb.message(3, func(m *protobuf) { // mapping
+		m.uint64(1, 1)
+		m.uint64(3, 0x10000)
+		m.uint64(5, b.string("PRG"))
+		m.bool(7, true)
+		m.bool(9, p.symbols != nil)
+	})
+	pb.Write(b.body.Bytes())
+	for _, s := range b.table {
+		pb.string(6, s)
+	}
+	pb.message(11, func(vt *protobuf) { // period_type
+		vt.uint64(1, b.string("cycles"))
+		vt.uint64(2, b.string("count"))
+	})
+	pb.uint64(12, 1)
+	pb.uint64(14, b.string("cycles")) // default_sample_type
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(pb.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// profileBuilder builds locations and functions of a profile.
+// string_tableは他のメッセージの後に書くので、locationとfunctionはbodyに溜めておく
+type profileBuilder struct {
+	p         *Profiler
+	body      protobuf
+	strings   map[string]uint64
+	table     []string
+	functions map[string]uint64
+	locations map[locationKey]uint64
+}
+
+type locationKey struct {
+	function string
+	loc      symbols.Location
+}
+
+func (b *profileBuilder) string(s string) uint64 {
+	id, ok := b.strings[s]
+	if !ok {
+		id = uint64(len(b.table))
+		b.strings[s] = id
+		b.table = append(b.table, s)
+	}
+	return id
+}
+
+// location returns the ID of loc in the subroutine of n.
+// 同じアドレスでも属するサブルーチンが違えば別のlocationにする
+func (b *profileBuilder) location(n *node, loc symbols.Location) uint64 {
+	key := locationKey{b.p.functionName(n), loc}
+	if id, ok := b.locations[key]; ok {
+		return id
+	}
+	id := uint64(len(b.locations) + 1)
+	b.locations[key] = id
+	fn := b.function(n)
+	var line int
+	if b.p.symbols != nil {
+		if l, ok := b.p.symbols.Line(loc); ok {
+			line = l.Line
+		}
+	}
+	b.body.message(4, func(m *protobuf) { // location
+		m.uint64(1, id)
+		m.uint64(2, 1)
+		m.uint64(3, uint64(loc.Address))
+		m.message(4, func(l *protobuf) {
+			l.uint64(1, fn)
+			l.uint64(2, uint64(line))
+		})
+	})
+	return id
+}
+
+func (b *profileBuilder) function(n *node) uint64 {
+	name := b.p.functionName(n)
+	if id, ok := b.functions[name]; ok {
+		return id
+	}
+	id := uint64(len(b.functions) + 1)
+	b.functions[name] = id
+	var file symbols.Line
+	if b.p.symbols != nil && n != b.p.root {
+		file, _ = b.p.symbols.Line(n.entry)
+	}
+	b.body.message(5, func(m *protobuf) { // function
+		m.uint64(1, id)
+		m.uint64(2, b.string(name))
+		m.uint64(3, b.string(name))
+		m.uint64(4, b.string(file.File))
+		m.uint64(5, uint64(file.Line))
+	})
+	return id
+}
+
+// protobuf encodes fields of a protocol buffer message.
+// https://github.com/google/pprof/blob/main/proto/profile.proto の必要な分だけを書く
+type protobuf struct {
+	buf []byte
+}
+
+func (pb *protobuf) Bytes() []byte {
+	return pb.buf
+}
+
+func (pb *protobuf) Write(b []byte) {
+	pb.buf = append(pb.buf, b...)
+}
+
+func (pb *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		pb.buf = append(pb.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	pb.buf = append(pb.buf, byte(x))
+}
+
+// tag writes a field number and a wire type. 0はvarint, 2は長さ付き
+func (pb *protobuf) tag(field, wireType int) {
+	pb.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (pb *protobuf) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	pb.tag(field, 0)
+	pb.varint(x)
+}
+
+func (pb *protobuf) bool(field int, b bool) {
+	if b {
+		pb.uint64(field, 1)
+	}
+}
+
+// string writes s even if it is empty because string_table needs "" at index 0.
+func (pb *protobuf) string(field int, s string) {
+	pb.tag(field, 2)
+	pb.varint(uint64(len(s)))
+	pb.buf = append(pb.buf, s...)
+}
+
+func (pb *protobuf) packed(field int, xs []uint64) {
+	var p protobuf
+	for _, x := range xs {
+		p.varint(x)
+	}
+	pb.tag(field, 2)
+	pb.varint(uint64(len(p.buf)))
+	pb.buf = append(pb.buf, p.buf...)
+}
+
+func (pb *protobuf) message(field int, f func(*protobuf)) {
+	var m protobuf
+	f(&m)
+	pb.tag(field, 2)
+	pb.varint(uint64(len(m.buf)))
+	pb.buf = append(pb.buf, m.buf...)
+}
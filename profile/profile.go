@@ -0,0 +1,325 @@
+// Package profile measures where CPU spends cycles.
+//
+// Profiler is a cpu.Tracer. It counts executed instructions and cycles per PC,
+// per subroutine called by JSR (and interrupt handler), and per frame.
+// The result is written as a text report or a pprof profile:
+//
+//	$ ./bin/gones -symbols game.dbg -profile cpu.pb.gz
+//	$ go tool pprof -top cpu.pb.gz
+package profile
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/symbols"
+)
+
+// Counter is the number of executed instructions and cycles.
+type Counter struct {
+	Instructions uint64
+	Cycles       uint64
+}
+
+func (c *Counter) add(o Counter) {
+	c.Instructions += o.Instructions
+	c.Cycles += o.Cycles
+}
+
+// Profiler is a cpu.Tracer counting instructions and cycles.
+//
+// Cycles of an instruction are the cycles until the next instruction starts,
+// so an interrupt sequence and a DMA stall are counted on the instruction before them.
+// The last traced instruction has no cycles.
+type Profiler struct {
+	symbols *symbols.Table
+	idle    []addressRange
+
+	root *node
+	cur  *node // 実行中のサブルーチン
+
+	prev     *cpu.TraceState
+	prevNode *node
+
+	frameStarted bool
+	frameStart   uint64
+	frameIdle    uint64   // 現在のフレームでidleなPCで使ったcycle数
+	frames       []uint64 // フレームごとのidleを除いたcycle数
+}
+
+type addressRange struct {
+	from, to uint16
+}
+
+// node is a subroutine in the call tree.
+type node struct {
+	parent    *node
+	caller    symbols.Location // 呼び出したJSRのアドレス. 割り込みの場合は割り込まれた命令
+	entry     symbols.Location
+	interrupt bool
+	sp        byte // 呼び出し前のスタックポインタ
+	calls     uint64
+
+	children map[callSite]*node
+	pcs      map[symbols.Location]*Counter
+}
+
+type callSite struct {
+	caller, entry symbols.Location
+	interrupt     bool
+}
+
+func newNode(parent *node) *node {
+	return &node{
+		parent:   parent,
+		children: map[callSite]*node{},
+		pcs:      map[symbols.Location]*Counter{},
+	}
+}
+
+// child returns the node called from n at site, creating it if needed.
+func (n *node) child(site callSite, sp byte) *node {
+	c, ok := n.children[site]
+	if !ok {
+		c = newNode(n)
+		c.caller, c.entry, c.interrupt = site.caller, site.entry, site.interrupt
+		n.children[site] = c
+	}
+	c.sp = sp
+	return c
+}
+
+func (n *node) counter(loc symbols.Location) *Counter {
+	c, ok := n.pcs[loc]
+	if !ok {
+		c = &Counter{}
+		n.pcs[loc] = c
+	}
+	return c
+}
+
+// Option is an option of Profiler.
+type Option func(*Profiler)
+
+// WithSymbols names subroutines by labels in t.
+func WithSymbols(t *symbols.Table) Option {
+	return func(p *Profiler) {
+		p.symbols = t
+	}
+}
+
+// WithIdle marks PCs from `from` to `to` (inclusive) as idle, e.g. a loop waiting for NMI.
+// Cycles of idle PCs are not counted in cycles per frame.
+func WithIdle(from, to uint16) Option {
+	return func(p *Profiler) {
+		p.idle = append(p.idle, addressRange{from, to})
+	}
+}
+
+// New creates a Profiler.
+func New(opts ...Option) *Profiler {
+	p := &Profiler{root: newNode(nil)}
+	p.cur = p.root
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Trace implements cpu.Tracer.
+func (p *Profiler) Trace(s *cpu.TraceState) {
+	loc := symbols.Location{Bank: s.Bank, Address: s.PC}
+	if prev := p.prev; prev != nil {
+		prevLoc := symbols.Location{Bank: prev.Bank, Address: prev.PC}
+		cycles := s.Cycles - prev.Cycles
+		p.prevNode.counter(prevLoc).Cycles += cycles
+		if p.isIdle(prev.PC) {
+			p.frameIdle += cycles
+		}
+
+		// scanlineが戻ったら次のフレーム. 最初のフレームは途中から数えているので捨てる
+		if s.Scanline < prev.Scanline {
+			if p.frameStarted {
+				p.frames = append(p.frames, s.Cycles-p.frameStart-p.frameIdle)
+			}
+			p.frameStarted, p.frameStart, p.frameIdle = true, s.Cycles, 0
+		}
+
+		// 呼び出し前のスタックポインタまで戻ったサブルーチンを抜ける. RTS, RTIの他TXSで捨てられた場合も含む
+		for p.cur != p.root && p.cur.sp <= s.S {
+			p.cur = p.cur.parent
+		}
+		// JSRの直後に割り込まれた場合は割り込みハンドラだけを数える
+		switch {
+		case s.Interrupts != prev.Interrupts:
+			// 割り込みシーケンスが積んだ3byteの前まで戻ったら抜ける
+			p.cur = p.cur.child(callSite{caller: prevLoc, entry: loc, interrupt: true}, s.S+3)
+			p.cur.calls++
+		case prev.Instruction.Name == "JSR" && s.S == prev.S-2:
+			p.cur = p.cur.child(callSite{caller: prevLoc, entry: loc}, prev.S)
+			p.cur.calls++
+		}
+	}
+	p.cur.counter(loc).Instructions++
+	p.prev, p.prevNode = s, p.cur
+}
+
+func (p *Profiler) isIdle(pc uint16) bool {
+	for _, r := range p.idle {
+		if r.from <= pc && pc <= r.to {
+			return true
+		}
+	}
+	return false
+}
+
+// Total returns the number of all traced instructions and their cycles.
+func (p *Profiler) Total() Counter {
+	var total Counter
+	p.walk(func(n *node) {
+		for _, c := range n.pcs {
+			total.add(*c)
+		}
+	})
+	return total
+}
+
+// Frames returns cycles of each completed frame except idle PCs.
+func (p *Profiler) Frames() []uint64 {
+	return append([]uint64(nil), p.frames...)
+}
+
+// PC is the profile of an address.
+type PC struct {
+	Location symbols.Location
+	Counter
+}
+
+// PCs returns the profile of each executed address in descending order of cycles.
+func (p *Profiler) PCs() []PC {
+	pcs := map[symbols.Location]*Counter{}
+	p.walk(func(n *node) {
+		for loc, c := range n.pcs {
+			if _, ok := pcs[loc]; !ok {
+				pcs[loc] = &Counter{}
+			}
+			pcs[loc].add(*c)
+		}
+	})
+	result := make([]PC, 0, len(pcs))
+	for loc, c := range pcs {
+		result = append(result, PC{Location: loc, Counter: *c})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Cycles != b.Cycles {
+			return a.Cycles > b.Cycles
+		}
+		return less(a.Location, b.Location)
+	})
+	return result
+}
+
+// Function is the profile of a subroutine or an interrupt handler.
+type Function struct {
+	Name string
+	// Entry is the address called by JSR or the interrupt handler. The code before the first call has no entry.
+	Entry     *symbols.Location
+	Interrupt bool
+	Calls     uint64
+	Self      Counter // サブルーチン内の命令だけ
+	Total     Counter // 呼び出したサブルーチンを含む
+}
+
+// Functions returns the profile of each subroutine in descending order of total cycles.
+func (p *Profiler) Functions() []Function {
+	funcs := map[string]*Function{}
+	var visit func(n *node, active map[string]bool) Counter
+	visit = func(n *node, active map[string]bool) Counter {
+		name := p.functionName(n)
+		f, ok := funcs[name]
+		if !ok {
+			f = &Function{Name: name, Interrupt: n.interrupt}
+			if n != p.root {
+				entry := n.entry
+				f.Entry = &entry
+			}
+			funcs[name] = f
+		}
+		f.Calls += n.calls
+
+		var total Counter
+		for _, c := range n.pcs {
+			total.add(*c)
+		}
+		f.Self.add(total)
+		recursive := active[name]
+		active[name] = true
+		for _, c := range n.children {
+			total.add(visit(c, active))
+		}
+		// 再帰呼び出しは外側の呼び出しに含まれているので二重に数えない
+		if !recursive {
+			delete(active, name)
+			f.Total.add(total)
+		}
+		return total
+	}
+	visit(p.root, map[string]bool{})
+
+	result := make([]Function, 0, len(funcs))
+	for _, f := range funcs {
+		result = append(result, *f)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Total.Cycles != b.Total.Cycles {
+			return a.Total.Cycles > b.Total.Cycles
+		}
+		return a.Name < b.Name
+	})
+	return result
+}
+
+// walk calls f for all nodes of the call tree.
+func (p *Profiler) walk(f func(n *node)) {
+	var visit func(n *node)
+	visit = func(n *node) {
+		f(n)
+		for _, c := range n.children {
+			visit(c)
+		}
+	}
+	visit(p.root)
+}
+
+// rootName is the function name of the code running before any call, e.g. the main loop after reset.
+const rootName = "(root)"
+
+func (p *Profiler) functionName(n *node) string {
+	if n == p.root {
+		return rootName
+	}
+	return p.name(n.entry)
+}
+
+// name returns the label of loc or "$C123", with the bank like "1:$C123" if it is known.
+func (p *Profiler) name(loc symbols.Location) string {
+	if p.symbols != nil {
+		if name, ok := p.symbols.Label(loc); ok {
+			return name
+		}
+	}
+	if loc.Bank < 0 {
+		return fmt.Sprintf("$%04X", loc.Address)
+	}
+	return fmt.Sprintf("%d:$%04X", loc.Bank, loc.Address)
+}
+
+func less(a, b symbols.Location) bool {
+	if a.Bank != b.Bank {
+		return a.Bank < b.Bank
+	}
+	return a.Address < b.Address
+}
@@ -0,0 +1,450 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/yusukemisa/gones/asm"
+	"github.com/yusukemisa/gones/bus"
+	"github.com/yusukemisa/gones/cpu"
+	"github.com/yusukemisa/gones/ppu"
+	"github.com/yusukemisa/gones/rom"
+	"github.com/yusukemisa/gones/symbols"
+)
+
+// program waits for NMI in the main loop and calls work in the NMI handler.
+// workは1回あたり LDX 2 + DEX 2*10 + BNE 3*9+2 + RTS 6 = 57cycle
+const program = `
+		.org $8000
+reset:	LDA #$80
+		STA $2000 ; NMIを有効にする
+wait:	JMP wait
+nmi:	JSR work
+		RTI
+work:	LDX #$0A
+loop:	DEX
+		BNE loop
+		RTS
+		.org $FFFA
+		.word nmi, reset, reset
+`
+
+// profile runs program with Profiler until frames are completed.
+func profile(t *testing.T, frames int, opts ...Option) *Profiler {
+	t.Helper()
+	var set []asm.Opcode
+	for _, op := range cpu.Opcodes() {
+		set = append(set, asm.Opcode(op))
+	}
+	a, err := asm.New(set).Assemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prg := make([]byte, 0x8000)
+	copy(prg[a.Origin-0x8000:], a.Bytes)
+
+	prof := New(opts...)
+	p, err := ppu.NewPPU(make([]byte, 0x2000), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cpu.NewCPU(bus.NewBus(&rom.Rom{PRG: prg}, p), cpu.WithTracer(prof))
+	c.PowerOn()
+	for len(prof.Frames()) < frames {
+		p.Run(c.Run() * 3)
+		if p.PollNMI() {
+			c.NMI()
+		}
+	}
+	return prof
+}
+
+func newSymbols() *symbols.Table {
+	t := symbols.New()
+	t.AddLabel("nmi", symbols.Location{Bank: 0, Address: 0x8008})
+	t.AddLabel("work", symbols.Location{Bank: 0, Address: 0x800C})
+	return t
+}
+
+func TestProfiler_Functions(t *testing.T) {
+	t.Parallel()
+	prof := profile(t, 3, WithSymbols(newSymbols()))
+
+	funcs := map[string]Function{}
+	for _, f := range prof.Functions() {
+		funcs[f.Name] = f
+	}
+	calls := funcs["work"].Calls
+	if calls < 3 {
+		t.Fatalf("work must be called every frame: calls=%d", calls)
+	}
+	for _, want := range []Function{
+		{
+			Name:  "work",
+			Entry: &symbols.Location{Bank: 0, Address: 0x800C},
+			Calls: calls,
+			Self:  Counter{Instructions: 22 * calls, Cycles: 57 * calls},
+			Total: Counter{Instructions: 22 * calls, Cycles: 57 * calls},
+		},
+		{
+			// JSR 6 + RTI 6. 割り込みシーケンスの7cycleは割り込まれた命令に数える
+			Name:      "nmi",
+			Entry:     &symbols.Location{Bank: 0, Address: 0x8008},
+			Interrupt: true,
+			Calls:     calls,
+			Self:      Counter{Instructions: 2 * calls, Cycles: 12 * calls},
+			Total:     Counter{Instructions: 24 * calls, Cycles: 69 * calls},
+		},
+	} {
+		if diff := cmp.Diff(want, funcs[want.Name]); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", want.Name, diff)
+		}
+	}
+	if root := funcs[rootName]; root.Entry != nil || root.Total != prof.Total() {
+		t.Errorf("root must include all: root=%+v, total=%+v", root, prof.Total())
+	}
+}
+
+func TestProfiler_PCs(t *testing.T) {
+	t.Parallel()
+	prof := profile(t, 3)
+
+	pcs := map[symbols.Location]Counter{}
+	var sum Counter
+	for _, pc := range prof.PCs() {
+		pcs[pc.Location] = pc.Counter
+		sum.add(pc.Counter)
+	}
+	if diff := cmp.Diff(prof.Total(), sum); diff != "" {
+		t.Errorf("sum of PCs mismatch (-want +got):\n%s", diff)
+	}
+	// DEXは1回の呼び出しで10回
+	dex, ldx := pcs[symbols.Location{Bank: 0, Address: 0x800E}], pcs[symbols.Location{Bank: 0, Address: 0x800C}]
+	if want, got := ldx.Instructions*10, dex.Instructions; want != got {
+		t.Errorf("DEX instructions: want=%d, got=%d", want, got)
+	}
+	if want, got := dex.Instructions*2, dex.Cycles; want != got {
+		t.Errorf("DEX cycles: want=%d, got=%d", want, got)
+	}
+	// 待ちループが最も多い
+	if want, got := (symbols.Location{Bank: 0, Address: 0x8005}), prof.PCs()[0].Location; want != got {
+		t.Errorf("hottest PC: want=%v, got=%v", want, got)
+	}
+}
+
+func TestProfiler_Frames(t *testing.T) {
+	t.Parallel()
+	// NTSCの1フレームは341*262/3 = 29780.67cycle. 区切りは命令単位なのでJMPの3cycleずれる
+	for _, f := range profile(t, 3).Frames() {
+		if f < 29778 || 29783 < f {
+			t.Errorf("cycles per frame: got=%d", f)
+		}
+	}
+
+	// 待ちループを除くとNMIの処理だけが残る
+	want := []uint64{69, 69, 69}
+	if diff := cmp.Diff(want, profile(t, 3, WithIdle(0x8005, 0x8007)).Frames()); diff != "" {
+		t.Errorf("busy cycles per frame mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestProfiler_Trace_interrupt(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		interrupts uint64 // 2命令目の前までに入った割り込みの回数
+		want       []Function
+	}{
+		// TXSでSが3下がっても割り込みではない
+		"TXS": {
+			interrupts: 0,
+			want: []Function{
+				{Name: rootName, Self: Counter{Instructions: 4, Cycles: 6}, Total: Counter{Instructions: 4, Cycles: 6}},
+			},
+		},
+		"NMI": {
+			interrupts: 1,
+			want: []Function{
+				{Name: rootName, Self: Counter{Instructions: 1, Cycles: 2}, Total: Counter{Instructions: 4, Cycles: 6}},
+				{
+					Name:      "0:$9000",
+					Entry:     &symbols.Location{Bank: 0, Address: 0x9000},
+					Interrupt: true,
+					Calls:     1,
+					Self:      Counter{Instructions: 3, Cycles: 4},
+					Total:     Counter{Instructions: 3, Cycles: 4},
+				},
+			},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			// 最後の命令は実行前なのでcycleはまだ数えない
+			prof := New()
+			for _, s := range []cpu.TraceState{
+				{PC: 0x8000, Instruction: cpu.Instruction{Name: "TXS"}, S: 0xFD},
+				{PC: 0x9000, Instruction: cpu.Instruction{Name: "NOP"}, S: 0xFA, Cycles: 2, Interrupts: tt.interrupts},
+				{PC: 0x9001, Instruction: cpu.Instruction{Name: "NOP"}, S: 0xFA, Cycles: 4, Interrupts: tt.interrupts},
+				{PC: 0x9002, Instruction: cpu.Instruction{Name: "NOP"}, S: 0xFA, Cycles: 6, Interrupts: tt.interrupts},
+			} {
+				s := s
+				prof.Trace(&s)
+			}
+			if diff := cmp.Diff(tt.want, prof.Functions()); diff != "" {
+				t.Errorf("functions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProfiler_WriteReport(t *testing.T) {
+	t.Parallel()
+	prof := profile(t, 3, WithSymbols(newSymbols()), WithIdle(0x8005, 0x8007))
+	var buf bytes.Buffer
+	if err := prof.WriteReport(&buf, 3); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, want := range []string{
+		"top 3 functions\n",
+		" nmi <interrupt>\n",
+		" work\n",
+		"$8005 bank 0\n",
+		"$800E bank 0\n",
+		"cycles per frame\n    0-999    ######################################## 3\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report must contain %q:\n%s", want, report)
+		}
+	}
+	if got := strings.Count(report, " bank 0"); got != 3 {
+		t.Errorf("PCs must be top 3: got=%d\n%s", got, report)
+	}
+}
+
+func TestProfiler_WriteProfile(t *testing.T) {
+	t.Parallel()
+	prof := profile(t, 1, WithSymbols(newSymbols()))
+	var buf bytes.Buffer
+	if err := prof.WriteProfile(&buf); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := decodeProfile(t, b)
+
+	if diff := cmp.Diff([]string{"instructions", "cycles"}, p.sampleTypes); diff != "" {
+		t.Errorf("sample types mismatch (-want +got):\n%s", diff)
+	}
+	// サンプルのスタックを関数名にして、末端の関数ごとに値を合計する
+	var total Counter
+	self := map[string]Counter{}
+	stacks := map[string][]string{}
+	for _, s := range p.samples {
+		var names []string
+		for _, id := range s.locations {
+			loc, ok := p.locations[id]
+			if !ok {
+				t.Fatalf("location %d not found", id)
+			}
+			name, ok := p.functions[loc.function]
+			if !ok {
+				t.Fatalf("function %d not found", loc.function)
+			}
+			names = append(names, name)
+		}
+		if len(s.values) != 2 {
+			t.Fatalf("values: %v", s.values)
+		}
+		c := Counter{Instructions: s.values[0], Cycles: s.values[1]}
+		total.add(c)
+		leaf := self[names[0]]
+		leaf.add(c)
+		self[names[0]] = leaf
+		stacks[names[0]] = names
+	}
+	if diff := cmp.Diff(prof.Total(), total); diff != "" {
+		t.Errorf("sum of samples mismatch (-want +got):\n%s", diff)
+	}
+	for _, f := range prof.Functions() {
+		if diff := cmp.Diff(f.Self, self[f.Name]); diff != "" {
+			t.Errorf("%s: self mismatch (-want +got):\n%s", f.Name, diff)
+		}
+	}
+	if diff := cmp.Diff([]string{"work", "nmi", rootName}, stacks["work"]); diff != "" {
+		t.Errorf("stack mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// decodedProfile is the part of profile.proto written by WriteProfile.
+type decodedProfile struct {
+	sampleTypes []string
+	samples     []decodedSample
+	locations   map[uint64]decodedLocation
+	functions   map[uint64]string // IDから関数名
+}
+
+type decodedSample struct {
+	locations []uint64
+	values    []uint64
+}
+
+type decodedLocation struct {
+	address  uint64
+	function uint64
+}
+
+// protoField is a field of a protocol buffer message. varint or length-delimited bytes.
+type protoField struct {
+	number int
+	varint uint64
+	bytes  []byte
+}
+
+// decodeFields decodes fields of wire type 0 and 2, which are all that WriteProfile uses.
+func decodeFields(t *testing.T, b []byte) []protoField {
+	t.Helper()
+	var fields []protoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("invalid key: %x", b)
+		}
+		b = b[n:]
+		f := protoField{number: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			f.varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("invalid varint: %x", b)
+			}
+			b = b[n:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				t.Fatalf("invalid length: %x", b)
+			}
+			f.bytes = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type: %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func decodePacked(t *testing.T, b []byte) []uint64 {
+	t.Helper()
+	var xs []uint64
+	for len(b) > 0 {
+		x, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("invalid packed varint: %x", b)
+		}
+		xs = append(xs, x)
+		b = b[n:]
+	}
+	return xs
+}
+
+func decodeProfile(t *testing.T, b []byte) *decodedProfile {
+	t.Helper()
+	p := &decodedProfile{locations: map[uint64]decodedLocation{}, functions: map[uint64]string{}}
+	var table []string
+	var sampleTypes []uint64
+	functionNames := map[uint64]uint64{}
+	for _, f := range decodeFields(t, b) {
+		switch f.number {
+		case 1: // sample_type
+			for _, vt := range decodeFields(t, f.bytes) {
+				if vt.number == 1 {
+					sampleTypes = append(sampleTypes, vt.varint)
+				}
+			}
+		case 2: // sample
+			var s decodedSample
+			for _, sf := range decodeFields(t, f.bytes) {
+				switch sf.number {
+				case 1:
+					s.locations = decodePacked(t, sf.bytes)
+				case 2:
+					s.values = decodePacked(t, sf.bytes)
+				}
+			}
+			p.samples = append(p.samples, s)
+		case 4: // location
+			var id uint64
+			var loc decodedLocation
+			for _, lf := range decodeFields(t, f.bytes) {
+				switch lf.number {
+				case 1:
+					id = lf.varint
+				case 3:
+					loc.address = lf.varint
+				case 4: // line
+					for _, l := range decodeFields(t, lf.bytes) {
+						if l.number == 1 {
+							loc.function = l.varint
+						}
+					}
+				}
+			}
+			p.locations[id] = loc
+		case 5: // function
+			var id, name uint64
+			for _, ff := range decodeFields(t, f.bytes) {
+				switch ff.number {
+				case 1:
+					id = ff.varint
+				case 2:
+					name = ff.varint
+				}
+			}
+			functionNames[id] = name
+		case 6: // string_table
+			table = append(table, string(f.bytes))
+		}
+	}
+	str := func(i uint64) string {
+		if i >= uint64(len(table)) {
+			t.Fatalf("string %d not found", i)
+		}
+		return table[i]
+	}
+	for _, i := range sampleTypes {
+		p.sampleTypes = append(p.sampleTypes, str(i))
+	}
+	for id, name := range functionNames {
+		p.functions[id] = str(name)
+	}
+	return p
+}
+
+func TestProtobuf(t *testing.T) {
+	t.Parallel()
+	var pb protobuf
+	pb.uint64(1, 150)
+	pb.uint64(2, 0) // 0は省略する
+	pb.string(3, "")
+	pb.packed(4, []uint64{3, 270})
+	pb.message(5, func(m *protobuf) {
+		m.bool(1, true)
+	})
+	want := []byte{0x08, 0x96, 0x01, 0x1A, 0x00, 0x22, 0x03, 0x03, 0x8E, 0x02, 0x2A, 0x02, 0x08, 0x01}
+	if diff := cmp.Diff(want, pb.Bytes()); diff != "" {
+		t.Errorf("protobuf mismatch (-want +got):\n%s", diff)
+	}
+}
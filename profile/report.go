@@ -0,0 +1,92 @@
+package profile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// frameBucket is the width of the histogram of cycles per frame.
+const frameBucket = 1000
+
+// WriteReport writes top n functions and PCs, and the histogram of cycles per frame.
+func (p *Profiler) WriteReport(w io.Writer, n int) error {
+	bw := bufio.NewWriter(w)
+	total := p.Total()
+	frames := p.Frames()
+	fmt.Fprintf(bw, "total: %d instructions, %d cycles, %d frames\n", total.Instructions, total.Cycles, len(frames))
+
+	fmt.Fprintf(bw, "\ntop %d functions\n", n)
+	fmt.Fprintf(bw, "%7s %12s %7s %12s %8s  %s\n", "self%", "self", "total%", "total", "calls", "function")
+	for i, f := range p.Functions() {
+		if i == n {
+			break
+		}
+		name := f.Name
+		if f.Interrupt {
+			name += " <interrupt>"
+		}
+		fmt.Fprintf(bw, "%6.2f%% %12d %6.2f%% %12d %8d  %s\n",
+			percent(f.Self.Cycles, total.Cycles), f.Self.Cycles,
+			percent(f.Total.Cycles, total.Cycles), f.Total.Cycles, f.Calls, name)
+	}
+
+	fmt.Fprintf(bw, "\ntop %d PCs\n", n)
+	fmt.Fprintf(bw, "%7s %12s %12s  %s\n", "cycles%", "cycles", "instructions", "address")
+	for i, pc := range p.PCs() {
+		if i == n {
+			break
+		}
+		addr := fmt.Sprintf("$%04X", pc.Location.Address)
+		if pc.Location.Bank >= 0 {
+			addr += fmt.Sprintf(" bank %d", pc.Location.Bank)
+		}
+		if p.symbols != nil {
+			if name, ok := p.symbols.Label(pc.Location); ok {
+				addr += " " + name
+			}
+		}
+		fmt.Fprintf(bw, "%6.2f%% %12d %12d  %s\n", percent(pc.Cycles, total.Cycles), pc.Cycles, pc.Instructions, addr)
+	}
+
+	if len(frames) > 0 {
+		fmt.Fprintf(bw, "\ncycles per frame\n")
+		writeHistogram(bw, frames)
+	}
+	return bw.Flush()
+}
+
+// writeHistogram writes the number of frames per frameBucket cycles.
+//
+//	29000-29999  ######################################## 58
+func writeHistogram(w io.Writer, frames []uint64) {
+	const width = 40
+	min, max := frames[0]/frameBucket, frames[0]/frameBucket
+	counts := map[uint64]int{}
+	most := 0
+	for _, f := range frames {
+		b := f / frameBucket
+		if b < min {
+			min = b
+		}
+		if b > max {
+			max = b
+		}
+		counts[b]++
+		if counts[b] > most {
+			most = counts[b]
+		}
+	}
+	for b := min; b <= max; b++ {
+		bar := strings.Repeat("#", (counts[b]*width+most-1)/most)
+		fmt.Fprintf(w, "%5d-%-5d  %-*s %d\n", b*frameBucket, (b+1)*frameBucket-1, width, bar, counts[b])
+	}
+}
+
+func percent(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
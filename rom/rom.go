@@ -1,45 +1,73 @@
 package rom
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
-	"log"
-	"os"
 )
 
+var (
+	// ErrBadHeader is reported when a file does not have a valid iNES header.
+	ErrBadHeader = errors.New("bad iNES header")
+	// ErrTruncated is reported when a file is shorter than the PRG-ROM and CHR-ROM sizes in the header.
+	ErrTruncated = errors.New("ROM is truncated")
+)
+
+var magic = []byte{0x4E, 0x45, 0x53, 0x1A}
+
 type Rom struct {
 	PRG []byte
 	CHR []byte
 }
 
-// NewRom creates `*Rom` from `nesFile`.
+// NewRom reads an iNES image such as *os.File.
 //
 // 00000000  4e 45 53 1a 02 01 01 00  00 00 00 00 00 00 00 00  |NES.............|
 // 0-3: Constant $4E $45 $53 $1A ("NES" followed by MS-DOS end-of-file)
 // 4: Size of PRG ROM in 16 KB units
 // 5: Size of CHR ROM in 8 KB units (Value 0 means the board uses CHR RAM)
-func NewRom(nesFile *os.File) *Rom {
+func NewRom(nesFile io.ReaderAt) (*Rom, error) {
 	sr := io.NewSectionReader(nesFile, 0, 0x10)
 	buf := make([]byte, 0x10) // 16ByteのiNESヘッダ
-	if _, err := sr.Read(buf); err != nil {
-		log.Fatal("failed to read iNES header:", err)
+	if _, err := io.ReadFull(sr, buf); err != nil {
+		if isEOF(err) {
+			return nil, fmt.Errorf("%w: shorter than 16 bytes", ErrBadHeader)
+		}
+		return nil, fmt.Errorf("failed to read iNES header: %w", err)
+	}
+	if !bytes.Equal(buf[:4], magic) {
+		return nil, fmt.Errorf("%w: magic %q", ErrBadHeader, buf[:4])
 	}
 
 	sizeOfPRG, sizeOfCHR := int(buf[4]), int(buf[5])
+	if sizeOfPRG == 0 {
+		return nil, fmt.Errorf("%w: no PRG-ROM", ErrBadHeader)
+	}
 	pr := io.NewSectionReader(nesFile, 0x10, int64(sizeOfPRG*0x4000))
 	cr := io.NewSectionReader(nesFile, int64(0x10+sizeOfPRG*0x4000), int64(sizeOfCHR*0x2000))
 
 	PRGROM, CHRROM := make([]byte, sizeOfPRG*0x4000), make([]byte, sizeOfCHR*0x2000)
-	if _, err := pr.Read(PRGROM); err != nil {
-		log.Fatal("failed to read PRGROM:", err)
+	if _, err := io.ReadFull(pr, PRGROM); err != nil {
+		if isEOF(err) {
+			return nil, fmt.Errorf("%w: PRG-ROM must be %d bytes", ErrTruncated, len(PRGROM))
+		}
+		return nil, fmt.Errorf("failed to read PRG-ROM: %w", err)
 	}
-
-	if _, err := cr.Read(CHRROM); err != nil {
-		log.Fatal(err)
+	if _, err := io.ReadFull(cr, CHRROM); err != nil {
+		if isEOF(err) {
+			return nil, fmt.Errorf("%w: CHR-ROM must be %d bytes", ErrTruncated, len(CHRROM))
+		}
+		return nil, fmt.Errorf("failed to read CHR-ROM: %w", err)
 	}
 	return &Rom{
 		PRG: PRGROM,
 		CHR: CHRROM,
-	}
+	}, nil
+}
+
+func isEOF(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
 }
 
 func (r *Rom) ReadPRG(address uint16) byte {
@@ -0,0 +1,68 @@
+package rom
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// image returns an iNES image with the header and size bytes after it.
+func image(header []byte, size int) []byte {
+	b := append([]byte{}, header...)
+	for i := 0; i < size; i++ {
+		b = append(b, byte(i))
+	}
+	return b
+}
+
+func TestNewRom(t *testing.T) {
+	t.Parallel()
+	header := []byte{0x4E, 0x45, 0x53, 0x1A, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	r, err := NewRom(bytes.NewReader(image(header, 0x4000+0x2000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 0x4000, len(r.PRG); want != got {
+		t.Errorf("PRG size: want=%#x, got=%#x", want, got)
+	}
+	if want, got := 0x2000, len(r.CHR); want != got {
+		t.Errorf("CHR size: want=%#x, got=%#x", want, got)
+	}
+	if diff := cmp.Diff([]byte{0x00, 0x01}, r.CHR[:2]); diff != "" {
+		t.Errorf("CHR mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewRom_error(t *testing.T) {
+	t.Parallel()
+	header := []byte{0x4E, 0x45, 0x53, 0x1A, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	// with returns header whose byte at i is replaced by v
+	with := func(i int, v byte) []byte {
+		h := append([]byte{}, header...)
+		h[i] = v
+		return h
+	}
+	for _, tt := range []struct {
+		name  string
+		image []byte
+		want  error
+	}{
+		{"empty", nil, ErrBadHeader},
+		{"short header", header[:8], ErrBadHeader},
+		{"magic", image(with(2, 'Z'), 0x8000+0x2000), ErrBadHeader},
+		{"no PRG", image(with(4, 0), 0x2000), ErrBadHeader},
+		{"short PRG", image(header, 0x4000), ErrTruncated},
+		{"short CHR", image(header, 0x8000+0x1000), ErrTruncated},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewRom(bytes.NewReader(tt.image))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("want=%v, got=%v", tt.want, err)
+			}
+		})
+	}
+}
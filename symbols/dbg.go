@@ -0,0 +1,186 @@
+package symbols
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// inesHeaderSize is the size of the iNES header before PRG-ROM in the output file.
+const inesHeaderSize = 0x10
+
+// dbgSegment is a seg record. Segments without ooffs are not in the ROM file.
+type dbgSegment struct {
+	start  int
+	ooffs  int
+	inFile bool
+}
+
+// location returns the location of offset in the segment.
+func (s dbgSegment) location(offset int) Location {
+	loc := Location{Bank: -1, Address: uint16(s.start + offset)}
+	if s.inFile && s.ooffs >= inesHeaderSize && loc.Address >= 0x8000 {
+		loc.Bank = (s.ooffs - inesHeaderSize + offset) / 0x4000
+	}
+	return loc
+}
+
+type dbgSpan struct {
+	seg, start int
+}
+
+type dbgLine struct {
+	file, line int
+	spans      []int
+}
+
+// ParseDbg parses a debug info file written by `ld65 --dbgfile`.
+// Labels (sym type=lab) and assembler source lines are added. PRG banks are 16KB from the file offset after the iNES header.
+func ParseDbg(r io.Reader) (*Table, error) {
+	files := map[int]string{}
+	segs := map[int]dbgSegment{}
+	spans := map[int]dbgSpan{}
+	var lines []dbgLine
+	type dbgSym struct {
+		name       string
+		val, seg   int
+		hasSegment bool
+	}
+	var syms []dbgSym
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		kind, rest, _ := strings.Cut(text, "\t")
+		if kind == text {
+			kind, rest, _ = strings.Cut(text, " ")
+		}
+		fields, err := parseDbgFields(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		f := dbgFields{fields: fields}
+		switch kind {
+		case "file":
+			files[f.int("id")] = f.fields["name"]
+		case "seg":
+			_, inFile := fields["ooffs"]
+			segs[f.int("id")] = dbgSegment{start: f.int("start"), ooffs: f.int("ooffs"), inFile: inFile}
+		case "span":
+			spans[f.int("id")] = dbgSpan{seg: f.int("seg"), start: f.int("start")}
+		case "line":
+			// type 1はC、2はマクロ展開. アセンブラのソース行だけを使う
+			if _, ok := fields["span"]; !ok || f.int("type") != 0 {
+				break
+			}
+			lines = append(lines, dbgLine{file: f.int("file"), line: f.int("line"), spans: f.ints("span")})
+		case "sym":
+			if fields["type"] != "lab" {
+				break
+			}
+			_, hasSegment := fields["seg"]
+			syms = append(syms, dbgSym{name: fields["name"], val: f.int("val"), seg: f.int("seg"), hasSegment: hasSegment})
+		}
+		if f.err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, f.err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	t := New()
+	for _, sym := range syms {
+		loc := Location{Bank: -1, Address: uint16(sym.val)}
+		if seg, ok := segs[sym.seg]; ok && sym.hasSegment {
+			loc = seg.location(sym.val - seg.start)
+		}
+		t.AddLabel(sym.name, loc)
+	}
+	for _, l := range lines {
+		file, ok := files[l.file]
+		if !ok {
+			return nil, fmt.Errorf("unknown file id %d", l.file)
+		}
+		// 複数のspanを持つ行は先頭のアドレスだけを登録する
+		start := -1
+		var loc Location
+		for _, id := range l.spans {
+			span, ok := spans[id]
+			if !ok {
+				return nil, fmt.Errorf("unknown span id %d", id)
+			}
+			seg, ok := segs[span.seg]
+			if !ok {
+				return nil, fmt.Errorf("unknown segment id %d", span.seg)
+			}
+			if addr := seg.start + span.start; start < 0 || addr < start {
+				start, loc = addr, seg.location(span.start)
+			}
+		}
+		t.AddLine(loc, Line{File: file, Line: l.line})
+	}
+	return t, nil
+}
+
+// dbgFields reads typed values and keeps the first error.
+type dbgFields struct {
+	fields map[string]string
+	err    error
+}
+
+// int parses a decimal or 0x prefixed hexadecimal value. A missing key is 0.
+func (f *dbgFields) int(key string) int {
+	s, ok := f.fields[key]
+	if !ok {
+		return 0
+	}
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil && f.err == nil {
+		f.err = fmt.Errorf("invalid %s: %s", key, s)
+	}
+	return int(v)
+}
+
+// ints parses a list of ids joined by '+', e.g. "3+4".
+func (f *dbgFields) ints(key string) []int {
+	var vs []int
+	for _, s := range strings.Split(f.fields[key], "+") {
+		v, err := strconv.Atoi(s)
+		if err != nil && f.err == nil {
+			f.err = fmt.Errorf("invalid %s: %s", key, f.fields[key])
+		}
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+// parseDbgFields parses `key=value,key="quoted, value"`.
+func parseDbgFields(s string) (map[string]string, error) {
+	fields := map[string]string{}
+	for s != "" {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, fmt.Errorf("missing '=': %s", s)
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string: %s", rest)
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+			rest = strings.TrimPrefix(rest, ",")
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		fields[strings.TrimSpace(key)] = value
+		s = rest
+	}
+	return fields, nil
+}
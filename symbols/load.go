@@ -0,0 +1,63 @@
+package symbols
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Load reads a symbol file by its extension and merges it into t.
+//   - .dbg: ld65 debug info
+//   - .nl: FCEUX name list. The bank is taken from the name, e.g. "game.nes.1.nl". "game.nes.ram.nl" is RAM.
+//   - .mlb: Mesen labels. prgSize is the size of PRG-ROM
+func (t *Table) Load(path string, prgSize int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var loaded *Table
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".dbg":
+		loaded, err = ParseDbg(f)
+	case ".nl":
+		loaded, err = ParseNL(f, nlBank(path))
+	case ".mlb":
+		loaded, err = ParseMLB(f, prgSize)
+	default:
+		return fmt.Errorf("unknown symbol file: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	t.Merge(loaded)
+	return nil
+}
+
+// nlBank returns the bank in a name like "game.nes.1.nl", or -1.
+func nlBank(path string) int {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	bank, err := strconv.ParseUint(strings.TrimPrefix(filepath.Ext(name), "."), 16, 8)
+	if err != nil {
+		return -1
+	}
+	return int(bank)
+}
+
+// Merge adds labels and lines of o to t. Labels of t take precedence.
+func (t *Table) Merge(o *Table) {
+	for name, loc := range o.names {
+		if _, ok := t.names[name]; !ok {
+			t.names[name] = loc
+		}
+	}
+	for addr, labels := range o.labels {
+		t.labels[addr] = append(t.labels[addr], labels...)
+	}
+	for addr, lines := range o.lines {
+		t.lines[addr] = append(t.lines[addr], lines...)
+	}
+}
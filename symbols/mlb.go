@@ -0,0 +1,62 @@
+package symbols
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseMLB parses a Mesen label file. prgSize is the size of PRG-ROM to map PRG offsets to CPU addresses.
+//
+//	P:0010:reset:comment        Mesen
+//	NesPrgRom:0010:reset        Mesen 2
+//	R:0300-030F:buffer
+//
+// PRG labels are mapped to both $8000 and $C000 windows of their 16KB bank. The fixed last bank prefers $C000.
+func ParseMLB(r io.Reader, prgSize int) (*Table, error) {
+	t := New()
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, ":", 4)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: invalid label: %s", n, line)
+		}
+		from, _, _ := strings.Cut(fields[1], "-")
+		offset, err := strconv.ParseUint(from, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid address: %s", n, line)
+		}
+		name := strings.TrimSpace(fields[2])
+		if name == "" {
+			continue
+		}
+
+		switch fields[0] {
+		case "P", "NesPrgRom":
+			if prgSize <= 0 || int(offset) >= prgSize {
+				return nil, fmt.Errorf("line %d: PRG offset out of range: %s", n, line)
+			}
+			bank, addr := int(offset/0x4000), uint16(offset%0x4000)
+			windows := []uint16{0x8000, 0xC000}
+			if (bank+1)*0x4000 == prgSize {
+				windows[0], windows[1] = windows[1], windows[0]
+			}
+			for _, w := range windows {
+				t.AddLabel(name, Location{Bank: bank, Address: w + addr})
+			}
+		case "R", "NesInternalRam":
+			t.AddLabel(name, Location{Bank: -1, Address: uint16(offset)})
+		case "S", "W", "NesSaveRam", "NesWorkRam":
+			t.AddLabel(name, Location{Bank: -1, Address: 0x6000 + uint16(offset)})
+		case "G", "NesMemory":
+			t.AddLabel(name, Location{Bank: -1, Address: uint16(offset)})
+		}
+	}
+	return t, scanner.Err()
+}
@@ -0,0 +1,44 @@
+package symbols
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseNL parses a FCEUX name list file such as "game.nes.0.nl" for bank 0 or "game.nes.ram.nl" with bank -1.
+//
+//	$C000#reset#comment
+//	$0300/10#buffer#an array of 16 bytes
+//
+// Lines without a name are comments of the address and ignored.
+func ParseNL(r io.Reader, bank int) (*Table, error) {
+	t := New()
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "$") {
+			continue
+		}
+		fields := strings.SplitN(line[1:], "#", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing '#': %s", n, line)
+		}
+		// 配列は "$0300/10" のようにサイズが付く. 先頭のアドレスにだけラベルを付ける
+		addr, _, _ := strings.Cut(fields[0], "/")
+		v, err := strconv.ParseUint(addr, 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid address: %s", n, line)
+		}
+		if name := strings.TrimSpace(fields[1]); name != "" {
+			loc := Location{Bank: bank, Address: uint16(v)}
+			if loc.Address < 0x8000 {
+				loc.Bank = -1
+			}
+			t.AddLabel(name, loc)
+		}
+	}
+	return t, scanner.Err()
+}
@@ -0,0 +1,130 @@
+// Package symbols maps labels and source lines to addresses in PRG banks.
+package symbols
+
+import (
+	"sort"
+	"strings"
+)
+
+// Location is an address in a PRG bank.
+// Bank is -1 if the address is not in PRG-ROM (RAM, registers) or the bank is unknown.
+type Location struct {
+	Bank    int
+	Address uint16
+}
+
+// match reports whether l and o are the same location. Unknown banks match any bank.
+func (l Location) match(o Location) bool {
+	return l.Address == o.Address && (l.Bank < 0 || o.Bank < 0 || l.Bank == o.Bank)
+}
+
+// Line is a line of a source file. Line starts from 1.
+type Line struct {
+	File string
+	Line int
+}
+
+// Table is a symbol table.
+type Table struct {
+	labels map[uint16][]label // アドレスごとのラベル. 登録順
+	names  map[string]Location
+	lines  map[uint16][]sourceLine
+}
+
+type label struct {
+	name string
+	loc  Location
+}
+
+type sourceLine struct {
+	line Line
+	loc  Location
+}
+
+// New creates an empty Table.
+func New() *Table {
+	return &Table{
+		labels: map[uint16][]label{},
+		names:  map[string]Location{},
+		lines:  map[uint16][]sourceLine{},
+	}
+}
+
+// AddLabel adds a label. The first label added at a location is used by Label.
+func (t *Table) AddLabel(name string, loc Location) {
+	if _, ok := t.names[name]; !ok {
+		t.names[name] = loc
+	}
+	t.labels[loc.Address] = append(t.labels[loc.Address], label{name, loc})
+}
+
+// AddLine records that the code of line starts at loc.
+func (t *Table) AddLine(loc Location, line Line) {
+	t.lines[loc.Address] = append(t.lines[loc.Address], sourceLine{line, loc})
+}
+
+// Label returns the label at loc.
+func (t *Table) Label(loc Location) (string, bool) {
+	for _, l := range t.labels[loc.Address] {
+		if l.loc.match(loc) {
+			return l.name, true
+		}
+	}
+	return "", false
+}
+
+// Address returns the location of a label.
+func (t *Table) Address(name string) (Location, bool) {
+	loc, ok := t.names[name]
+	return loc, ok
+}
+
+// Line returns the source line whose code starts at loc.
+func (t *Table) Line(loc Location) (Line, bool) {
+	for _, l := range t.lines[loc.Address] {
+		if l.loc.match(loc) {
+			return l.line, true
+		}
+	}
+	return Line{}, false
+}
+
+// Locations returns locations where the code of line starts in ascending order.
+// file matches a recorded file name if either is a path suffix of the other, e.g. "/src/game/main.s" and "main.s".
+func (t *Table) Locations(file string, line int) []Location {
+	var locs []Location
+	for _, lines := range t.lines {
+		for _, l := range lines {
+			if l.line.Line == line && sameFile(l.line.File, file) {
+				locs = append(locs, l.loc)
+			}
+		}
+	}
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].Bank != locs[j].Bank {
+			return locs[i].Bank < locs[j].Bank
+		}
+		return locs[i].Address < locs[j].Address
+	})
+	return locs
+}
+
+// sameFile reports whether a is a path suffix of b or vice versa.
+func sameFile(a, b string) bool {
+	a, b = strings.ReplaceAll(a, "\\", "/"), strings.ReplaceAll(b, "\\", "/")
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if a == b {
+		return true
+	}
+	return len(a) > 0 && len(a) < len(b) && b[len(b)-len(a)-1] == '/' && b[len(b)-len(a):] == a
+}
+
+// LabelFunc returns a function looking up the label of an address.
+// bank returns the PRG bank mapped at the address, e.g. cpu.CPU.Bank.
+func (t *Table) LabelFunc(bank func(address uint16) int) func(address uint16) (string, bool) {
+	return func(address uint16) (string, bool) {
+		return t.Label(Location{Bank: bank(address), Address: address})
+	}
+}
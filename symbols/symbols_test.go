@@ -0,0 +1,253 @@
+package symbols
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDbg(t *testing.T) {
+	t.Parallel()
+	f, err := os.Open("testdata/game.dbg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	table, err := ParseDbg(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Label", func(t *testing.T) {
+		t.Parallel()
+		for _, tt := range []struct {
+			loc  Location
+			want string
+		}{
+			{Location{0, 0x8000}, "reset"},
+			{Location{-1, 0x8002}, "loop"},
+			{Location{1, 0xC000}, "nmi"},
+			{Location{-1, 0x0300}, "counter"},
+			{Location{1, 0x8000}, ""},
+			{Location{-1, 0x2000}, ""}, // equは含めない
+		} {
+			got, _ := table.Label(tt.loc)
+			if tt.want != got {
+				t.Errorf("Label(%v): want=%q, got=%q", tt.loc, tt.want, got)
+			}
+		}
+	})
+
+	t.Run("Address", func(t *testing.T) {
+		t.Parallel()
+		got, ok := table.Address("nmi")
+		if diff := cmp.Diff(Location{1, 0xC000}, got); !ok || diff != "" {
+			t.Errorf("Address mismatch (-want +got):\n%s", diff)
+		}
+		if _, ok := table.Address("external"); ok {
+			t.Error("imported symbol must not have an address")
+		}
+	})
+
+	t.Run("Line", func(t *testing.T) {
+		t.Parallel()
+		for _, tt := range []struct {
+			loc  Location
+			want Line
+		}{
+			{Location{0, 0x8000}, Line{"src/main.s", 10}},
+			{Location{0, 0x8002}, Line{"src/main.s", 11}},
+			{Location{0, 0x8005}, Line{"src/main.s", 20}},
+			{Location{1, 0xC001}, Line{"src/main.s", 40}},
+			{Location{-1, 0x0300}, Line{"src/main.s", 50}},
+			{Location{0, 0x8008}, Line{}}, // マクロ展開の行と2つ目のspan
+			{Location{0, 0xC001}, Line{}},
+		} {
+			got, _ := table.Line(tt.loc)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Line(%v) mismatch (-want +got):\n%s", tt.loc, diff)
+			}
+		}
+	})
+
+	t.Run("Locations", func(t *testing.T) {
+		t.Parallel()
+		for _, tt := range []struct {
+			file string
+			line int
+			want []Location
+		}{
+			{"src/main.s", 11, []Location{{0, 0x8002}}},
+			{"/home/user/game/src/main.s", 30, []Location{{1, 0xC000}}},
+			{`C:\game\src\main.s`, 20, []Location{{0, 0x8005}}},
+			{"main.s", 10, []Location{{0, 0x8000}}},
+			{"ain.s", 10, nil},
+			{"src/main.s", 12, nil},
+		} {
+			if diff := cmp.Diff(tt.want, table.Locations(tt.file, tt.line)); diff != "" {
+				t.Errorf("Locations(%s, %d) mismatch (-want +got):\n%s", tt.file, tt.line, diff)
+			}
+		}
+	})
+}
+
+func TestParseDbg_error(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name string
+		src  string
+	}{
+		{"missing =", "file\tid=0,name"},
+		{"unterminated string", "file\tid=0,name=\"main.s"},
+		{"invalid number", "seg\tid=x"},
+		{"unknown span", "file\tid=0,name=\"a.s\"\nline\tid=0,file=0,line=1,span=3"},
+		{"unknown file", "line\tid=0,file=2,line=1,span=0"},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := ParseDbg(strings.NewReader(tt.src)); err == nil {
+				t.Error("error expected")
+			}
+		})
+	}
+}
+
+func TestParseNL(t *testing.T) {
+	t.Parallel()
+	src := `$8000#reset#entry point
+$8010##comment only
+$0300/10#buffer#
+$C000#nmi#`
+	table, err := ParseNL(strings.NewReader(src), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		loc  Location
+		want string
+	}{
+		{Location{2, 0x8000}, "reset"},
+		{Location{2, 0xC000}, "nmi"},
+		{Location{0, 0x0300}, "buffer"}, // RAMはバンクによらない
+		{Location{1, 0x8000}, ""},
+		{Location{2, 0x8010}, ""},
+		{Location{2, 0x0301}, ""},
+	} {
+		got, _ := table.Label(tt.loc)
+		if tt.want != got {
+			t.Errorf("Label(%v): want=%q, got=%q", tt.loc, tt.want, got)
+		}
+	}
+
+	if _, err := ParseNL(strings.NewReader("$8000"), 0); err == nil {
+		t.Error("error expected for missing '#'")
+	}
+	if _, err := ParseNL(strings.NewReader("$80G0#reset#"), 0); err == nil {
+		t.Error("error expected for invalid address")
+	}
+}
+
+func TestParseMLB(t *testing.T) {
+	t.Parallel()
+	src := `P:0010:reset:comment
+NesPrgRom:4020:nmi
+R:0300-030F:buffer
+W:0010:save
+G:2000:PPUCTRL
+P:0030:`
+	table, err := ParseMLB(strings.NewReader(src), 0x8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		loc  Location
+		want string
+	}{
+		{Location{0, 0x8010}, "reset"},
+		{Location{0, 0xC010}, "reset"},
+		{Location{1, 0xC020}, "nmi"},
+		{Location{1, 0x8020}, "nmi"},
+		{Location{-1, 0x0300}, "buffer"},
+		{Location{-1, 0x6010}, "save"},
+		{Location{-1, 0x2000}, "PPUCTRL"},
+		{Location{0, 0x8030}, ""},
+		{Location{1, 0x8010}, ""},
+	} {
+		got, _ := table.Label(tt.loc)
+		if tt.want != got {
+			t.Errorf("Label(%v): want=%q, got=%q", tt.loc, tt.want, got)
+		}
+	}
+
+	// 最後のバンクは$C000に固定されるので$C000側を優先する
+	got, _ := table.Address("nmi")
+	if diff := cmp.Diff(Location{1, 0xC020}, got); diff != "" {
+		t.Errorf("Address mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, src := range []string{"P:8000:far", "P:0010", "P:zz:bad"} {
+		if _, err := ParseMLB(strings.NewReader(src), 0x8000); err == nil {
+			t.Errorf("ParseMLB(%q): error expected", src)
+		}
+	}
+}
+
+func TestTable_Load(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	table := New()
+	for _, path := range []string{
+		"testdata/game.dbg",
+		write("game.nes.1.nl", "$8000#bank1_start#\n$C000#other_nmi#\n"),
+		write("game.nes.ram.nl", "$0300#other_counter#\n$0400#scratch#\n"),
+		write("game.mlb", "P:0004:mlb_label\n"),
+	} {
+		if err := table.Load(path, 0x8000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := table.Load(write("game.sym", ""), 0x8000); err == nil {
+		t.Error("error expected for unknown extension")
+	}
+
+	for _, tt := range []struct {
+		loc  Location
+		want string
+	}{
+		{Location{0, 0x8000}, "reset"},
+		{Location{1, 0x8000}, "bank1_start"},
+		{Location{1, 0xC000}, "nmi"}, // 先に読んだラベルを優先する
+		{Location{-1, 0x0300}, "counter"},
+		{Location{-1, 0x0400}, "scratch"},
+		{Location{0, 0x8004}, "mlb_label"},
+	} {
+		got, _ := table.Label(tt.loc)
+		if tt.want != got {
+			t.Errorf("Label(%v): want=%q, got=%q", tt.loc, tt.want, got)
+		}
+	}
+	if got, ok := table.Line(Location{0, 0x8000}); !ok || got.Line != 10 {
+		t.Errorf("Line: want=10, got=%v", got)
+	}
+
+	label := table.LabelFunc(func(address uint16) int {
+		if address >= 0xC000 {
+			return 1
+		}
+		return 0
+	})
+	if got, _ := label(0xC000); got != "nmi" {
+		t.Errorf("LabelFunc: want=%q, got=%q", "nmi", got)
+	}
+}